@@ -0,0 +1,179 @@
+package cmd
+
+import (
+    "encoding/json"
+    "fmt"
+    "go-auto-proxy/internal/config"
+    "go-auto-proxy/internal/fail2ban"
+    "log"
+    "net"
+    "os"
+    "text/tabwriter"
+
+    "github.com/spf13/cobra"
+)
+
+var (
+    banJail       string
+    banJSON       bool
+    banAddJail    string
+    banRemoveJail string
+)
+
+var banCmd = &cobra.Command{
+    Use:   "ban",
+    Short: "List, add and remove fail2ban bans",
+}
+
+// bannedIP 是 ban list 的一列；Allowlisted 表示 IP 在允許清單中卻仍被封鎖，通常是配置錯誤
+type bannedIP struct {
+    Jail        string `json:"jail"`
+    IP          string `json:"ip"`
+    Allowlisted bool   `json:"allowlisted"`
+}
+
+var banListCmd = &cobra.Command{
+    Use:   "list",
+    Short: "List currently banned IPs per jail",
+    RunE: func(cmd *cobra.Command, args []string) error {
+        info, err := config.ReadConfig()
+        if err != nil {
+            return fmt.Errorf("failed to read config: %v", err)
+        }
+        statuses, err := jailStatuses()
+        if err != nil {
+            return err
+        }
+
+        banned := []bannedIP{}
+        for _, status := range statuses {
+            for _, ip := range status.BannedIPs {
+                banned = append(banned, bannedIP{Jail: status.Name, IP: ip, Allowlisted: fail2ban.Allowed(ip, info.Fail2Ban.Allowlist)})
+            }
+        }
+        if banJSON {
+            return printJSON(banned)
+        }
+        w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+        fmt.Fprintln(w, "JAIL\tIP\tNOTE")
+        allowlisted := 0
+        for _, b := range banned {
+            note := ""
+            if b.Allowlisted {
+                note = "! allowlisted"
+                allowlisted++
+            }
+            fmt.Fprintf(w, "%s\t%s\t%s\n", b.Jail, b.IP, note)
+        }
+        if err := w.Flush(); err != nil {
+            return err
+        }
+        if allowlisted > 0 {
+            log.Printf("Warning: %d banned IP(s) are in the fail2ban allowlist; check ignoreip in the jails or run 'go-auto-proxy fail2ban configure'.", allowlisted)
+        }
+        return nil
+    },
+}
+
+var banStatsCmd = &cobra.Command{
+    Use:   "stats",
+    Short: "Show failure and ban counters per jail",
+    RunE: func(cmd *cobra.Command, args []string) error {
+        statuses, err := jailStatuses()
+        if err != nil {
+            return err
+        }
+        if banJSON {
+            return printJSON(statuses)
+        }
+        w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+        fmt.Fprintln(w, "JAIL\tFAILED\tTOTAL FAILED\tBANNED\tTOTAL BANNED")
+        for _, s := range statuses {
+            fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d\n", s.Name, s.CurrentlyFailed, s.TotalFailed, s.CurrentlyBanned, s.TotalBanned)
+        }
+        return w.Flush()
+    },
+}
+
+var banAddCmd = &cobra.Command{
+    Use:   "add <ip>",
+    Short: "Ban an IP in a jail",
+    Args:  cobra.ExactArgs(1),
+    RunE: func(cmd *cobra.Command, args []string) error {
+        ip := args[0]
+        if net.ParseIP(ip) == nil {
+            return fmt.Errorf("%q is not a valid IP address", ip)
+        }
+        info, err := config.ReadConfig()
+        if err != nil {
+            return fmt.Errorf("failed to read config: %v", err)
+        }
+        if fail2ban.Allowed(ip, info.Fail2Ban.Allowlist) {
+            return fmt.Errorf("%s is in the fail2ban allowlist in config.json and cannot be banned", ip)
+        }
+        if err := fail2ban.Ban(banAddJail, ip); err != nil {
+            return err
+        }
+        log.Printf("Banned %s in jail %s.", ip, banAddJail)
+        return nil
+    },
+}
+
+var banRemoveCmd = &cobra.Command{
+    Use:   "remove <ip>",
+    Short: "Unban an IP (from every jail unless --jail is given)",
+    Args:  cobra.ExactArgs(1),
+    RunE: func(cmd *cobra.Command, args []string) error {
+        ip := args[0]
+        if net.ParseIP(ip) == nil {
+            return fmt.Errorf("%q is not a valid IP address", ip)
+        }
+        jail := banRemoveJail
+        if err := fail2ban.Unban(jail, ip); err != nil {
+            return err
+        }
+        if jail == "" {
+            jail = "all jails"
+        }
+        log.Printf("Unbanned %s from %s.", ip, jail)
+        return nil
+    },
+}
+
+// jailStatuses 查詢所有 jail 的狀態，--jail 可限定單個 jail
+func jailStatuses() ([]fail2ban.JailStatus, error) {
+    jails := []string{banJail}
+    if banJail == "" {
+        var err error
+        if jails, err = fail2ban.Jails(); err != nil {
+            return nil, fmt.Errorf("failed to list fail2ban jails: %v", err)
+        }
+    }
+    statuses := make([]fail2ban.JailStatus, 0, len(jails))
+    for _, jail := range jails {
+        status, err := fail2ban.Status(jail)
+        if err != nil {
+            return nil, fmt.Errorf("failed to query jail %s: %v", jail, err)
+        }
+        statuses = append(statuses, status)
+    }
+    return statuses, nil
+}
+
+// printJSON 以縮排 JSON 輸出到標準輸出
+func printJSON(v interface{}) error {
+    enc := json.NewEncoder(os.Stdout)
+    enc.SetIndent("", "  ")
+    return enc.Encode(v)
+}
+
+func init() {
+    for _, c := range []*cobra.Command{banListCmd, banStatsCmd} {
+        c.Flags().StringVar(&banJail, "jail", "", "only show this jail")
+        c.Flags().BoolVar(&banJSON, "json", false, "print as JSON")
+    }
+    banAddCmd.Flags().StringVar(&banAddJail, "jail", "sshd", "jail to add the ban to")
+    banRemoveCmd.Flags().StringVar(&banRemoveJail, "jail", "", "only unban from this jail")
+    banCmd.AddCommand(banListCmd, banAddCmd, banRemoveCmd, banStatsCmd)
+    rootCmd.AddCommand(banCmd)
+}
@@ -0,0 +1,466 @@
+package cmd
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "go-auto-proxy/internal/acme"
+    "go-auto-proxy/internal/cert"
+    "go-auto-proxy/internal/config"
+    "go-auto-proxy/internal/dns"
+    "go-auto-proxy/internal/system"
+    "go-auto-proxy/internal/systemd"
+    "log"
+    "math"
+    "net"
+    "os"
+    "os/exec"
+    "path/filepath"
+    "strconv"
+    "strings"
+    "time"
+
+    "github.com/spf13/cobra"
+)
+
+const (
+    // renewBefore 憑證距離到期少於此時間時才重新申請
+    renewBefore = 30 * 24 * time.Hour
+    // forceRenewal 作為 renewBefore 時總是重新申請
+    forceRenewal = time.Duration(math.MaxInt64)
+)
+
+var (
+    certClient     string
+    certDomain     string
+    certEmail      string
+    certChallenge  string
+    certWebroot    string
+    certForce      bool
+    certJSON       bool
+    certWithin     string
+    certOnCalendar string
+    certSNI        string
+    certIPs        []string
+    certOut        string
+)
+
+var certCmd = &cobra.Command{
+    Use:   "cert",
+    Short: "Manage TLS certificates for trojan-go",
+}
+
+var certIssueCmd = &cobra.Command{
+    Use:   "issue",
+    Short: "Obtain or renew the certificate with the configured ACME client",
+    RunE: func(cmd *cobra.Command, args []string) error {
+        info, err := config.ReadConfig()
+        if err != nil {
+            return fmt.Errorf("failed to read config (run 'go-auto-proxy init' first): %v", err)
+        }
+        switch {
+        case cmd.Flags().Changed("client"):
+            if certClient != "acme.sh" && certClient != "native" {
+                return fmt.Errorf("unsupported ACME client %q (use acme.sh or native)", certClient)
+            }
+            info.AcmeSH.Client = certClient
+        case info.AcmeSH.Client == "selfsign":
+            // cert issue 一律向 ACME CA 申請；從自簽憑證切回時，有安裝 acme.sh 就沿用它
+            info.AcmeSH.Client = "native"
+            if _, err := os.Stat(info.AcmeSH.Path); err == nil {
+                info.AcmeSH.Client = "acme.sh"
+            }
+            log.Printf("Switching from the local CA to the %s ACME client.", info.AcmeSH.Client)
+        }
+        if cmd.Flags().Changed("domain") {
+            info.AcmeSH.Domain = certDomain
+        }
+        if cmd.Flags().Changed("email") {
+            info.AcmeSH.Email = certEmail
+        }
+        if cmd.Flags().Changed("challenge") {
+            info.AcmeSH.Challenge = certChallenge
+        }
+        if cmd.Flags().Changed("webroot") {
+            info.AcmeSH.Webroot = certWebroot
+        }
+        if info.AcmeSH.Domain == "" {
+            return fmt.Errorf("no domain configured, pass --domain")
+        }
+
+        before := renewBefore
+        if certForce {
+            before = forceRenewal
+        }
+        _, err = issueCertificate(cmd.Context(), info, before)
+        return err
+    },
+}
+
+var certStatusCmd = &cobra.Command{
+    Use:   "status",
+    Short: "Show the installed certificate's names, issuer, expiry and key match",
+    RunE: func(cmd *cobra.Command, args []string) error {
+        info, err := config.ReadConfig()
+        if err != nil {
+            return fmt.Errorf("failed to read config: %v", err)
+        }
+        if info.AcmeSH.CertPath == "" {
+            return fmt.Errorf("no certificate installed yet, run 'go-auto-proxy cert issue'")
+        }
+        status, err := cert.Inspect(info.AcmeSH.CertPath, info.AcmeSH.KeyPath)
+        if err != nil {
+            return fmt.Errorf("failed to inspect certificate: %v", err)
+        }
+
+        if certJSON {
+            enc := json.NewEncoder(os.Stdout)
+            enc.SetIndent("", "  ")
+            return enc.Encode(status)
+        }
+        fmt.Printf("Certificate: %s\n", status.Path)
+        fmt.Printf("Subject:     %s\n", status.Subject)
+        fmt.Printf("SANs:        %s\n", strings.Join(append(status.DNSNames, status.IPAddresses...), ", "))
+        fmt.Printf("Issuer:      %s\n", status.Issuer)
+        fmt.Printf("Valid from:  %s\n", status.NotBefore.Format(time.RFC3339))
+        fmt.Printf("Expires:     %s (in %d days)\n", status.NotAfter.Format(time.RFC3339), int(time.Until(status.NotAfter).Hours()/24))
+        fmt.Printf("Key matches: %t\n", status.KeyMatches)
+        fmt.Printf("SHA-256:     %s\n", status.Fingerprint)
+        return nil
+    },
+}
+
+var certRenewCmd = &cobra.Command{
+    Use:   "renew",
+    Short: "Renew the certificate if it expires soon and restart trojan-go when it changed",
+    RunE: func(cmd *cobra.Command, args []string) error {
+        window, err := parseDuration(certWithin)
+        if err != nil {
+            return err
+        }
+        info, err := config.ReadConfig()
+        if err != nil {
+            return fmt.Errorf("failed to read config: %v", err)
+        }
+        if info.AcmeSH.Domain == "" && info.AcmeSH.Client != "selfsign" {
+            return fmt.Errorf("no domain configured, run 'go-auto-proxy cert issue --domain <domain>' first")
+        }
+
+        if info.AcmeSH.CertPath != "" {
+            if status, err := cert.Inspect(info.AcmeSH.CertPath, ""); err == nil && !status.ExpiresWithin(window) {
+                log.Printf("Certificate for %s expires at %s, not within %s; nothing to do.", info.AcmeSH.Domain, status.NotAfter.Format(time.RFC3339), certWithin)
+                return nil
+            }
+        }
+
+        changed, err := issueCertificate(cmd.Context(), info, forceRenewal)
+        if err != nil {
+            return err
+        }
+        if !changed {
+            log.Println("Certificate unchanged, trojan-go restart not needed.")
+            return nil
+        }
+        return restartTrojan()
+    },
+}
+
+var certInstallTimerCmd = &cobra.Command{
+    Use:   "install-timer",
+    Short: "Install a systemd timer that runs 'cert renew' periodically",
+    RunE: func(cmd *cobra.Command, args []string) error {
+        if _, err := parseDuration(certWithin); err != nil {
+            return err
+        }
+        exe, err := os.Executable()
+        if err != nil {
+            return err
+        }
+        wd, err := os.Getwd()
+        if err != nil {
+            return err
+        }
+        return systemd.InstallTimer(systemd.Timer{
+            Name:             "go-auto-proxy-cert-renew",
+            Description:      "go-auto-proxy certificate renewal",
+            ExecStart:        fmt.Sprintf("%s cert renew --if-expiring-within %s", exe, certWithin),
+            WorkingDirectory: wd,
+            OnCalendar:       certOnCalendar,
+            RandomizedDelay:  "1h",
+        })
+    },
+}
+
+var certSelfSignCmd = &cobra.Command{
+    Use:   "selfsign",
+    Short: "Create a local CA and issue a server certificate for private deployments",
+    RunE: func(cmd *cobra.Command, args []string) error {
+        info, err := config.ReadConfig()
+        if err != nil {
+            return fmt.Errorf("failed to read config (run 'go-auto-proxy init' first): %v", err)
+        }
+        sni := info.AcmeSH.Domain
+        if cmd.Flags().Changed("sni") {
+            sni = certSNI
+        }
+        var dnsNames []string
+        if sni != "" {
+            dnsNames = []string{sni}
+        }
+        var ips []net.IP
+        for _, s := range certIPs {
+            ip := net.ParseIP(s)
+            if ip == nil {
+                return fmt.Errorf("invalid IP address %q", s)
+            }
+            ips = append(ips, ip)
+        }
+        if len(dnsNames) == 0 && len(ips) == 0 {
+            return fmt.Errorf("pass --sni and/or --ip for the server certificate")
+        }
+
+        info.AcmeSH.Client = "selfsign"
+        info.AcmeSH.Domain = sni
+        if info.TrojanGo.RemoteAddr == "" && len(ips) > 0 {
+            info.TrojanGo.RemoteAddr = ips[0].String()
+        }
+        return selfSign(info, dnsNames, ips)
+    },
+}
+
+var certExportCACmd = &cobra.Command{
+    Use:   "export-ca",
+    Short: "Print or save the local CA certificate so clients can pin it",
+    RunE: func(cmd *cobra.Command, args []string) error {
+        info, err := config.ReadConfig()
+        if err != nil {
+            return fmt.Errorf("failed to read config: %v", err)
+        }
+        if info.AcmeSH.CAPath == "" {
+            return fmt.Errorf("no local CA configured, run 'go-auto-proxy cert selfsign' first")
+        }
+        data, err := os.ReadFile(info.AcmeSH.CAPath)
+        if err != nil {
+            return err
+        }
+        if certOut == "" {
+            _, err = os.Stdout.Write(data)
+            return err
+        }
+        if err := os.WriteFile(certOut, data, 0644); err != nil {
+            return err
+        }
+        log.Printf("CA certificate exported to %s", certOut)
+        return nil
+    },
+}
+
+// selfSign 由本地 CA 簽發伺服器憑證，並更新配置與 trojan-go 配置
+func selfSign(info system.SystemInfo, dnsNames []string, ips []net.IP) error {
+    dir := filepath.Join(config.StateDir, "selfsign")
+    ca, err := cert.LoadOrCreateCA(dir, "go-auto-proxy local CA")
+    if err != nil {
+        return err
+    }
+    certPEM, keyPEM, err := ca.IssueServerCert(dnsNames, ips)
+    if err != nil {
+        return err
+    }
+    certPath := filepath.Join(dir, "server.crt")
+    keyPath := filepath.Join(dir, "server.key")
+    if err := os.WriteFile(keyPath, keyPEM, 0600); err != nil {
+        return err
+    }
+    if err := os.WriteFile(certPath, certPEM, 0644); err != nil {
+        return err
+    }
+    log.Printf("Issued self-signed server certificate for %v %v", dnsNames, ips)
+
+    if info.AcmeSH.CertPath, err = filepath.Abs(certPath); err != nil {
+        return err
+    }
+    if info.AcmeSH.KeyPath, err = filepath.Abs(keyPath); err != nil {
+        return err
+    }
+    if info.AcmeSH.CAPath, err = filepath.Abs(ca.CertPath); err != nil {
+        return err
+    }
+    if err := config.WriteConfig(info); err != nil {
+        return fmt.Errorf("failed to update config: %v", err)
+    }
+    return writeTrojanConfigs(info)
+}
+
+// renewSelfSigned 以現有憑證的 SAN 重新簽發自簽憑證
+func renewSelfSigned(info system.SystemInfo, before time.Duration) (bool, error) {
+    status, err := cert.Inspect(info.AcmeSH.CertPath, "")
+    if err != nil {
+        return false, fmt.Errorf("failed to inspect current certificate: %v", err)
+    }
+    if !status.ExpiresWithin(before) {
+        log.Printf("Certificate is valid until %s, no renewal needed.", status.NotAfter.Format(time.RFC3339))
+        return false, nil
+    }
+    var ips []net.IP
+    for _, s := range status.IPAddresses {
+        ips = append(ips, net.ParseIP(s))
+    }
+    if err := selfSign(info, status.DNSNames, ips); err != nil {
+        return false, err
+    }
+    return true, nil
+}
+
+// issueCertificate 以設定的 ACME 客戶端申請或續期憑證並更新配置，
+// 返回已安裝的憑證是否發生變化
+func issueCertificate(ctx context.Context, info system.SystemInfo, before time.Duration) (bool, error) {
+    if info.AcmeSH.Client == "selfsign" {
+        return renewSelfSigned(info, before)
+    }
+
+    storage := acme.Storage{Dir: filepath.Join(config.StateDir, "acme")}
+    certPath, keyPath := storage.CertPaths(info.AcmeSH.Domain)
+    oldFingerprint := cert.Fingerprint(certPath)
+    oldCertPath := info.AcmeSH.CertPath
+
+    var err error
+    if info.AcmeSH.Client == "native" {
+        err = issueWithNativeClient(ctx, info, storage, before)
+    } else {
+        err = issueWithAcmeSH(info, certPath, keyPath, before == forceRenewal)
+    }
+    if err != nil {
+        return false, err
+    }
+
+    if info.AcmeSH.CertPath, err = filepath.Abs(certPath); err != nil {
+        return false, err
+    }
+    if info.AcmeSH.KeyPath, err = filepath.Abs(keyPath); err != nil {
+        return false, err
+    }
+    // 公開憑證不需要客戶端釘選本地 CA
+    info.AcmeSH.CAPath = ""
+    if err := config.WriteConfig(info); err != nil {
+        return false, fmt.Errorf("failed to update config: %v", err)
+    }
+
+    // 從自簽憑證切換過來時，即使 ACME 憑證本身沒變，安裝的憑證也換了
+    changed := cert.Fingerprint(certPath) != oldFingerprint || info.AcmeSH.CertPath != oldCertPath
+    if changed {
+        log.Printf("Certificate installed: %s", info.AcmeSH.CertPath)
+        if err := writeTrojanConfigs(info); err != nil {
+            return true, err
+        }
+    }
+    return changed, nil
+}
+
+// parseDuration 解析時間長度，額外支援以 d 結尾的天數（如 30d）
+func parseDuration(s string) (time.Duration, error) {
+    if strings.HasSuffix(s, "d") {
+        days, err := strconv.Atoi(strings.TrimSuffix(s, "d"))
+        if err != nil || days < 0 {
+            return 0, fmt.Errorf("invalid duration %q", s)
+        }
+        return time.Duration(days) * 24 * time.Hour, nil
+    }
+    d, err := time.ParseDuration(s)
+    if err != nil {
+        return 0, fmt.Errorf("invalid duration %q: %v", s, err)
+    }
+    return d, nil
+}
+
+// issueWithNativeClient 使用內建 ACME 客戶端申請或續期憑證
+func issueWithNativeClient(ctx context.Context, info system.SystemInfo, storage acme.Storage, before time.Duration) error {
+    dirURL, err := acme.DirectoryURL(info.AcmeSH.Provider)
+    if err != nil {
+        return err
+    }
+    client := &acme.Client{DirectoryURL: dirURL, Solvers: map[string]acme.Solver{}}
+    switch info.AcmeSH.Challenge {
+    case "", "http-01":
+        client.Solvers["http-01"] = &acme.HTTP01Solver{Webroot: info.AcmeSH.Webroot}
+    case "tls-alpn-01":
+        client.Solvers["tls-alpn-01"] = &acme.TLSALPN01Solver{}
+    case "dns-01":
+        provider, err := dns.New(info.DNS)
+        if err != nil {
+            return err
+        }
+        client.Solvers["dns-01"] = &dns.ChallengeSolver{Provider: provider, PropagationTimeout: 2 * time.Minute}
+    default:
+        return fmt.Errorf("unsupported challenge type %q", info.AcmeSH.Challenge)
+    }
+
+    _, err = acme.ObtainOrRenew(ctx, client, storage, info.AcmeSH.Email, info.AcmeSH.Domain, before)
+    return err
+}
+
+// issueWithAcmeSH 透過 acme.sh 申請憑證並安裝到狀態目錄
+func issueWithAcmeSH(info system.SystemInfo, certPath, keyPath string, force bool) error {
+    args := []string{"--issue", "-d", info.AcmeSH.Domain, "--server", info.AcmeSH.Provider}
+    var env []string
+    switch {
+    case info.AcmeSH.Challenge == "dns-01":
+        hook, hookEnv, err := dns.AcmeSHHook(info.DNS)
+        if err != nil {
+            return err
+        }
+        args = append(args, "--dns", hook)
+        env = hookEnv
+    case info.AcmeSH.Challenge == "tls-alpn-01":
+        args = append(args, "--alpn")
+    case info.AcmeSH.Webroot != "":
+        args = append(args, "--webroot", info.AcmeSH.Webroot)
+    default:
+        args = append(args, "--standalone")
+    }
+    if force {
+        args = append(args, "--force")
+    }
+
+    log.Printf("Issuing certificate for %s with acme.sh...", info.AcmeSH.Domain)
+    issueCmd := exec.Command(info.AcmeSH.Path, args...)
+    issueCmd.Env = append(os.Environ(), env...)
+    out, err := issueCmd.CombinedOutput()
+    log.Printf("acme.sh output: %s", out)
+    var exitErr *exec.ExitError
+    if errors.As(err, &exitErr) && exitErr.ExitCode() == 2 {
+        // acme.sh 以退出碼 2 表示憑證尚未到續期時間
+        log.Printf("acme.sh skipped issuing, certificate for %s is not due for renewal.", info.AcmeSH.Domain)
+    } else if err != nil {
+        return fmt.Errorf("acme.sh failed to issue certificate: %v", err)
+    }
+
+    if err := os.MkdirAll(filepath.Dir(certPath), 0700); err != nil {
+        return err
+    }
+    out, err = exec.Command(info.AcmeSH.Path, "--install-cert", "-d", info.AcmeSH.Domain,
+        "--fullchain-file", certPath, "--key-file", keyPath).CombinedOutput()
+    if err != nil {
+        log.Printf("acme.sh output: %s", out)
+        return fmt.Errorf("acme.sh failed to install certificate: %v", err)
+    }
+    return nil
+}
+
+func init() {
+    certIssueCmd.Flags().StringVar(&certClient, "client", "", "ACME client: acme.sh or native (defaults to config; after 'cert selfsign' acme.sh when installed, otherwise native)")
+    certIssueCmd.Flags().StringVar(&certDomain, "domain", "", "domain to issue the certificate for (defaults to config)")
+    certIssueCmd.Flags().StringVar(&certEmail, "email", "", "contact email for the ACME account")
+    certIssueCmd.Flags().StringVar(&certChallenge, "challenge", "", "challenge type: http-01, tls-alpn-01 or dns-01")
+    certIssueCmd.Flags().StringVar(&certWebroot, "webroot", "", "serve http-01 challenges from this web root instead of listening on port 80")
+    certIssueCmd.Flags().BoolVar(&certForce, "force", false, "issue a new certificate even if the current one is not due for renewal")
+    certStatusCmd.Flags().BoolVar(&certJSON, "json", false, "print the status as JSON")
+    certRenewCmd.Flags().StringVar(&certWithin, "if-expiring-within", "30d", "only renew when the certificate expires within this duration (e.g. 30d, 720h)")
+    certInstallTimerCmd.Flags().StringVar(&certWithin, "if-expiring-within", "30d", "renewal window passed to 'cert renew'")
+    certInstallTimerCmd.Flags().StringVar(&certOnCalendar, "on-calendar", "daily", "systemd OnCalendar expression for the timer")
+    certSelfSignCmd.Flags().StringVar(&certSNI, "sni", "", "server name for the certificate (defaults to the configured domain)")
+    certSelfSignCmd.Flags().StringSliceVar(&certIPs, "ip", nil, "IP address to include in the certificate (repeatable)")
+    certExportCACmd.Flags().StringVar(&certOut, "out", "", "write the CA certificate to this file instead of stdout")
+    certCmd.AddCommand(withLogFile(certIssueCmd), certStatusCmd, withLogFile(certRenewCmd), certInstallTimerCmd, certSelfSignCmd, certExportCACmd)
+    rootCmd.AddCommand(certCmd)
+}
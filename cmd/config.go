@@ -0,0 +1,154 @@
+package cmd
+
+import (
+    "fmt"
+    "go-auto-proxy/internal/config"
+    "go-auto-proxy/internal/secret"
+    "os"
+    "path/filepath"
+    "strings"
+
+    "github.com/spf13/cobra"
+)
+
+var (
+    configReveal   bool
+    configKeyOut      string
+    configKeyForce    bool
+    configKeyStateDir bool
+)
+
+var configCmd = &cobra.Command{
+    Use:   "config",
+    Short: "Inspect config.json",
+}
+
+var configShowCmd = &cobra.Command{
+    Use:   "show",
+    Short: "Print the configuration with passwords and API tokens redacted",
+    RunE: func(cmd *cobra.Command, args []string) error {
+        info, err := config.ReadConfig()
+        if err != nil {
+            return fmt.Errorf("failed to read config: %v", err)
+        }
+        if !configReveal {
+            return printJSON(info)
+        }
+        fmt.Fprintln(os.Stderr, "Warning: the output below contains secrets, do not paste it into issues or chats.")
+        data, err := secret.MarshalIndentRevealed(info, "", "  ")
+        if err != nil {
+            return err
+        }
+        fmt.Println(string(data))
+        return nil
+    },
+}
+
+var configKeygenCmd = &cobra.Command{
+    Use:   "keygen",
+    Short: "Generate an AES-256 key for encrypting secrets in config.json",
+    Long: `Generate an AES-256 key for encrypting secrets in config.json.
+
+When a key is available, passwords and API tokens are written to config.json
+encrypted with AES-256-GCM and decrypted transparently when the config is read.
+The key is looked up in this order:
+  ` + secret.KeyEnv + `       the base64 key itself
+  ` + secret.KeyFileEnv + `  path to a key file
+  $CREDENTIALS_DIRECTORY/` + secret.CredentialName + `  systemd LoadCredential=
+  ` + secret.KeyFile + `  the default key file
+Keep the key out of any backup that contains config.json. The default key file
+sits next to config.json, so keygen refuses to write there unless
+--allow-state-dir is given.`,
+    RunE: func(cmd *cobra.Command, args []string) error {
+        if inStateDir(configKeyOut) && !configKeyStateDir {
+            return fmt.Errorf(`refusing to write the key to %s: it is in %s next to config.json and would be backed up with it.
+Write it outside the state directory and point go-auto-proxy at it, for example:
+  go-auto-proxy config keygen --out /etc/go-auto-proxy/secret.key
+  export %s=/etc/go-auto-proxy/secret.key
+or load it as a systemd credential:
+  LoadCredential=%s:/etc/go-auto-proxy/secret.key
+Use --allow-state-dir to write it to %s anyway`, configKeyOut, config.StateDir, secret.KeyFileEnv, secret.CredentialName, configKeyOut)
+        }
+        if _, err := os.Stat(configKeyOut); err == nil && !configKeyForce {
+            return fmt.Errorf("%s already exists; secrets encrypted with it would become unreadable, use --force to replace it", configKeyOut)
+        }
+        key, err := secret.GenerateKey()
+        if err != nil {
+            return err
+        }
+        if err := os.MkdirAll(filepath.Dir(configKeyOut), 0700); err != nil {
+            return err
+        }
+        if err := secret.WriteFile(configKeyOut, []byte(key+"\n")); err != nil {
+            return err
+        }
+        fmt.Printf("Secret key written to %s\n", configKeyOut)
+        if configKeyOut != secret.KeyFile {
+            fmt.Printf("Point %s at it, or load it as a systemd credential:\n  LoadCredential=%s:%s\n", secret.KeyFileEnv, secret.CredentialName, configKeyOut)
+        }
+        fmt.Println("Run 'go-auto-proxy config encrypt' to encrypt the secrets already in config.json.")
+        return nil
+    },
+}
+
+// inStateDir 判斷 path 是否位於 config.StateDir 之內
+func inStateDir(path string) bool {
+    abs, err := filepath.Abs(path)
+    if err != nil {
+        return false
+    }
+    dir, err := filepath.Abs(config.StateDir)
+    if err != nil {
+        return false
+    }
+    rel, err := filepath.Rel(dir, abs)
+    return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+var configEncryptCmd = &cobra.Command{
+    Use:   "encrypt",
+    Short: "Rewrite config.json with passwords and API tokens encrypted",
+    RunE: func(cmd *cobra.Command, args []string) error {
+        key, err := secret.LoadKey()
+        if err != nil {
+            return err
+        }
+        if key == nil {
+            return fmt.Errorf("no secret key available, run 'go-auto-proxy config keygen' first")
+        }
+        info, err := config.ReadConfig()
+        if err != nil {
+            return fmt.Errorf("failed to read config: %v", err)
+        }
+        if err := config.WriteConfig(info); err != nil {
+            return fmt.Errorf("failed to write config: %v", err)
+        }
+        fmt.Println("Secrets in config.json are now encrypted.")
+        return nil
+    },
+}
+
+var configDecryptCmd = &cobra.Command{
+    Use:   "decrypt",
+    Short: "Rewrite config.json with passwords and API tokens in clear text",
+    RunE: func(cmd *cobra.Command, args []string) error {
+        info, err := config.ReadConfig()
+        if err != nil {
+            return fmt.Errorf("failed to read config: %v", err)
+        }
+        if err := config.WritePlainConfig(info); err != nil {
+            return fmt.Errorf("failed to write config: %v", err)
+        }
+        fmt.Println("Secrets in config.json are now stored in clear text; remove the key to keep them that way.")
+        return nil
+    },
+}
+
+func init() {
+    configShowCmd.Flags().BoolVar(&configReveal, "reveal", false, "print passwords and API tokens in clear text")
+    configKeygenCmd.Flags().StringVar(&configKeyOut, "out", secret.KeyFile, "where to write the key")
+    configKeygenCmd.Flags().BoolVar(&configKeyForce, "force", false, "replace an existing key")
+    configKeygenCmd.Flags().BoolVar(&configKeyStateDir, "allow-state-dir", false, "allow writing the key into the state directory next to config.json")
+    configCmd.AddCommand(configShowCmd, configKeygenCmd, configEncryptCmd, configDecryptCmd)
+    rootCmd.AddCommand(configCmd)
+}
@@ -0,0 +1,133 @@
+package cmd
+
+import (
+    "context"
+    "fmt"
+    "go-auto-proxy/internal/config"
+    "go-auto-proxy/internal/ddns"
+    "go-auto-proxy/internal/dns"
+    "go-auto-proxy/internal/system"
+    "go-auto-proxy/internal/systemd"
+    "log"
+    "os"
+    "path/filepath"
+
+    "github.com/spf13/cobra"
+)
+
+var (
+    ddnsName       string
+    ddnsInterval   string
+    ddnsOnCalendar string
+)
+
+var ddnsCmd = &cobra.Command{
+    Use:   "ddns",
+    Short: "Keep the domain's A/AAAA record in sync with the external IP",
+}
+
+var ddnsRunCmd = &cobra.Command{
+    Use:   "run",
+    Short: "Check the external IP once, or repeatedly with --interval",
+    RunE: func(cmd *cobra.Command, args []string) error {
+        info, err := config.ReadConfig()
+        if err != nil {
+            return fmt.Errorf("failed to read config: %v", err)
+        }
+        name := info.AcmeSH.Domain
+        if ddnsName != "" {
+            name = ddnsName
+        }
+        if name == "" {
+            return fmt.Errorf("no domain configured, pass --name")
+        }
+        provider, err := dns.New(info.DNS)
+        if err != nil {
+            return fmt.Errorf("%v (run 'go-auto-proxy dns configure')", err)
+        }
+
+        updater := &ddns.Updater{
+            Provider:  provider,
+            Name:      name,
+            TTL:       120,
+            Detect:    detectExternalIP,
+            StatePath: filepath.Join(config.StateDir, "ddns.json"),
+            OnChange:  recordExternalIP,
+        }
+        if ddnsInterval == "" {
+            _, err := updater.Run(cmd.Context())
+            return err
+        }
+
+        interval, err := parseDuration(ddnsInterval)
+        if err != nil {
+            return err
+        }
+        log.Printf("DDNS daemon started for %s, checking every %s.", name, interval)
+        return updater.Loop(cmd.Context(), interval)
+    },
+}
+
+var ddnsInstallTimerCmd = &cobra.Command{
+    Use:   "install-timer",
+    Short: "Install a systemd timer that runs 'ddns run' periodically",
+    RunE: func(cmd *cobra.Command, args []string) error {
+        exe, err := os.Executable()
+        if err != nil {
+            return err
+        }
+        wd, err := os.Getwd()
+        if err != nil {
+            return err
+        }
+        execStart := exe + " ddns run"
+        if ddnsName != "" {
+            execStart += " --name " + ddnsName
+        }
+        return systemd.InstallTimer(systemd.Timer{
+            Name:             "go-auto-proxy-ddns",
+            Description:      "go-auto-proxy dynamic DNS update",
+            ExecStart:        execStart,
+            WorkingDirectory: wd,
+            OnCalendar:       ddnsOnCalendar,
+        })
+    },
+}
+
+// detectExternalIP 以 config.json 的來源設定偵測對外 IPv4 與 IPv6
+func detectExternalIP(ctx context.Context) (system.ExternalIPs, error) {
+    cfg := system.DefaultIPDetection
+    if info, err := config.ReadConfig(); err == nil && len(info.IPDetection.IPv4Providers)+len(info.IPDetection.IPv6Providers) > 0 {
+        cfg = info.IPDetection
+    }
+    return system.DetectExternalIP(ctx, cfg)
+}
+
+// recordExternalIP 將新的對外 IP 寫回 config.json；沒有偵測到的位址族保留原值
+func recordExternalIP(ips system.ExternalIPs) error {
+    info, err := config.ReadConfig()
+    if err != nil {
+        return err
+    }
+    changed := false
+    if ips.IPv4 != "" && info.ExternalIP != ips.IPv4 {
+        info.ExternalIP = ips.IPv4
+        changed = true
+    }
+    if ips.IPv6 != "" && info.ExternalIPv6 != ips.IPv6 {
+        info.ExternalIPv6 = ips.IPv6
+        changed = true
+    }
+    if !changed {
+        return nil
+    }
+    return config.WriteConfig(info)
+}
+
+func init() {
+    ddnsCmd.PersistentFlags().StringVar(&ddnsName, "name", "", "record to keep updated (defaults to the configured domain)")
+    ddnsRunCmd.Flags().StringVar(&ddnsInterval, "interval", "", "run as a daemon and check at this interval (e.g. 5m)")
+    ddnsInstallTimerCmd.Flags().StringVar(&ddnsOnCalendar, "on-calendar", "*:0/5", "systemd OnCalendar expression for the timer")
+    ddnsCmd.AddCommand(withLogFile(ddnsRunCmd), ddnsInstallTimerCmd)
+    rootCmd.AddCommand(ddnsCmd)
+}
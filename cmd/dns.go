@@ -0,0 +1,109 @@
+package cmd
+
+import (
+    "fmt"
+    "go-auto-proxy/internal/config"
+    "go-auto-proxy/internal/dns"
+    "go-auto-proxy/internal/secret"
+    "log"
+    "os"
+    "strings"
+
+    "github.com/spf13/cobra"
+)
+
+var (
+    dnsProvider string
+    dnsZone     string
+)
+
+var dnsCmd = &cobra.Command{
+    Use:   "dns",
+    Short: "Manage the DNS provider used for DNS-01 challenges",
+}
+
+var dnsConfigureCmd = &cobra.Command{
+    Use:   "configure",
+    Short: "Store DNS provider credentials in config.json",
+    Long: `Store DNS provider credentials in config.json (written with 0600 permissions).
+
+Credentials are read from the environment so they stay out of shell history:
+  GO_AUTO_PROXY_DNS_TOKEN       Cloudflare API token, or DNSPod "ID,Token"
+  GO_AUTO_PROXY_DNS_KEY_ID      Alibaba Cloud AccessKey ID
+  GO_AUTO_PROXY_DNS_KEY_SECRET  Alibaba Cloud AccessKey Secret
+Missing values are prompted for on stdin.`,
+    RunE: func(cmd *cobra.Command, args []string) error {
+        info, err := config.ReadConfig()
+        if err != nil {
+            return fmt.Errorf("failed to read config (run 'go-auto-proxy init' first): %v", err)
+        }
+
+        cfg := dns.Config{Provider: dnsProvider, Zone: dnsZone}
+        switch dnsProvider {
+        case "cloudflare", "dnspod":
+            token, err := credential("GO_AUTO_PROXY_DNS_TOKEN", "API token")
+            if err != nil {
+                return err
+            }
+            cfg.APIToken = secret.Secret(token)
+        case "alidns":
+            if cfg.AccessKeyID, err = credential("GO_AUTO_PROXY_DNS_KEY_ID", "AccessKey ID"); err != nil {
+                return err
+            }
+            key, err := credential("GO_AUTO_PROXY_DNS_KEY_SECRET", "AccessKey Secret")
+            if err != nil {
+                return err
+            }
+            cfg.AccessKeySecret = secret.Secret(key)
+        default:
+            return fmt.Errorf("unsupported DNS provider %q (use cloudflare, alidns or dnspod)", dnsProvider)
+        }
+
+        provider, err := dns.New(cfg)
+        if err != nil {
+            return err
+        }
+        name := cfg.Zone
+        if name == "" {
+            name = info.AcmeSH.Domain
+        }
+        if name != "" {
+            if _, err := provider.FindRecords(cmd.Context(), name, "A"); err != nil {
+                return fmt.Errorf("failed to verify %s credentials: %v", dnsProvider, err)
+            }
+            log.Printf("%s credentials verified for %s.", dnsProvider, name)
+        }
+
+        info.DNS = cfg
+        if err := config.WriteConfig(info); err != nil {
+            return fmt.Errorf("failed to update config: %v", err)
+        }
+        log.Printf("DNS provider %s saved to config.json. Use 'go-auto-proxy cert issue --challenge dns-01' to issue via DNS-01.", dnsProvider)
+        return nil
+    },
+}
+
+// credential 從環境變數讀取憑證，未設定時提示輸入
+func credential(env, label string) (string, error) {
+    if v := os.Getenv(env); v != "" {
+        return v, nil
+    }
+    fmt.Printf("%s (or set %s): ", label, env)
+    var input string
+    if _, err := fmt.Scanln(&input); err != nil {
+        return "", fmt.Errorf("failed to read input: %v", err)
+    }
+    input = strings.TrimSpace(input)
+    if input == "" {
+        return "", fmt.Errorf("%s is required", label)
+    }
+    return input, nil
+}
+
+func init() {
+    dnsConfigureCmd.Flags().StringVar(&dnsProvider, "provider", "", "DNS provider: cloudflare, alidns or dnspod")
+    dnsConfigureCmd.Flags().StringVar(&dnsZone, "zone", "", "DNS zone containing the domain, e.g. example.com (guessed when empty)")
+    dnsConfigureCmd.MarkFlagRequired("provider")
+    dnsCmd.AddCommand(dnsConfigureCmd)
+    rootCmd.AddCommand(dnsCmd)
+}
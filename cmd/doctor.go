@@ -0,0 +1,89 @@
+package cmd
+
+import (
+    "fmt"
+    "go-auto-proxy/internal/config"
+    "go-auto-proxy/internal/doctor"
+    "go-auto-proxy/internal/system"
+    "os"
+    "text/tabwriter"
+    "time"
+
+    "github.com/spf13/cobra"
+)
+
+var doctorJSON bool
+
+// downloadHosts 是安裝過程需要連線的主機
+var downloadHosts = []string{"github.com", "raw.githubusercontent.com", "get.acme.sh", "download.zerotier.com", "acme-v02.api.letsencrypt.org"}
+
+var doctorCmd = &cobra.Command{
+    Use:   "doctor",
+    Short: "Check the host for common problems before and after init",
+    RunE: func(cmd *cobra.Command, args []string) error {
+        // 尚未執行 init 時以偵測到的系統資訊檢查
+        info, err := config.ReadConfig()
+        if err != nil {
+            info = system.GetSystemInfo(cmd.Context())
+        }
+
+        results := doctor.Run(cmd.Context(), doctorChecks(info))
+        failed := doctor.Count(results, doctor.Fail)
+        if doctorJSON {
+            if err := printJSON(results); err != nil {
+                return err
+            }
+        } else {
+            printDoctorResults(results)
+        }
+        if failed > 0 {
+            cmd.SilenceUsage = true
+            return fmt.Errorf("%d check(s) failed", failed)
+        }
+        return nil
+    },
+}
+
+// doctorChecks 依配置組合要執行的檢查
+func doctorChecks(info system.SystemInfo) []doctor.Check {
+    wd, _ := os.Getwd()
+    return []doctor.Check{
+        doctor.SudoCheck(),
+        doctor.PortCheck(info.TrojanGo.Port, "trojan-go"),
+        doctor.PortCheck(80, "nginx"),
+        doctor.DomainCheck(info.AcmeSH.Domain, info.ExternalIP),
+        doctor.ClockCheck("https://acme-v02.api.letsencrypt.org/directory"),
+        doctor.DiskCheck(wd, 2<<30, 512<<20),
+        doctor.MemoryCheck(256<<20, 64<<20),
+        doctor.BBRCheck(),
+        doctor.ReachabilityCheck(downloadHosts...),
+        doctor.CertCheck(info.AcmeSH.CertPath, info.AcmeSH.KeyPath, 14*24*time.Hour),
+        doctor.ServiceCheck("trojan-go", "nginx", "fail2ban", "zerotier-one"),
+    }
+}
+
+// printDoctorResults 以表格輸出結果，未通過的項目附上修復建議
+func printDoctorResults(results []doctor.Result) {
+    w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+    fmt.Fprintln(w, "STATUS\tCHECK\tDETAILS")
+    for _, r := range results {
+        fmt.Fprintf(w, "%s\t%s\t%s\n", r.Status, r.Name, r.Message)
+    }
+    w.Flush()
+
+    if doctor.Count(results, doctor.Pass) < len(results) {
+        fmt.Println("\nHints:")
+    }
+    for _, r := range results {
+        if r.Hint != "" && r.Status != doctor.Pass {
+            fmt.Printf("  - %s: %s\n", r.Name, r.Hint)
+        }
+    }
+    fmt.Printf("\n%d passed, %d warnings, %d failed\n",
+        doctor.Count(results, doctor.Pass), doctor.Count(results, doctor.Warn), doctor.Count(results, doctor.Fail))
+}
+
+func init() {
+    doctorCmd.Flags().BoolVar(&doctorJSON, "json", false, "print the results as JSON")
+    rootCmd.AddCommand(doctorCmd)
+}
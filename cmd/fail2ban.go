@@ -0,0 +1,93 @@
+package cmd
+
+import (
+    "fmt"
+    "go-auto-proxy/internal/config"
+    "go-auto-proxy/internal/fail2ban"
+    "go-auto-proxy/internal/report"
+    "go-auto-proxy/internal/system"
+    "log"
+    "sort"
+
+    "github.com/spf13/cobra"
+)
+
+var fail2banDryRun bool
+
+var fail2banCmd = &cobra.Command{
+    Use:   "fail2ban",
+    Short: "Manage fail2ban jails for the monitored services",
+}
+
+var fail2banConfigureCmd = &cobra.Command{
+    Use:   "configure",
+    Short: "Generate jails and filters from fail2ban.monitored_items in config.json and reload fail2ban",
+    RunE: func(cmd *cobra.Command, args []string) error {
+        info, err := config.ReadConfig()
+        if err != nil {
+            return fmt.Errorf("failed to read config: %v", err)
+        }
+        opts := fail2banOptions(info)
+        if !fail2banDryRun {
+            return fail2ban.Apply(opts)
+        }
+
+        files, err := fail2ban.Render(opts)
+        if err != nil {
+            return err
+        }
+        // 依路徑排序，讓兩次輸出可以直接比對
+        paths := make([]string, 0, len(files))
+        for path := range files {
+            paths = append(paths, path)
+        }
+        sort.Strings(paths)
+        for _, path := range paths {
+            fmt.Printf("==> %s\n%s\n", path, files[path])
+        }
+        return nil
+    },
+}
+
+// fail2banOptions 由 config.json 的 fail2ban 區塊建立 jail 生成選項
+func fail2banOptions(info system.SystemInfo) fail2ban.Options {
+    return fail2ban.Options{
+        Items: info.Fail2Ban.MonitoredItems,
+        Defaults: fail2ban.Settings{
+            BanTime:  info.Fail2Ban.BanTime,
+            FindTime: info.Fail2Ban.FindTime,
+            MaxRetry: info.Fail2Ban.MaxRetry,
+        },
+        Overrides:  info.Fail2Ban.Jails,
+        TrojanPort: info.TrojanGo.Port,
+        IgnoreIP:   info.Fail2Ban.Allowlist,
+    }
+}
+
+// configureFail2Ban 在 init 結束時套用 jail；失敗只記錄在日誌與報告中，不影響其他步驟
+func configureFail2Ban(info system.SystemInfo, c *report.Component) {
+    if len(info.Fail2Ban.MonitoredItems) == 0 {
+        return
+    }
+    opts := fail2banOptions(info)
+    if err := fail2ban.Apply(opts); err != nil {
+        log.Println("Failed to configure fail2ban:", err)
+        log.Println("Fix fail2ban settings in config.json and run 'go-auto-proxy fail2ban configure'.")
+        c.Fail(err)
+        return
+    }
+    if files, err := fail2ban.Render(opts); err == nil {
+        paths := make([]string, 0, len(files))
+        for path := range files {
+            paths = append(paths, path)
+        }
+        sort.Strings(paths)
+        c.AddFiles(paths...)
+    }
+}
+
+func init() {
+    fail2banConfigureCmd.Flags().BoolVar(&fail2banDryRun, "dry-run", false, "print the generated files without writing them")
+    fail2banCmd.AddCommand(fail2banConfigureCmd)
+    rootCmd.AddCommand(fail2banCmd)
+}
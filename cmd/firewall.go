@@ -0,0 +1,157 @@
+package cmd
+
+import (
+    "fmt"
+    "go-auto-proxy/internal/config"
+    "go-auto-proxy/internal/firewall"
+    "go-auto-proxy/internal/system"
+    "go-auto-proxy/internal/trojan"
+    "go-auto-proxy/internal/zerotier"
+
+    "github.com/spf13/cobra"
+)
+
+var firewallDryRun bool
+
+var firewallCmd = &cobra.Command{
+    Use:   "firewall",
+    Short: "Manage host firewall rules for trojan-go, HTTP, SSH and ZeroTier",
+}
+
+var firewallApplyCmd = &cobra.Command{
+    Use:   "apply",
+    Short: "Detect ufw, firewalld, nftables or iptables and allow the ports from config.json",
+    RunE: func(cmd *cobra.Command, args []string) error {
+        info, err := config.ReadConfig()
+        if err != nil {
+            return fmt.Errorf("failed to read config: %v", err)
+        }
+        if !firewallDryRun {
+            return syncFirewall(info, info.TrojanGo.Port)
+        }
+
+        fw, err := firewall.Detect(info.Firewall.Backend)
+        if err != nil {
+            return err
+        }
+        name := "none"
+        if fw != nil {
+            name = fw.Name()
+        }
+        fmt.Printf("Firewall: %s\n", name)
+        for _, rule := range firewallRules(info) {
+            fmt.Printf("  allow %s\n", rule)
+        }
+        return nil
+    },
+}
+
+var firewallRemoveCmd = &cobra.Command{
+    Use:   "remove",
+    Short: "Remove every firewall rule tagged " + firewall.Tag,
+    RunE: func(cmd *cobra.Command, args []string) error {
+        return firewall.Cleanup()
+    },
+}
+
+// firewallRules 返回需要放行的端口：SSH 永遠保留，以免啟用防火牆後失去連線；
+// zerotier 模式下 trojan-go 只對 ZeroTier 介面放行，不在公網開放
+func firewallRules(info system.SystemInfo) []firewall.Rule {
+    var rules []firewall.Rule
+    for _, port := range firewall.SSHPorts() {
+        rules = append(rules, firewall.Rule{Port: port, Proto: "tcp"})
+    }
+    rules = append(rules, firewall.Rule{Port: 80, Proto: "tcp"}, trojanRule(info))
+    if info.Firewall.ZeroTier {
+        if len(info.Firewall.ZeroTierSources) == 0 {
+            rules = append(rules, firewall.Rule{Port: zerotier.Port, Proto: "udp"})
+        }
+        for _, source := range info.Firewall.ZeroTierSources {
+            rules = append(rules, firewall.Rule{Port: zerotier.Port, Proto: "udp", Source: source})
+        }
+    }
+    return rules
+}
+
+// trojanRule 返回 trojan-go 端口的放行規則
+func trojanRule(info system.SystemInfo) firewall.Rule {
+    rule := firewall.Rule{Port: info.TrojanGo.Port, Proto: "tcp"}
+    if info.TrojanGo.Mode == trojan.ModeZeroTier && info.ZeroTier.Device != "" {
+        rule.Interface = info.ZeroTier.Device
+    }
+    return rule
+}
+
+// syncFirewall 放行目前的端口，並移除 oldPort 與另一種模式留下的 trojan-go 規則；
+// zerotier 模式下重新插入介面限制，確保它排在放行規則之前。規則最後由 Apply 保存
+func syncFirewall(info system.SystemInfo, oldPort int) error {
+    port := info.TrojanGo.Port
+    fw, err := firewall.Detect(info.Firewall.Backend)
+    if err != nil {
+        return err
+    }
+    rules := firewallRules(info)
+    if fw != nil {
+        for _, stale := range staleTrojanRules(info, oldPort) {
+            if containsRule(rules, stale) {
+                continue
+            }
+            if err := fw.Remove(stale); err != nil {
+                return fmt.Errorf("failed to remove %s: %v", stale, err)
+            }
+        }
+    }
+    if err := firewall.Apply(fw, rules); err != nil {
+        return err
+    }
+
+    if info.ZeroTier.Device == "" {
+        return nil
+    }
+    ports := []int{port}
+    if oldPort != port {
+        ports = append(ports, oldPort)
+    }
+    for _, p := range ports {
+        if err := firewall.RemoveInterfaceRestriction(p, info.ZeroTier.Device); err != nil {
+            return err
+        }
+    }
+    if info.TrojanGo.Mode == trojan.ModeZeroTier {
+        return firewall.RestrictToInterface(port, info.ZeroTier.Device)
+    }
+    return nil
+}
+
+// staleTrojanRules 返回目前與舊端口在公網及 ZeroTier 介面上的所有 trojan-go 規則，
+// 由呼叫者略過仍需要的規則
+func staleTrojanRules(info system.SystemInfo, oldPort int) []firewall.Rule {
+    ports := []int{info.TrojanGo.Port}
+    if oldPort != info.TrojanGo.Port {
+        ports = append(ports, oldPort)
+    }
+    var stale []firewall.Rule
+    for _, port := range ports {
+        stale = append(stale, firewall.Rule{Port: port, Proto: "tcp"})
+        if info.ZeroTier.Device != "" {
+            stale = append(stale, firewall.Rule{Port: port, Proto: "tcp", Interface: info.ZeroTier.Device})
+        }
+    }
+    return stale
+}
+
+// containsRule 判斷 rules 中是否有相同的規則
+func containsRule(rules []firewall.Rule, rule firewall.Rule) bool {
+    for _, r := range rules {
+        if r == rule {
+            return true
+        }
+    }
+    return false
+}
+
+func init() {
+    firewallApplyCmd.Flags().BoolVar(&firewallDryRun, "dry-run", false, "print the detected firewall and rules without changing anything")
+    firewallCmd.AddCommand(firewallApplyCmd, firewallRemoveCmd)
+    rootCmd.AddCommand(firewallCmd)
+}
@@ -1,79 +1,283 @@
 package cmd
 
 import (
+    "context"
+    "errors"
     "fmt"
     "go-auto-proxy/internal/config"
     "go-auto-proxy/internal/installer"
+    "go-auto-proxy/internal/logging"
+    "go-auto-proxy/internal/nginx"
+    "go-auto-proxy/internal/report"
     "go-auto-proxy/internal/system"
-    "io"
+    "go-auto-proxy/internal/zerotier"
     "log"
+    "log/slog"
     "os"
     "os/exec"
     "os/user"
     "path/filepath"
+    "regexp"
     "strings"
+    "time"
 
     "github.com/spf13/cobra"
 )
 
+var (
+    acmeClient      string
+    domain          string
+    email           string
+    zeroTierNetwork string
+    trojanPort      string
+    resumeInit      bool
+)
+
 var initCmd = &cobra.Command{
     Use:   "init",
     Short: "Initialize go-auto-proxy and install dependencies",
-    Run: func(cmd *cobra.Command, args []string) {
-        logFile, err := os.OpenFile("go-auto-proxy.log", os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0666)
-        if err != nil {
-            fmt.Printf("Failed to open log file: %v\n", err)
-            return
-        }
-        defer logFile.Close()
-
-        mw := io.MultiWriter(os.Stdout, logFile)
-        log.SetOutput(mw)
-        log.SetFlags(log.Ldate | log.Ltime)
-
+    RunE: func(cmd *cobra.Command, args []string) (runErr error) {
+        defer logging.SetStep("")
+        // 失敗時以非零狀態結束，自動化流程不必解析報告也能判斷；中斷由 Execute 以 130 結束
+        result := report.ResultFailed
+        defer func() {
+            if result == report.ResultFailed {
+                cmd.SilenceUsage = true
+                runErr = fmt.Errorf("init failed, see the errors above")
+            }
+        }()
+        logging.SetStep("preflight")
         log.Println("Initializing go-auto-proxy...")
+        if logPath != "" {
+            log.Printf("Logging to %s", logPath)
+        }
 
         if err := checkDirPermissions(); err != nil {
-            log.Println(err)
+            slog.Error(err.Error())
             return
         }
 
         if err := checkUserAndSudo(); err != nil {
-            log.Println(err)
+            slog.Error(err.Error())
             return
         }
 
-        sysInfo := system.GetSystemInfo()
-        log.Printf("System Info: %+v", sysInfo)
-        log.Printf("ZeroTier Network ID: %s (update config.json to join a network)", sysInfo.ZeroTier.NetworkID)
-        log.Printf("acme.sh Path: %s, Provider: %s", sysInfo.AcmeSH.Path, sysInfo.AcmeSH.Provider)
-        log.Printf("trojan-go Port: %d, Password: %s", sysInfo.TrojanGo.Port, sysInfo.TrojanGo.Password)
-        log.Printf("fail2ban Monitored Items: %v", sysInfo.Fail2Ban.MonitoredItems)
+        rep := report.New()
+        rep.LogFile = logPath
+        defer func() { finishReport(rep, result) }()
 
-        if err := handleTrojanGoDir(); err != nil {
-            log.Println(err)
-            return
+        ctx := cmd.Context()
+        var sysInfo system.SystemInfo
+        var state config.InitState
+        if resumeInit {
+            logging.SetStep("resume")
+            previous, err := config.ReadInitState()
+            if os.IsNotExist(err) {
+                log.Println("Error: no interrupted init to resume; run 'go-auto-proxy init' instead.")
+                return
+            } else if err != nil {
+                slog.Error(err.Error())
+                return
+            }
+            if sysInfo, err = config.ReadConfig(); err != nil {
+                log.Println("Error reading config:", err)
+                return
+            }
+            state = previous
+            rep.Component("config").Detail = "reused the existing " + config.Path()
+            log.Printf("Resuming init from step %s (completed: %s).", state.Step, strings.Join(state.Completed, ", "))
+        } else {
+            logging.SetStep("system-info")
+            sysInfo = system.GetSystemInfo(ctx)
+            if acmeClient != "acme.sh" && acmeClient != "native" {
+                log.Printf("Unsupported ACME client %q (use acme.sh or native)", acmeClient)
+                return
+            }
+            sysInfo.AcmeSH.Client = acmeClient
+            sysInfo.AcmeSH.Domain = domain
+            sysInfo.AcmeSH.Email = email
+            sysInfo.ZeroTier.NetworkID = zeroTierNetwork
+            port, err := resolveTrojanPort(trojanPort, sysInfo)
+            if err != nil {
+                slog.Error(err.Error())
+                return
+            }
+            sysInfo.TrojanGo.Port = port
+            log.Printf("System Info: %+v", sysInfo)
+            log.Printf("ZeroTier Network ID: %s", sysInfo.ZeroTier.NetworkID)
+            log.Printf("acme.sh Path: %s, Provider: %s", sysInfo.AcmeSH.Path, sysInfo.AcmeSH.Provider)
+            log.Printf("trojan-go Port: %d, Password: %s (run 'go-auto-proxy config show --reveal' to see it)", sysInfo.TrojanGo.Port, sysInfo.TrojanGo.Password)
+            log.Printf("fail2ban Monitored Items: %v", sysInfo.Fail2Ban.MonitoredItems)
+            warnCloudFirewall(sysInfo)
+            if ctx.Err() != nil {
+                log.Println("Interrupted before anything was changed.")
+                result = report.ResultInterrupted
+                return
+            }
+
+            logging.SetStep("config")
+            if err := handleTrojanGoDir(); err != nil {
+                slog.Error(err.Error())
+                return
+            }
+
+            c := rep.Component("config")
+            if err := config.WriteConfig(sysInfo); err != nil {
+                log.Println("Error writing config:", err)
+                c.Fail(err)
+                return
+            }
+            c.Status = report.StatusInstalled
+            c.AddFiles(config.Path())
+        }
+
+        // 從這裡開始會修改系統，進度寫入狀態檔，中斷或失敗後可以 init --resume 繼續
+        stopAt := func(step string, err error) {
+            state.Step = step
+            state.Interrupted = ctx.Err() != nil
+            state.Error = err.Error()
+            if state.Interrupted {
+                result = report.ResultInterrupted
+            }
+            if err := config.WriteInitState(state); err != nil {
+                log.Println("Failed to record init progress:", err)
+            }
+            if state.Interrupted {
+                log.Printf("Warning: interrupted during step %s.", step)
+            } else {
+                log.Printf("Error in step %s: %v", step, err)
+            }
+            log.Println("Fix the problem if needed, then run 'go-auto-proxy init --resume' to continue from this step.")
+        }
+        // phase 開始下一個階段；已收到中斷訊號時記錄進度並返回 false
+        phase := func(step string) bool {
+            if err := ctx.Err(); err != nil {
+                stopAt(step, err)
+                return false
+            }
+            logging.SetStep(step)
+            state.Step = step
+            if err := config.WriteInitState(state); err != nil {
+                log.Println("Failed to record init progress:", err)
+            }
+            return true
         }
 
-        err = config.WriteConfig(sysInfo)
+        if !phase("install") {
+            return
+        }
+        opts := installer.Options{
+            SkipAcmeSH: sysInfo.AcmeSH.Client == "native",
+            Completed:  state.IsCompleted,
+            Overrides:  sysInfo.Install,
+            OnStepDone: func(step string) {
+                state.Completed = append(state.Completed, step)
+                if err := config.WriteInitState(state); err != nil {
+                    log.Println("Failed to record init progress:", err)
+                }
+            },
+        }
+        results, err := installer.InstallDependencies(ctx, opts)
+        for _, r := range results {
+            c := rep.Component(r.Step)
+            c.Status = r.Status
+            c.Attempts = r.Attempts
+            c.Duration = r.Duration
+            if r.Err != nil {
+                c.Errors = append(c.Errors, r.Err.Error())
+            }
+            if r.Status == installer.StatusSkipped && state.IsCompleted(r.Step) {
+                c.Detail = "completed in a previous run"
+            }
+            if r.Step == "trojan-go" && r.Status == installer.StatusInstalled {
+                c.AddFiles(filepath.Join("trojan-go", "trojan-go"))
+            }
+        }
         if err != nil {
-            log.Println("Error writing config:", err)
+            step := "install"
+            var stepErr *installer.StepError
+            if errors.As(err, &stepErr) {
+                step = "install/" + stepErr.Step
+            }
+            stopAt(step, err)
             return
         }
 
-        if err := installer.InstallDependencies(); err != nil {
-            log.Println("Error installing dependencies:", err)
+        if !phase("verify") {
             return
         }
-
-        if err := testInstalledTools(); err != nil {
+        if err := testInstalledTools(ctx, sysInfo, rep); err != nil {
             log.Println("Some tools failed verification:", err)
         }
 
+        if !phase("ports") {
+            return
+        }
+        if err := syncFirewall(sysInfo, sysInfo.TrojanGo.Port); err != nil {
+            log.Println("Failed to configure the firewall for the trojan-go port:", err)
+            rep.Component("firewall").Fail(err)
+        } else {
+            rep.Component("firewall").Status = report.StatusInstalled
+        }
+        if err := nginx.Apply(nginx.Site{Domain: sysInfo.AcmeSH.Domain, Webroot: sysInfo.AcmeSH.Webroot, TrojanPort: sysInfo.TrojanGo.Port}); err != nil {
+            log.Println("Failed to configure nginx for the trojan-go port:", err)
+            rep.Component("nginx").Fail(err)
+        } else {
+            rep.Component("nginx").AddFiles(nginx.SitePath())
+        }
+        if !phase("fail2ban") {
+            return
+        }
+        configureFail2Ban(sysInfo, rep.Component("fail2ban"))
+        if !phase("tune") {
+            return
+        }
+        configureTune(sysInfo, rep.Component("tune"))
+
+        if !phase("zerotier") {
+            return
+        }
+        if sysInfo.ZeroTier.NetworkID != "" {
+            if err := joinZeroTier(ctx, "", 2*time.Minute); err != nil {
+                log.Println("Failed to join ZeroTier network:", err)
+                rep.Component("zerotier").Fail(err)
+            }
+        } else {
+            log.Println("Run 'go-auto-proxy zerotier join <network-id>' to join a ZeroTier network.")
+        }
+
+        if sysInfo.AcmeSH.Domain != "" {
+            log.Println("Run 'go-auto-proxy cert issue' to obtain a certificate for", sysInfo.AcmeSH.Domain)
+        }
+
+        if ctx.Err() != nil {
+            stopAt("zerotier", ctx.Err())
+            return
+        }
+        if err := config.ClearInitState(); err != nil {
+            log.Println("Failed to remove the init progress file:", err)
+        }
+        logging.SetStep("")
+        result = report.ResultCompleted
         log.Println("Initialization completed.")
+        return nil
     },
 }
 
+// finishReport 輸出 init 的結果表格，並將 JSON 報告寫在 config.json 旁邊
+func finishReport(rep *report.Report, result string) {
+    rep.Finish(result)
+    fmt.Println()
+    if err := rep.WriteTable(os.Stdout); err != nil {
+        log.Println("Failed to print the init report:", err)
+    }
+    if err := rep.WriteJSON(config.ReportFile); err != nil {
+        log.Println("Failed to write the init report:", err)
+        return
+    }
+    log.Printf("Report written to %s", config.ReportFile)
+}
+
 // checkDirPermissions 檢查當前目錄的寫入與刪除權限
 func checkDirPermissions() error {
     testFile := "test-permission-file"
@@ -150,48 +354,61 @@ func checkUserAndSudo() error {
     return nil
 }
 
-// testInstalledTools 測試已安裝的工具是否可用
-func testInstalledTools() error {
+// testInstalledTools 測試已安裝的工具是否可用，並將版本或錯誤記錄在報告中
+func testInstalledTools(ctx context.Context, info system.SystemInfo, rep *report.Report) error {
     var errors []string
+    fail := func(component, msg string) {
+        errors = append(errors, msg)
+        rep.Component(component).Fail(fmt.Errorf("%s", msg))
+    }
 
     // 測試 trojan-go
     trojanPath := filepath.Join("trojan-go", "trojan-go")
     if _, err := os.Stat(trojanPath); err != nil {
-        errors = append(errors, fmt.Sprintf("trojan-go not found at %s: %v", trojanPath, err))
-    } else if err := exec.Command(trojanPath, "--version").Run(); err != nil {
-        errors = append(errors, fmt.Sprintf("trojan-go failed to run: %v", err))
+        fail("trojan-go", fmt.Sprintf("trojan-go not found at %s: %v", trojanPath, err))
+    } else if version, err := toolVersion(ctx, trojanPath, "--version"); err != nil {
+        fail("trojan-go", fmt.Sprintf("trojan-go failed to run: %v", err))
     } else {
+        rep.Component("trojan-go").Version = version
         log.Println("trojan-go verified successfully.")
     }
 
-    // 測試 acme.sh
-    homeDir, _ := os.UserHomeDir()
-    acmePath := filepath.Join(homeDir, ".acme.sh", "acme.sh")
-    if err := exec.Command(acmePath, "--version").Run(); err != nil {
-        errors = append(errors, fmt.Sprintf("acme.sh failed to run: %v (ensure it’s installed correctly at %s)", err, acmePath))
-    } else {
-        log.Println("acme.sh verified successfully.")
+    // 測試 acme.sh（使用內建 ACME 客戶端時不需要）
+    if info.AcmeSH.Client != "native" {
+        homeDir, _ := os.UserHomeDir()
+        acmePath := filepath.Join(homeDir, ".acme.sh", "acme.sh")
+        if version, err := toolVersion(ctx, acmePath, "--version"); err != nil {
+            fail("acme.sh", fmt.Sprintf("acme.sh failed to run: %v (ensure it’s installed correctly at %s)", err, acmePath))
+        } else {
+            rep.Component("acme.sh").Version = version
+            log.Println("acme.sh verified successfully.")
+        }
     }
 
     // 測試 nginx
-    if err := exec.Command("nginx", "-v").Run(); err != nil {
-        errors = append(errors, fmt.Sprintf("nginx failed to run: %v (ensure it’s installed correctly)", err))
+    if version, err := toolVersion(ctx, "nginx", "-v"); err != nil {
+        fail("nginx", fmt.Sprintf("nginx failed to run: %v (ensure it’s installed correctly)", err))
     } else {
+        rep.Component("nginx").Version = version
         log.Println("nginx verified successfully.")
     }
 
     // 測試 fail2ban
-    if err := exec.Command("fail2ban-client", "version").Run(); err != nil {
-        errors = append(errors, fmt.Sprintf("fail2ban failed to run: %v (ensure it’s installed correctly)", err))
+    if version, err := toolVersion(ctx, "fail2ban-client", "version"); err != nil {
+        fail("fail2ban", fmt.Sprintf("fail2ban failed to run: %v (ensure it’s installed correctly)", err))
     } else {
+        rep.Component("fail2ban").Version = version
         log.Println("fail2ban verified successfully.")
     }
 
-    // 測試 zerotier
-    if err := exec.Command("zerotier-cli", "-v").Run(); err != nil {
-        errors = append(errors, fmt.Sprintf("zerotier failed to run: %v (ensure it’s installed correctly)", err))
+    // 測試 zerotier：透過本地 API 確認服務已啟動
+    if status, err := zeroTierStatus(ctx); err != nil {
+        fail("zerotier", fmt.Sprintf("zerotier failed to respond: %v (ensure zerotier-one is installed and running)", err))
     } else {
-        log.Println("zerotier verified successfully.")
+        c := rep.Component("zerotier")
+        c.Version = status.Version
+        c.Detail = fmt.Sprintf("node %s, online: %t", status.Address, status.Online)
+        log.Printf("zerotier verified successfully (node %s, version %s, online: %t).", status.Address, status.Version, status.Online)
     }
 
     if len(errors) > 0 {
@@ -200,6 +417,64 @@ func testInstalledTools() error {
     return nil
 }
 
+// versionPattern 匹配版本命令輸出中的版本號，如 v0.10.6、nginx/1.18.0 中的 1.18.0
+var versionPattern = regexp.MustCompile(`v?\d+(\.\d+)+`)
+
+// toolVersion 執行版本命令並返回輸出中第一個版本號；找不到時返回第一行輸出
+func toolVersion(ctx context.Context, name string, args ...string) (string, error) {
+    out, err := exec.CommandContext(ctx, name, args...).CombinedOutput()
+    if err != nil {
+        return "", err
+    }
+    return parseVersion(string(out)), nil
+}
+
+func parseVersion(output string) string {
+    if version := versionPattern.FindString(output); version != "" {
+        return version
+    }
+    line, _, _ := strings.Cut(strings.TrimSpace(output), "\n")
+    return line
+}
+
+// warnCloudFirewall 提醒雲端主機的外部防火牆也必須放行 trojan-go 與 HTTP 端口
+func warnCloudFirewall(info system.SystemInfo) {
+    cloud := info.Cloud
+    if cloud.Provider == system.CloudNone || cloud.Provider == "" {
+        return
+    }
+    log.Printf("Cloud: %s, region %s, zone %s, instance %s", cloud.Provider, cloud.Region, cloud.Zone, cloud.InstanceID)
+    port := info.TrojanGo.Port
+    switch cloud.Provider {
+    case system.CloudGCE:
+        log.Printf("Warning: the GCE VPC firewall must allow tcp:%d and tcp:80, e.g.:", port)
+        log.Printf("  gcloud compute firewall-rules create go-auto-proxy --allow tcp:%d,tcp:80 --source-ranges 0.0.0.0/0", port)
+    case system.CloudAWS:
+        log.Printf("Warning: the instance security group must allow inbound TCP %d and 80.", port)
+    case system.CloudAzure:
+        log.Printf("Warning: the network security group must allow inbound TCP %d and 80.", port)
+    case system.CloudOracle:
+        log.Printf("Warning: the VCN security list must allow ingress TCP %d and 80, and the image's iptables rules may also block them.", port)
+    }
+}
+
+// zeroTierStatus 查詢本機 ZeroTier 服務狀態
+func zeroTierStatus(ctx context.Context) (zerotier.Status, error) {
+    client, err := zerotier.NewClient()
+    if err != nil {
+        return zerotier.Status{}, err
+    }
+    ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+    defer cancel()
+    return client.Status(ctx)
+}
+
 func init() {
-    rootCmd.AddCommand(initCmd)
+    initCmd.Flags().StringVar(&acmeClient, "acme-client", "acme.sh", "ACME client to use for certificates: acme.sh or native")
+    initCmd.Flags().StringVar(&domain, "domain", "", "domain name (SNI) to request a certificate for")
+    initCmd.Flags().StringVar(&email, "email", "", "contact email for the ACME account")
+    initCmd.Flags().StringVar(&trojanPort, "port", "443", "trojan-go port, or auto to pick a free one from trojan_go.port_range")
+    initCmd.Flags().BoolVar(&resumeInit, "resume", false, "continue an interrupted or failed init from "+config.InitStatePath+" using the existing config.json")
+    initCmd.Flags().StringVar(&zeroTierNetwork, "zerotier-network", "", "ZeroTier network ID to join after installation")
+    rootCmd.AddCommand(withLogFile(initCmd))
 }
\ No newline at end of file
@@ -1,23 +1,97 @@
 package cmd
 
 import (
+    "context"
     "fmt"
+    "go-auto-proxy/internal/config"
+    "go-auto-proxy/internal/logging"
     "os"
+    "os/signal"
+    "path/filepath"
+    "strings"
+    "syscall"
 
     "github.com/spf13/cobra"
 )
 
+// logFileAnnotation 標記需要寫入日誌檔的命令（安裝、憑證與 DDNS 等會修改系統的長時間作業）
+const logFileAnnotation = "go-auto-proxy/log-file"
+
+var (
+    logLevel  string
+    logFormat string
+    logDir    string
+
+    // logPath 為本次執行的日誌檔，未寫檔時為空
+    logPath  string
+    closeLog = func() error { return nil }
+)
+
 // rootCmd 表示 CLI 的根命令
 var rootCmd = &cobra.Command{
     Use:   "go-auto-proxy",
     Short: "A CLI tool for automating proxy setup",
     Long:  `go-auto-proxy is a command-line tool to automate proxy-related setup, including system info collection and dependency installation.`,
+    PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+        return setupLogging(cmd)
+    },
+}
+
+// setupLogging 依 --log-level、--log-format 與 --log-dir 設定日誌；
+// 帶有 logFileAnnotation 的命令每次執行寫入各自的檔案
+func setupLogging(cmd *cobra.Command) error {
+    opts := logging.DefaultOptions()
+    opts.Level = logLevel
+    opts.Format = logFormat
+    opts.Dir = ""
+    if cmd.Annotations[logFileAnnotation] != "" {
+        opts.Dir = logDir
+        opts.FallbackDir = filepath.Join(config.StateDir, "logs")
+        opts.Name = strings.ReplaceAll(strings.TrimPrefix(cmd.CommandPath(), cmd.Root().Name()+" "), " ", "-")
+    }
+    path, closeFn, err := logging.Setup(opts)
+    if err != nil {
+        return err
+    }
+    logPath = path
+    closeLog = closeFn
+    return nil
 }
 
-// Execute 執行根命令
+// withLogFile 讓命令寫入日誌檔
+func withLogFile(cmd *cobra.Command) *cobra.Command {
+    if cmd.Annotations == nil {
+        cmd.Annotations = map[string]string{}
+    }
+    cmd.Annotations[logFileAnnotation] = "true"
+    return cmd
+}
+
+// Execute 執行根命令；SIGINT/SIGTERM 取消命令的 context，
+// 第二次收到訊號時恢復預設行為立即結束
 func Execute() {
-    if err := rootCmd.Execute(); err != nil {
+    ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+    go func() {
+        <-ctx.Done()
+        stop()
+    }()
+    err := rootCmd.ExecuteContext(ctx)
+    // stop 也會取消 ctx，必須先判斷是否因訊號結束
+    interrupted := ctx.Err() != nil
+    stop()
+    closeLog()
+    if err != nil {
         fmt.Println(err)
         os.Exit(1)
     }
-}
\ No newline at end of file
+    if interrupted {
+        // 與 shell 慣例相同，以 128+SIGINT 結束
+        os.Exit(130)
+    }
+}
+
+func init() {
+    rootCmd.PersistentFlags().StringVar(&logLevel, "log-level", "info", "log level: debug, info, warn or error")
+    rootCmd.PersistentFlags().StringVar(&logFormat, "log-format", "text", "log file format: text or json")
+    rootCmd.PersistentFlags().StringVar(&logDir, "log-dir", logging.DefaultDir, "directory for per-run log files (falls back to "+filepath.Join(config.StateDir, "logs")+" when not writable)")
+}
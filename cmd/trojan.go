@@ -0,0 +1,233 @@
+package cmd
+
+import (
+    "fmt"
+    "go-auto-proxy/internal/config"
+    "go-auto-proxy/internal/nginx"
+    "go-auto-proxy/internal/system"
+    "go-auto-proxy/internal/systemd"
+    "go-auto-proxy/internal/trojan"
+    "log"
+    "os"
+    "path/filepath"
+    "strconv"
+
+    "github.com/spf13/cobra"
+)
+
+const (
+    trojanBinary       = "trojan-go/trojan-go"
+    trojanServerConfig = "trojan-go/config.json"
+    trojanClientDir    = "trojan-go/client"
+    // clientCAFile 匯出給客戶端的 CA 檔名，與 client.json 放在同一目錄
+    clientCAFile = "ca.crt"
+    // trojanUnit 是 trojan-go 的 systemd 服務，cert renew、doctor 與 fail2ban 的 journalmatch 都依賴它
+    trojanUnit = "trojan-go"
+)
+
+var trojanCmd = &cobra.Command{
+    Use:   "trojan",
+    Short: "Manage trojan-go configuration",
+}
+
+var trojanConfigCmd = &cobra.Command{
+    Use:   "config",
+    Short: "Generate the trojan-go server config and the client export from config.json",
+    RunE: func(cmd *cobra.Command, args []string) error {
+        info, err := config.ReadConfig()
+        if err != nil {
+            return fmt.Errorf("failed to read config: %v", err)
+        }
+        return writeTrojanConfigs(info)
+    },
+}
+
+var trojanModeCmd = &cobra.Command{
+    Use:   "mode <public|zerotier>",
+    Short: "Switch between listening on all interfaces and listening only on the ZeroTier address",
+    Args:  cobra.ExactArgs(1),
+    RunE: func(cmd *cobra.Command, args []string) error {
+        mode := args[0]
+        if mode != trojan.ModePublic && mode != trojan.ModeZeroTier {
+            return fmt.Errorf("unsupported mode %q (use public or zerotier)", mode)
+        }
+        info, err := config.ReadConfig()
+        if err != nil {
+            return fmt.Errorf("failed to read config: %v", err)
+        }
+        if mode == trojan.ModeZeroTier && (trojan.ZeroTierAddr(info) == "" || info.ZeroTier.Device == "") {
+            return fmt.Errorf("no ZeroTier address assigned yet, run 'go-auto-proxy zerotier join' first")
+        }
+
+        info.TrojanGo.Mode = mode
+        if err := config.WriteConfig(info); err != nil {
+            return fmt.Errorf("failed to write config: %v", err)
+        }
+        if info.AcmeSH.CertPath != "" {
+            if err := writeTrojanConfigs(info); err != nil {
+                return err
+            }
+        }
+
+        // 公網介面上關閉端口，只保留 ZeroTier；切回 public 時移除這些規則
+        if err := syncFirewall(info, info.TrojanGo.Port); err != nil {
+            return err
+        }
+        log.Printf("trojan-go mode set to %s, restart trojan-go to apply.", mode)
+        return nil
+    },
+}
+
+var trojanPortCmd = &cobra.Command{
+    Use:   "port <number|auto>",
+    Short: "Change the trojan-go port, or pick a free one from trojan_go.port_range with 'auto'",
+    Args:  cobra.ExactArgs(1),
+    RunE: func(cmd *cobra.Command, args []string) error {
+        info, err := config.ReadConfig()
+        if err != nil {
+            return fmt.Errorf("failed to read config: %v", err)
+        }
+        port, err := resolveTrojanPort(args[0], info)
+        if err != nil {
+            return err
+        }
+        oldPort := info.TrojanGo.Port
+        info.TrojanGo.Port = port
+        if err := config.WriteConfig(info); err != nil {
+            return fmt.Errorf("failed to write config: %v", err)
+        }
+        if info.AcmeSH.CertPath != "" {
+            if err := writeTrojanConfigs(info); err != nil {
+                return err
+            }
+        }
+        if err := syncPortConfig(info, oldPort); err != nil {
+            return err
+        }
+        log.Printf("trojan-go port set to %d, restart trojan-go to apply.", port)
+        return nil
+    },
+}
+
+var trojanInstallServiceCmd = &cobra.Command{
+    Use:   "install-service",
+    Short: "Install and enable the trojan-go systemd service for the server config in this directory",
+    RunE: func(cmd *cobra.Command, args []string) error {
+        return installTrojanService()
+    },
+}
+
+// installTrojanService 安裝以目前目錄的 trojan-go 與伺服器配置執行的 systemd 服務
+func installTrojanService() error {
+    binary, err := filepath.Abs(filepath.FromSlash(trojanBinary))
+    if err != nil {
+        return err
+    }
+    if _, err := os.Stat(binary); err != nil {
+        return fmt.Errorf("trojan-go not found at %s, run 'go-auto-proxy init' first", binary)
+    }
+    configPath, err := filepath.Abs(filepath.FromSlash(trojanServerConfig))
+    if err != nil {
+        return err
+    }
+    return systemd.InstallService(systemd.Service{
+        Name:             trojanUnit,
+        Description:      "trojan-go proxy (managed by go-auto-proxy)",
+        ExecStart:        fmt.Sprintf("%s -config %s", binary, configPath),
+        WorkingDirectory: filepath.Dir(binary),
+    })
+}
+
+// restartTrojan 在服務已安裝時重新啟動 trojan-go；尚未安裝時只提示，不視為錯誤
+func restartTrojan() error {
+    if !systemd.Exists(trojanUnit) {
+        log.Printf("Warning: %s.service is not installed, restart trojan-go manually or run 'go-auto-proxy trojan install-service'.", trojanUnit)
+        return nil
+    }
+    return systemd.Restart(trojanUnit)
+}
+
+// resolveTrojanPort 解析端口參數：auto 從配置的範圍中選擇空閒端口，
+// 指定端口時拒絕已被 trojan-go 以外的程序占用的端口
+func resolveTrojanPort(value string, info system.SystemInfo) (int, error) {
+    if value == "auto" {
+        low, high, err := system.ParsePortRange(info.TrojanGo.PortRange)
+        if err != nil {
+            return 0, err
+        }
+        port, err := system.FreePort(low, high)
+        if err != nil {
+            return 0, err
+        }
+        log.Printf("Picked free port %d from range %s.", port, info.TrojanGo.PortRange)
+        return port, nil
+    }
+    port, err := strconv.Atoi(value)
+    if err != nil || port <= 0 || port > 65535 {
+        return 0, fmt.Errorf("invalid port %q (use a number between 1 and 65535, or auto)", value)
+    }
+    if err := system.CheckPortOwner(port, "trojan-go"); err != nil {
+        return 0, fmt.Errorf("%v; choose another port or use --port auto", err)
+    }
+    return port, nil
+}
+
+// syncPortConfig 讓防火牆規則與 nginx 轉向跟隨 trojan-go 端口；oldPort 的規則會被移除
+func syncPortConfig(info system.SystemInfo, oldPort int) error {
+    if err := syncFirewall(info, oldPort); err != nil {
+        return err
+    }
+    return nginx.Apply(nginx.Site{Domain: info.AcmeSH.Domain, Webroot: info.AcmeSH.Webroot, TrojanPort: info.TrojanGo.Port})
+}
+
+// writeTrojanConfigs 生成伺服器配置與客戶端匯出包；
+// 使用本地 CA 時一併匯出 CA，讓客戶端以 ssl.verify 驗證伺服器
+func writeTrojanConfigs(info system.SystemInfo) error {
+    if info.AcmeSH.CertPath == "" {
+        return fmt.Errorf("no certificate configured, run 'go-auto-proxy cert issue' or 'go-auto-proxy cert selfsign' first")
+    }
+    if info.TrojanGo.Mode == trojan.ModeZeroTier && trojan.ZeroTierAddr(info) == "" {
+        return fmt.Errorf("trojan-go is in zerotier mode but no ZeroTier address is assigned, run 'go-auto-proxy zerotier join' first")
+    }
+    if err := trojan.Write(filepath.FromSlash(trojanServerConfig), trojan.ServerConfig(info)); err != nil {
+        return fmt.Errorf("failed to write trojan-go server config: %v", err)
+    }
+    log.Printf("trojan-go server config written to %s", trojanServerConfig)
+    if !systemd.Exists(trojanUnit) {
+        if err := installTrojanService(); err != nil {
+            log.Printf("Warning: failed to install %s.service: %v", trojanUnit, err)
+        }
+    }
+
+    caPath := ""
+    if info.AcmeSH.CAPath != "" {
+        data, err := os.ReadFile(info.AcmeSH.CAPath)
+        if err != nil {
+            return fmt.Errorf("failed to read CA certificate: %v", err)
+        }
+        if err := os.MkdirAll(filepath.FromSlash(trojanClientDir), 0755); err != nil {
+            return err
+        }
+        if err := os.WriteFile(filepath.Join(filepath.FromSlash(trojanClientDir), clientCAFile), data, 0644); err != nil {
+            return err
+        }
+        caPath = clientCAFile
+    } else if err := os.Remove(filepath.Join(filepath.FromSlash(trojanClientDir), clientCAFile)); err != nil && !os.IsNotExist(err) {
+        // 改用公開憑證後，舊的本地 CA 不應繼續隨客戶端配置發佈
+        return err
+    }
+    clientPath := filepath.Join(filepath.FromSlash(trojanClientDir), "client.json")
+    if err := trojan.Write(clientPath, trojan.ClientConfig(info, caPath)); err != nil {
+        return fmt.Errorf("failed to write trojan-go client config: %v", err)
+    }
+    log.Printf("trojan-go client config exported to %s", clientPath)
+    if caPath != "" {
+        log.Printf("Copy %s together with %s to clients; ssl.cert refers to it by relative path.", clientCAFile, clientPath)
+    }
+    return nil
+}
+
+func init() {
+    trojanCmd.AddCommand(trojanConfigCmd, trojanModeCmd, trojanPortCmd, trojanInstallServiceCmd)
+    rootCmd.AddCommand(trojanCmd)
+}
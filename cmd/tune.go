@@ -0,0 +1,139 @@
+package cmd
+
+import (
+    "fmt"
+    "go-auto-proxy/internal/config"
+    "go-auto-proxy/internal/report"
+    "go-auto-proxy/internal/system"
+    "go-auto-proxy/internal/tune"
+    "log"
+    "strings"
+
+    "github.com/spf13/cobra"
+)
+
+var (
+    tuneProfile string
+    tuneDryRun  bool
+)
+
+var tuneCmd = &cobra.Command{
+    Use:   "tune",
+    Short: "Tune kernel networking (BBR, fq, socket buffers) and file descriptor limits",
+}
+
+var tuneShowCmd = &cobra.Command{
+    Use:   "show",
+    Short: "Compare the current sysctl values with a tuning profile",
+    RunE: func(cmd *cobra.Command, args []string) error {
+        profile, err := selectedProfile()
+        if err != nil {
+            return err
+        }
+        printTunePlan(profile)
+        return nil
+    },
+}
+
+var tuneApplyCmd = &cobra.Command{
+    Use:   "apply",
+    Short: "Write " + tune.SysctlPath + " from a profile and load it",
+    RunE: func(cmd *cobra.Command, args []string) error {
+        profile, err := selectedProfile()
+        if err != nil {
+            return err
+        }
+        if tuneDryRun {
+            printTunePlan(profile)
+            return nil
+        }
+        if _, err := tune.Apply(profile); err != nil {
+            return err
+        }
+        if tuneProfile != "" {
+            return saveTuneProfile(tuneProfile)
+        }
+        return nil
+    },
+}
+
+var tuneRevertCmd = &cobra.Command{
+    Use:   "revert",
+    Short: "Remove the managed sysctl and limits files and restore the previous values",
+    RunE: func(cmd *cobra.Command, args []string) error {
+        return tune.Revert()
+    },
+}
+
+// selectedProfile 優先使用 --profile，其次是 config.json 的 tune.profile
+func selectedProfile() (tune.Profile, error) {
+    name := tuneProfile
+    if name == "" {
+        info, err := config.ReadConfig()
+        if err != nil {
+            return tune.Profile{}, fmt.Errorf("failed to read config: %v", err)
+        }
+        name = info.Tune.Profile
+    }
+    return tune.Lookup(name)
+}
+
+// saveTuneProfile 將選擇的 profile 寫回 config.json
+func saveTuneProfile(name string) error {
+    info, err := config.ReadConfig()
+    if err != nil {
+        // 尚未執行 init 時沒有 config.json，不需要記錄
+        return nil
+    }
+    info.Tune.Profile = name
+    return config.WriteConfig(info)
+}
+
+func printTunePlan(profile tune.Profile) {
+    fmt.Printf("Profile: %s (%s)\n", profile.Name, profile.Description)
+    if applied := tune.AppliedProfile(); applied != "" {
+        fmt.Printf("Applied: %s\n", applied)
+    }
+    if !tune.BBRLoaded() {
+        fmt.Println("tcp_bbr is not loaded; apply will try 'modprobe tcp_bbr' and skip BBR and fq if that fails")
+    }
+    fmt.Printf("%-38s %-24s %s\n", "KEY", "CURRENT", "PROFILE")
+    for _, c := range tune.Plan(profile) {
+        mark := ""
+        if c.Changed() {
+            mark = " *"
+        }
+        current := c.Current
+        if current == "" {
+            current = "-"
+        }
+        fmt.Printf("%-38s %-24s %s%s\n", c.Key, current, c.Desired, mark)
+    }
+    fmt.Printf("nofile limit: %d\n", profile.NoFile)
+}
+
+// configureTune 在 init 中套用 config.json 的 tune.profile；設為 none 時略過，失敗只記錄在日誌與報告中
+func configureTune(info system.SystemInfo, c *report.Component) {
+    if info.Tune.Profile == "" || info.Tune.Profile == "none" {
+        return
+    }
+    profile, err := tune.Lookup(info.Tune.Profile)
+    if err == nil {
+        profile, err = tune.Apply(profile)
+    }
+    if err != nil {
+        log.Println("Failed to apply the tuning profile:", err)
+        c.Fail(err)
+        return
+    }
+    c.Status = report.StatusInstalled
+    c.Detail = "profile " + profile.Name
+    c.AddFiles(tune.SysctlPath, tune.LimitsPath, tune.SystemdPath)
+}
+
+func init() {
+    tuneCmd.PersistentFlags().StringVar(&tuneProfile, "profile", "", "tuning profile: "+strings.Join(tune.Names(), ", ")+" (defaults to tune.profile in config.json)")
+    tuneApplyCmd.Flags().BoolVar(&tuneDryRun, "dry-run", false, "show the changes without writing anything")
+    tuneCmd.AddCommand(tuneShowCmd, tuneApplyCmd, tuneRevertCmd)
+    rootCmd.AddCommand(tuneCmd)
+}
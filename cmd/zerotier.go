@@ -0,0 +1,167 @@
+package cmd
+
+import (
+    "context"
+    "fmt"
+    "go-auto-proxy/internal/config"
+    "go-auto-proxy/internal/zerotier"
+    "log"
+    "os"
+    "strings"
+    "text/tabwriter"
+    "time"
+
+    "github.com/spf13/cobra"
+)
+
+// zeroTierPollInterval 輪詢網路狀態的間隔
+const zeroTierPollInterval = 2 * time.Second
+
+var (
+    zeroTierTimeout time.Duration
+    zeroTierJSON    bool
+)
+
+var zeroTierCmd = &cobra.Command{
+    Use:   "zerotier",
+    Short: "Manage the ZeroTier network membership",
+}
+
+var zeroTierJoinCmd = &cobra.Command{
+    Use:   "join [network-id]",
+    Short: "Join the ZeroTier network (defaults to zerotier.network_id in config.json) and record the assigned IPs",
+    Args:  cobra.MaximumNArgs(1),
+    RunE: func(cmd *cobra.Command, args []string) error {
+        networkID := ""
+        if len(args) == 1 {
+            networkID = args[0]
+        }
+        return joinZeroTier(cmd.Context(), networkID, zeroTierTimeout)
+    },
+}
+
+var zeroTierLeaveCmd = &cobra.Command{
+    Use:   "leave [network-id]",
+    Short: "Leave the ZeroTier network and clear it from config.json",
+    Args:  cobra.MaximumNArgs(1),
+    RunE: func(cmd *cobra.Command, args []string) error {
+        info, err := config.ReadConfig()
+        if err != nil {
+            return fmt.Errorf("failed to read config: %v", err)
+        }
+        networkID := info.ZeroTier.NetworkID
+        if len(args) == 1 {
+            networkID = args[0]
+        }
+        client, err := zerotier.NewClient()
+        if err != nil {
+            return err
+        }
+        if err := client.Leave(cmd.Context(), networkID); err != nil {
+            return err
+        }
+        log.Printf("Left ZeroTier network %s.", networkID)
+        if networkID != info.ZeroTier.NetworkID {
+            return nil
+        }
+        info.ZeroTier.NetworkID = ""
+        info.ZeroTier.AssignedIPs = nil
+        info.ZeroTier.Device = ""
+        return config.WriteConfig(info)
+    },
+}
+
+// zeroTierReport 是 zerotier status --json 的輸出
+type zeroTierReport struct {
+    Node     zerotier.Status    `json:"node"`
+    Networks []zerotier.Network `json:"networks"`
+    Peers    []zerotier.Peer    `json:"peers"`
+}
+
+var zeroTierStatusCmd = &cobra.Command{
+    Use:   "status",
+    Short: "Show the node status, joined networks and peers from the local ZeroTier service",
+    RunE: func(cmd *cobra.Command, args []string) error {
+        client, err := zerotier.NewClient()
+        if err != nil {
+            return err
+        }
+        ctx := cmd.Context()
+        var report zeroTierReport
+        if report.Node, err = client.Status(ctx); err != nil {
+            return err
+        }
+        if report.Networks, err = client.Networks(ctx); err != nil {
+            return err
+        }
+        if report.Peers, err = client.Peers(ctx); err != nil {
+            return err
+        }
+        if zeroTierJSON {
+            return printJSON(report)
+        }
+
+        fmt.Printf("Node:    %s (version %s, online: %t)\n", report.Node.Address, report.Node.Version, report.Node.Online)
+        fmt.Println()
+        w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+        fmt.Fprintln(w, "NETWORK\tNAME\tSTATUS\tDEVICE\tADDRESSES")
+        for _, n := range report.Networks {
+            fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", n.ID, n.Name, n.Status, n.PortDeviceName, strings.Join(n.AssignedAddresses, ","))
+        }
+        w.Flush()
+        fmt.Println()
+        fmt.Fprintln(w, "PEER\tROLE\tLATENCY\tPATH")
+        for _, p := range report.Peers {
+            path := "-"
+            for _, candidate := range p.Paths {
+                if candidate.Preferred {
+                    path = candidate.Address
+                }
+            }
+            fmt.Fprintf(w, "%s\t%s\t%dms\t%s\n", p.Address, p.Role, p.Latency, path)
+        }
+        return w.Flush()
+    },
+}
+
+// joinZeroTier 加入網路並將網路 ID 與分配的位址寫回 config.json；
+// networkID 為空時使用配置中的值
+func joinZeroTier(ctx context.Context, networkID string, timeout time.Duration) error {
+    info, err := config.ReadConfig()
+    if err != nil {
+        return fmt.Errorf("failed to read config: %v", err)
+    }
+    if networkID == "" {
+        networkID = info.ZeroTier.NetworkID
+    }
+    if networkID == "" {
+        return fmt.Errorf("no ZeroTier network configured, pass a network ID or set zerotier.network_id in config.json")
+    }
+
+    client, err := zerotier.NewClient()
+    if err != nil {
+        return err
+    }
+    ctx, cancel := context.WithTimeout(ctx, timeout)
+    defer cancel()
+    network, err := client.JoinAndWait(ctx, networkID, zeroTierPollInterval)
+    if err != nil {
+        return err
+    }
+
+    info.ZeroTier.NetworkID = networkID
+    info.ZeroTier.AssignedIPs = network.IPs()
+    info.ZeroTier.Device = network.PortDeviceName
+    if err := config.WriteConfig(info); err != nil {
+        return fmt.Errorf("failed to write config: %v", err)
+    }
+    log.Printf("Joined ZeroTier network %s (%s) on %s with %v", networkID, network.Name, network.PortDeviceName, info.ZeroTier.AssignedIPs)
+    return nil
+}
+
+func init() {
+    zeroTierJoinCmd.Flags().DurationVar(&zeroTierTimeout, "timeout", 2*time.Minute, "how long to wait for the network to become OK")
+    zeroTierStatusCmd.Flags().BoolVar(&zeroTierJSON, "json", false, "print as JSON")
+    zeroTierCmd.AddCommand(zeroTierJoinCmd, zeroTierLeaveCmd, zeroTierStatusCmd)
+    rootCmd.AddCommand(zeroTierCmd)
+}
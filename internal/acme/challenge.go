@@ -0,0 +1,215 @@
+package acme
+
+import (
+    "context"
+    "crypto/ecdsa"
+    "crypto/elliptic"
+    "crypto/rand"
+    "crypto/sha256"
+    "crypto/tls"
+    "crypto/x509"
+    "crypto/x509/pkix"
+    "encoding/asn1"
+    "encoding/base64"
+    "fmt"
+    "math/big"
+    "net"
+    "net/http"
+    "os"
+    "path/filepath"
+    "strings"
+    "sync"
+    "time"
+)
+
+const (
+    // ALPNProto 是 TLS-ALPN-01 驗證使用的 ALPN 協議名稱
+    ALPNProto = "acme-tls/1"
+    httpChallengePath = "/.well-known/acme-challenge/"
+)
+
+// idPeAcmeIdentifier 是 RFC 8737 定義的 acmeIdentifier 擴展 OID
+var idPeAcmeIdentifier = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 1, 31}
+
+// HTTP01Solver 透過 HTTP 回應 key authorization；
+// 設定 Webroot 時寫入檔案交由現有的網頁伺服器（如 nginx）提供，否則自行監聽 Addr
+type HTTP01Solver struct {
+    Addr    string
+    Webroot string
+
+    mu     sync.Mutex
+    tokens map[string]string
+    server *http.Server
+}
+
+func (s *HTTP01Solver) Present(ctx context.Context, domain, token, keyAuth string) error {
+    if s.Webroot != "" {
+        dir := filepath.Join(s.Webroot, filepath.FromSlash(httpChallengePath))
+        if err := os.MkdirAll(dir, 0755); err != nil {
+            return err
+        }
+        return os.WriteFile(filepath.Join(dir, token), []byte(keyAuth), 0644)
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if s.tokens == nil {
+        s.tokens = make(map[string]string)
+    }
+    s.tokens[token] = keyAuth
+    if s.server != nil {
+        return nil
+    }
+
+    addr := s.Addr
+    if addr == "" {
+        addr = ":80"
+    }
+    ln, err := net.Listen("tcp", addr)
+    if err != nil {
+        return fmt.Errorf("failed to listen on %s for http-01 (is nginx using the port? try a webroot): %v", addr, err)
+    }
+    s.server = &http.Server{Handler: http.HandlerFunc(s.serveHTTP), ReadHeaderTimeout: 10 * time.Second}
+    go s.server.Serve(ln)
+    return nil
+}
+
+func (s *HTTP01Solver) CleanUp(ctx context.Context, domain, token, keyAuth string) error {
+    if s.Webroot != "" {
+        return os.Remove(filepath.Join(s.Webroot, filepath.FromSlash(httpChallengePath), token))
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    delete(s.tokens, token)
+    if len(s.tokens) > 0 || s.server == nil {
+        return nil
+    }
+    err := s.server.Close()
+    s.server = nil
+    return err
+}
+
+func (s *HTTP01Solver) serveHTTP(w http.ResponseWriter, r *http.Request) {
+    if !strings.HasPrefix(r.URL.Path, httpChallengePath) {
+        http.NotFound(w, r)
+        return
+    }
+    token := strings.TrimPrefix(r.URL.Path, httpChallengePath)
+    s.mu.Lock()
+    keyAuth, ok := s.tokens[token]
+    s.mu.Unlock()
+    if !ok {
+        http.NotFound(w, r)
+        return
+    }
+    w.Write([]byte(keyAuth))
+}
+
+// TLSALPN01Solver 在 Addr 上以 acme-tls/1 協議提供驗證憑證
+type TLSALPN01Solver struct {
+    Addr string
+
+    mu       sync.Mutex
+    certs    map[string]*tls.Certificate
+    listener net.Listener
+}
+
+func (s *TLSALPN01Solver) Present(ctx context.Context, domain, token, keyAuth string) error {
+    cert, err := ChallengeCert(domain, keyAuth)
+    if err != nil {
+        return err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if s.certs == nil {
+        s.certs = make(map[string]*tls.Certificate)
+    }
+    s.certs[domain] = cert
+    if s.listener != nil {
+        return nil
+    }
+
+    addr := s.Addr
+    if addr == "" {
+        addr = ":443"
+    }
+    ln, err := tls.Listen("tcp", addr, &tls.Config{
+        NextProtos:     []string{ALPNProto},
+        GetCertificate: s.getCertificate,
+    })
+    if err != nil {
+        return fmt.Errorf("failed to listen on %s for tls-alpn-01 (stop trojan-go first?): %v", addr, err)
+    }
+    s.listener = ln
+    go func() {
+        for {
+            conn, err := ln.Accept()
+            if err != nil {
+                return
+            }
+            go func() {
+                defer conn.Close()
+                conn.SetDeadline(time.Now().Add(10 * time.Second))
+                conn.(*tls.Conn).Handshake()
+            }()
+        }
+    }()
+    return nil
+}
+
+func (s *TLSALPN01Solver) CleanUp(ctx context.Context, domain, token, keyAuth string) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    delete(s.certs, domain)
+    if len(s.certs) > 0 || s.listener == nil {
+        return nil
+    }
+    err := s.listener.Close()
+    s.listener = nil
+    return err
+}
+
+func (s *TLSALPN01Solver) getCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if cert, ok := s.certs[hello.ServerName]; ok {
+        return cert, nil
+    }
+    return nil, fmt.Errorf("no tls-alpn-01 certificate for %q", hello.ServerName)
+}
+
+// DNS01Value 返回 DNS-01 驗證 TXT 記錄的值
+func DNS01Value(keyAuth string) string {
+    sum := sha256.Sum256([]byte(keyAuth))
+    return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// ChallengeCert 產生 RFC 8737 要求的自簽驗證憑證
+func ChallengeCert(domain, keyAuth string) (*tls.Certificate, error) {
+    key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+    if err != nil {
+        return nil, err
+    }
+    sum := sha256.Sum256([]byte(keyAuth))
+    ext, err := asn1.Marshal(sum[:])
+    if err != nil {
+        return nil, err
+    }
+    tmpl := &x509.Certificate{
+        SerialNumber: big.NewInt(1),
+        Subject:      pkix.Name{CommonName: domain},
+        DNSNames:     []string{domain},
+        NotBefore:    time.Now().Add(-time.Hour),
+        NotAfter:     time.Now().Add(24 * time.Hour),
+        ExtraExtensions: []pkix.Extension{
+            {Id: idPeAcmeIdentifier, Critical: true, Value: ext},
+        },
+    }
+    der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+    if err != nil {
+        return nil, err
+    }
+    return &tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, nil
+}
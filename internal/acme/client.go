@@ -0,0 +1,454 @@
+package acme
+
+import (
+    "bytes"
+    "context"
+    "crypto"
+    "crypto/ecdsa"
+    "crypto/elliptic"
+    "crypto/rand"
+    "crypto/sha256"
+    "crypto/x509"
+    "crypto/x509/pkix"
+    "encoding/base64"
+    "encoding/json"
+    "fmt"
+    "io"
+    "log"
+    "math/big"
+    "net/http"
+    "strings"
+    "time"
+)
+
+// 常用 ACME 目錄位址
+const (
+    LetsEncryptURL        = "https://acme-v02.api.letsencrypt.org/directory"
+    LetsEncryptStagingURL = "https://acme-staging-v02.api.letsencrypt.org/directory"
+)
+
+// DirectoryURL 將 provider 名稱轉換為 ACME 目錄位址，若本身即為 URL 則直接返回
+func DirectoryURL(provider string) (string, error) {
+    switch provider {
+    case "", "letsencrypt":
+        return LetsEncryptURL, nil
+    case "letsencrypt-staging":
+        return LetsEncryptStagingURL, nil
+    }
+    if strings.HasPrefix(provider, "https://") || strings.HasPrefix(provider, "http://") {
+        return provider, nil
+    }
+    return "", fmt.Errorf("unsupported ACME provider %q for native client", provider)
+}
+
+// Solver 負責佈署與清除 ACME 驗證所需的資源
+type Solver interface {
+    Present(ctx context.Context, domain, token, keyAuth string) error
+    CleanUp(ctx context.Context, domain, token, keyAuth string) error
+}
+
+// Client 是實作 RFC 8555 的 ACME 客戶端
+type Client struct {
+    DirectoryURL string
+    Key          *ecdsa.PrivateKey
+    HTTPClient   *http.Client
+    // Solvers 以驗證類型（如 http-01）為鍵，依序嘗試
+    Solvers map[string]Solver
+    // PollInterval 輪詢授權與訂單狀態的間隔
+    PollInterval time.Duration
+
+    dir   directory
+    kid   string
+    nonce string
+}
+
+type directory struct {
+    NewNonce   string `json:"newNonce"`
+    NewAccount string `json:"newAccount"`
+    NewOrder   string `json:"newOrder"`
+}
+
+type order struct {
+    Status         string   `json:"status"`
+    Authorizations []string `json:"authorizations"`
+    Finalize       string   `json:"finalize"`
+    Certificate    string   `json:"certificate"`
+    url            string
+}
+
+type authorization struct {
+    Status     string `json:"status"`
+    Identifier struct {
+        Type  string `json:"type"`
+        Value string `json:"value"`
+    } `json:"identifier"`
+    Challenges []challenge `json:"challenges"`
+}
+
+type challenge struct {
+    Type   string `json:"type"`
+    URL    string `json:"url"`
+    Token  string `json:"token"`
+    Status string `json:"status"`
+}
+
+// Problem 是 ACME 伺服器返回的錯誤（RFC 7807）
+type Problem struct {
+    Type   string `json:"type"`
+    Detail string `json:"detail"`
+    Status int    `json:"status"`
+}
+
+func (p *Problem) Error() string {
+    return fmt.Sprintf("acme: %s: %s", p.Type, p.Detail)
+}
+
+func (c *Client) httpClient() *http.Client {
+    if c.HTTPClient != nil {
+        return c.HTTPClient
+    }
+    return &http.Client{Timeout: 30 * time.Second}
+}
+
+func (c *Client) pollInterval() time.Duration {
+    if c.PollInterval > 0 {
+        return c.PollInterval
+    }
+    return 2 * time.Second
+}
+
+// Register 取得目錄並建立（或取回既有）帳戶
+func (c *Client) Register(ctx context.Context, email string) error {
+    req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.DirectoryURL, nil)
+    if err != nil {
+        return err
+    }
+    resp, err := c.httpClient().Do(req)
+    if err != nil {
+        return fmt.Errorf("failed to fetch ACME directory: %v", err)
+    }
+    defer resp.Body.Close()
+    if err := json.NewDecoder(resp.Body).Decode(&c.dir); err != nil {
+        return fmt.Errorf("failed to decode ACME directory: %v", err)
+    }
+
+    payload := map[string]interface{}{"termsOfServiceAgreed": true}
+    if email != "" {
+        payload["contact"] = []string{"mailto:" + email}
+    }
+    resp, err = c.post(ctx, c.dir.NewAccount, payload)
+    if err != nil {
+        return fmt.Errorf("failed to register ACME account: %v", err)
+    }
+    resp.Body.Close()
+    c.kid = resp.Header.Get("Location")
+    if c.kid == "" {
+        return fmt.Errorf("ACME server did not return an account URL")
+    }
+    log.Printf("ACME account ready: %s", c.kid)
+    return nil
+}
+
+// Obtain 為指定網域申請憑證，返回 PEM 格式的憑證鏈與私鑰
+func (c *Client) Obtain(ctx context.Context, domains []string) (certPEM, keyPEM []byte, err error) {
+    if c.kid == "" {
+        return nil, nil, fmt.Errorf("ACME account not registered")
+    }
+
+    ids := make([]map[string]string, 0, len(domains))
+    for _, d := range domains {
+        ids = append(ids, map[string]string{"type": "dns", "value": d})
+    }
+    resp, err := c.post(ctx, c.dir.NewOrder, map[string]interface{}{"identifiers": ids})
+    if err != nil {
+        return nil, nil, fmt.Errorf("failed to create order: %v", err)
+    }
+    var o order
+    err = decodeBody(resp, &o)
+    if err != nil {
+        return nil, nil, err
+    }
+    o.url = resp.Header.Get("Location")
+
+    for _, authzURL := range o.Authorizations {
+        if err := c.authorize(ctx, authzURL); err != nil {
+            return nil, nil, err
+        }
+    }
+
+    certKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+    if err != nil {
+        return nil, nil, err
+    }
+    csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+        Subject:  pkix.Name{CommonName: domains[0]},
+        DNSNames: domains,
+    }, certKey)
+    if err != nil {
+        return nil, nil, fmt.Errorf("failed to create CSR: %v", err)
+    }
+    resp, err = c.post(ctx, o.Finalize, map[string]string{"csr": b64(csr)})
+    if err != nil {
+        return nil, nil, fmt.Errorf("failed to finalize order: %v", err)
+    }
+    if err := decodeBody(resp, &o); err != nil {
+        return nil, nil, err
+    }
+
+    for o.Status != "valid" {
+        if o.Status == "invalid" {
+            return nil, nil, fmt.Errorf("order for %v became invalid", domains)
+        }
+        if err := sleep(ctx, c.pollInterval()); err != nil {
+            return nil, nil, err
+        }
+        resp, err := c.post(ctx, o.url, nil)
+        if err != nil {
+            return nil, nil, fmt.Errorf("failed to poll order: %v", err)
+        }
+        if err := decodeBody(resp, &o); err != nil {
+            return nil, nil, err
+        }
+    }
+
+    resp, err = c.post(ctx, o.Certificate, nil)
+    if err != nil {
+        return nil, nil, fmt.Errorf("failed to download certificate: %v", err)
+    }
+    defer resp.Body.Close()
+    certPEM, err = io.ReadAll(resp.Body)
+    if err != nil {
+        return nil, nil, err
+    }
+    keyPEM, err = encodeECKey(certKey)
+    if err != nil {
+        return nil, nil, err
+    }
+    return certPEM, keyPEM, nil
+}
+
+// authorize 完成單一授權的驗證流程
+func (c *Client) authorize(ctx context.Context, authzURL string) error {
+    resp, err := c.post(ctx, authzURL, nil)
+    if err != nil {
+        return fmt.Errorf("failed to fetch authorization: %v", err)
+    }
+    var authz authorization
+    if err := decodeBody(resp, &authz); err != nil {
+        return err
+    }
+    if authz.Status == "valid" {
+        return nil
+    }
+
+    domain := authz.Identifier.Value
+    var chal *challenge
+    var solver Solver
+    for i := range authz.Challenges {
+        if s, ok := c.Solvers[authz.Challenges[i].Type]; ok {
+            chal, solver = &authz.Challenges[i], s
+            break
+        }
+    }
+    if chal == nil {
+        return fmt.Errorf("no supported challenge offered for %s", domain)
+    }
+
+    keyAuth, err := c.keyAuthorization(chal.Token)
+    if err != nil {
+        return err
+    }
+    log.Printf("Solving %s challenge for %s...", chal.Type, domain)
+    if err := solver.Present(ctx, domain, chal.Token, keyAuth); err != nil {
+        return fmt.Errorf("failed to present %s challenge for %s: %v", chal.Type, domain, err)
+    }
+    defer func() {
+        if err := solver.CleanUp(ctx, domain, chal.Token, keyAuth); err != nil {
+            log.Printf("Failed to clean up %s challenge for %s: %v", chal.Type, domain, err)
+        }
+    }()
+
+    resp, err = c.post(ctx, chal.URL, struct{}{})
+    if err != nil {
+        return fmt.Errorf("failed to accept challenge: %v", err)
+    }
+    resp.Body.Close()
+
+    for {
+        resp, err := c.post(ctx, authzURL, nil)
+        if err != nil {
+            return fmt.Errorf("failed to poll authorization: %v", err)
+        }
+        if err := decodeBody(resp, &authz); err != nil {
+            return err
+        }
+        switch authz.Status {
+        case "valid":
+            log.Printf("Authorization for %s is valid.", domain)
+            return nil
+        case "invalid", "deactivated", "expired", "revoked":
+            return fmt.Errorf("authorization for %s is %s", domain, authz.Status)
+        }
+        if err := sleep(ctx, c.pollInterval()); err != nil {
+            return err
+        }
+    }
+}
+
+// keyAuthorization 計算 token 對應的 key authorization
+func (c *Client) keyAuthorization(token string) (string, error) {
+    thumb, err := thumbprint(&c.Key.PublicKey)
+    if err != nil {
+        return "", err
+    }
+    return token + "." + thumb, nil
+}
+
+// post 發送 JWS 簽名請求，payload 為 nil 時為 POST-as-GET，遇到 badNonce 時重試一次
+func (c *Client) post(ctx context.Context, url string, payload interface{}) (*http.Response, error) {
+    var resp *http.Response
+    for attempt := 0; attempt < 2; attempt++ {
+        body, err := c.sign(ctx, url, payload)
+        if err != nil {
+            return nil, err
+        }
+        req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+        if err != nil {
+            return nil, err
+        }
+        req.Header.Set("Content-Type", "application/jose+json")
+        resp, err = c.httpClient().Do(req)
+        if err != nil {
+            return nil, err
+        }
+        c.nonce = resp.Header.Get("Replay-Nonce")
+        if resp.StatusCode < 400 {
+            return resp, nil
+        }
+        prob := &Problem{Status: resp.StatusCode}
+        json.NewDecoder(resp.Body).Decode(prob)
+        resp.Body.Close()
+        if prob.Type != "urn:ietf:params:acme:error:badNonce" || attempt == 1 {
+            return nil, prob
+        }
+    }
+    return resp, nil
+}
+
+// sign 產生 flattened JWS
+func (c *Client) sign(ctx context.Context, url string, payload interface{}) ([]byte, error) {
+    nonce, err := c.fetchNonce(ctx)
+    if err != nil {
+        return nil, err
+    }
+    protected := map[string]interface{}{
+        "alg":   "ES256",
+        "nonce": nonce,
+        "url":   url,
+    }
+    if c.kid != "" {
+        protected["kid"] = c.kid
+    } else {
+        protected["jwk"] = jwk(&c.Key.PublicKey)
+    }
+    header, err := json.Marshal(protected)
+    if err != nil {
+        return nil, err
+    }
+    var body []byte
+    if payload != nil {
+        body, err = json.Marshal(payload)
+        if err != nil {
+            return nil, err
+        }
+    }
+
+    h64, p64 := b64(header), b64(body)
+    digest := sha256.Sum256([]byte(h64 + "." + p64))
+    r, s, err := ecdsa.Sign(rand.Reader, c.Key, digest[:])
+    if err != nil {
+        return nil, err
+    }
+    sig := make([]byte, 64)
+    r.FillBytes(sig[:32])
+    s.FillBytes(sig[32:])
+
+    return json.Marshal(map[string]string{
+        "protected": h64,
+        "payload":   p64,
+        "signature": b64(sig),
+    })
+}
+
+// fetchNonce 取得可用的 nonce，優先使用上一個回應帶回的值
+func (c *Client) fetchNonce(ctx context.Context) (string, error) {
+    if c.nonce != "" {
+        n := c.nonce
+        c.nonce = ""
+        return n, nil
+    }
+    req, err := http.NewRequestWithContext(ctx, http.MethodHead, c.dir.NewNonce, nil)
+    if err != nil {
+        return "", err
+    }
+    resp, err := c.httpClient().Do(req)
+    if err != nil {
+        return "", fmt.Errorf("failed to fetch nonce: %v", err)
+    }
+    resp.Body.Close()
+    n := resp.Header.Get("Replay-Nonce")
+    if n == "" {
+        return "", fmt.Errorf("ACME server did not return a nonce")
+    }
+    return n, nil
+}
+
+// jwk 返回 ECDSA 公鑰的 JWK 表示，欄位依字典序排列以便計算 thumbprint
+func jwk(pub *ecdsa.PublicKey) map[string]string {
+    size := (pub.Curve.Params().BitSize + 7) / 8
+    return map[string]string{
+        "crv": pub.Curve.Params().Name,
+        "kty": "EC",
+        "x":   b64(pad(pub.X, size)),
+        "y":   b64(pad(pub.Y, size)),
+    }
+}
+
+// thumbprint 計算 RFC 7638 JWK thumbprint
+func thumbprint(pub *ecdsa.PublicKey) (string, error) {
+    // encoding/json 對 map 的鍵排序，正好符合 RFC 7638 的要求
+    data, err := json.Marshal(jwk(pub))
+    if err != nil {
+        return "", err
+    }
+    sum := crypto.SHA256.New()
+    sum.Write(data)
+    return b64(sum.Sum(nil)), nil
+}
+
+func pad(n *big.Int, size int) []byte {
+    b := make([]byte, size)
+    n.FillBytes(b)
+    return b
+}
+
+func b64(data []byte) string {
+    return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeBody(resp *http.Response, v interface{}) error {
+    defer resp.Body.Close()
+    if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+        return fmt.Errorf("failed to decode ACME response: %v", err)
+    }
+    return nil
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+    select {
+    case <-ctx.Done():
+        return ctx.Err()
+    case <-time.After(d):
+        return nil
+    }
+}
@@ -0,0 +1,415 @@
+package acme
+
+import (
+    "bytes"
+    "context"
+    "crypto/ecdsa"
+    "crypto/elliptic"
+    "crypto/rand"
+    "crypto/sha256"
+    "crypto/tls"
+    "crypto/x509"
+    "crypto/x509/pkix"
+    "encoding/asn1"
+    "encoding/base64"
+    "encoding/json"
+    "encoding/pem"
+    "fmt"
+    "io"
+    "math/big"
+    "net"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "sync"
+    "testing"
+    "time"
+
+    "github.com/stretchr/testify/assert"
+    "github.com/stretchr/testify/require"
+)
+
+// testServer 是僅供測試使用的最小 ACME 伺服器，會驗證 JWS 簽名並實際回連驗證挑戰
+type testServer struct {
+    t        *testing.T
+    srv      *httptest.Server
+    caKey    *ecdsa.PrivateKey
+    caCert   *x509.Certificate
+    httpAddr string // http-01 回連位址
+    tlsAddr  string // tls-alpn-01 回連位址
+
+    mu          sync.Mutex
+    nonce       int
+    nonces      map[string]bool
+    accountKey  *ecdsa.PublicKey
+    domain      string
+    token       string
+    authzStatus string
+    certPEM     []byte
+    validated   []string
+}
+
+func newTestServer(t *testing.T) *testServer {
+    caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+    require.NoError(t, err)
+    tmpl := &x509.Certificate{
+        SerialNumber:          big.NewInt(1),
+        Subject:               pkix.Name{CommonName: "test ACME CA"},
+        NotBefore:             time.Now().Add(-time.Hour),
+        NotAfter:              time.Now().Add(24 * time.Hour),
+        IsCA:                  true,
+        BasicConstraintsValid: true,
+        KeyUsage:              x509.KeyUsageCertSign,
+    }
+    der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &caKey.PublicKey, caKey)
+    require.NoError(t, err)
+    caCert, err := x509.ParseCertificate(der)
+    require.NoError(t, err)
+
+    ts := &testServer{t: t, caKey: caKey, caCert: caCert, nonces: map[string]bool{}, token: "test-token_123"}
+    ts.srv = httptest.NewServer(http.HandlerFunc(ts.handle))
+    t.Cleanup(ts.srv.Close)
+    return ts
+}
+
+func (ts *testServer) url(path string) string {
+    return ts.srv.URL + path
+}
+
+func (ts *testServer) newNonce() string {
+    ts.mu.Lock()
+    defer ts.mu.Unlock()
+    ts.nonce++
+    n := fmt.Sprintf("nonce-%d", ts.nonce)
+    ts.nonces[n] = true
+    return n
+}
+
+func (ts *testServer) handle(w http.ResponseWriter, r *http.Request) {
+    w.Header().Set("Replay-Nonce", ts.newNonce())
+    switch {
+    case r.URL.Path == "/directory":
+        json.NewEncoder(w).Encode(map[string]string{
+            "newNonce":   ts.url("/nonce"),
+            "newAccount": ts.url("/account"),
+            "newOrder":   ts.url("/order"),
+        })
+        return
+    case r.URL.Path == "/nonce":
+        return
+    }
+
+    payload, err := ts.verify(r)
+    if err != nil {
+        w.WriteHeader(http.StatusBadRequest)
+        json.NewEncoder(w).Encode(Problem{Type: "urn:ietf:params:acme:error:malformed", Detail: err.Error()})
+        return
+    }
+
+    switch r.URL.Path {
+    case "/account":
+        w.Header().Set("Location", ts.url("/acct/1"))
+        w.WriteHeader(http.StatusCreated)
+        w.Write([]byte(`{"status":"valid"}`))
+    case "/order":
+        var req struct {
+            Identifiers []struct{ Value string } `json:"identifiers"`
+        }
+        json.Unmarshal(payload, &req)
+        ts.mu.Lock()
+        ts.domain = req.Identifiers[0].Value
+        ts.authzStatus = "pending"
+        ts.mu.Unlock()
+        w.Header().Set("Location", ts.url("/order/1"))
+        w.WriteHeader(http.StatusCreated)
+        ts.writeOrder(w)
+    case "/order/1":
+        ts.writeOrder(w)
+    case "/authz/1":
+        ts.mu.Lock()
+        defer ts.mu.Unlock()
+        json.NewEncoder(w).Encode(map[string]interface{}{
+            "status":     ts.authzStatus,
+            "identifier": map[string]string{"type": "dns", "value": ts.domain},
+            "challenges": []map[string]string{
+                {"type": "http-01", "url": ts.url("/chall/http-01"), "token": ts.token, "status": "pending"},
+                {"type": "tls-alpn-01", "url": ts.url("/chall/tls-alpn-01"), "token": ts.token, "status": "pending"},
+            },
+        })
+    case "/chall/http-01", "/chall/tls-alpn-01":
+        typ := strings.TrimPrefix(r.URL.Path, "/chall/")
+        status := "valid"
+        if err := ts.validate(typ); err != nil {
+            ts.t.Logf("challenge %s failed: %v", typ, err)
+            status = "invalid"
+        }
+        ts.mu.Lock()
+        ts.authzStatus = status
+        ts.validated = append(ts.validated, typ)
+        ts.mu.Unlock()
+        json.NewEncoder(w).Encode(map[string]string{"type": typ, "status": status})
+    case "/finalize/1":
+        var req struct {
+            CSR string `json:"csr"`
+        }
+        json.Unmarshal(payload, &req)
+        if err := ts.issue(req.CSR); err != nil {
+            w.WriteHeader(http.StatusBadRequest)
+            json.NewEncoder(w).Encode(Problem{Type: "urn:ietf:params:acme:error:badCSR", Detail: err.Error()})
+            return
+        }
+        ts.writeOrder(w)
+    case "/cert/1":
+        ts.mu.Lock()
+        defer ts.mu.Unlock()
+        w.Header().Set("Content-Type", "application/pem-certificate-chain")
+        w.Write(ts.certPEM)
+    default:
+        http.NotFound(w, r)
+    }
+}
+
+func (ts *testServer) writeOrder(w http.ResponseWriter) {
+    ts.mu.Lock()
+    defer ts.mu.Unlock()
+    o := map[string]interface{}{
+        "status":         "pending",
+        "authorizations": []string{ts.url("/authz/1")},
+        "finalize":       ts.url("/finalize/1"),
+    }
+    if ts.authzStatus == "valid" {
+        o["status"] = "ready"
+    }
+    if ts.certPEM != nil {
+        o["status"] = "valid"
+        o["certificate"] = ts.url("/cert/1")
+    }
+    json.NewEncoder(w).Encode(o)
+}
+
+// verify 驗證 JWS 的 nonce、url 與 ES256 簽名，返回解碼後的 payload
+func (ts *testServer) verify(r *http.Request) ([]byte, error) {
+    var jws struct {
+        Protected, Payload, Signature string
+    }
+    if err := json.NewDecoder(r.Body).Decode(&jws); err != nil {
+        return nil, err
+    }
+    rawHeader, err := base64.RawURLEncoding.DecodeString(jws.Protected)
+    if err != nil {
+        return nil, err
+    }
+    var header struct {
+        Alg   string            `json:"alg"`
+        Nonce string            `json:"nonce"`
+        URL   string            `json:"url"`
+        Kid   string            `json:"kid"`
+        JWK   map[string]string `json:"jwk"`
+    }
+    if err := json.Unmarshal(rawHeader, &header); err != nil {
+        return nil, err
+    }
+    if header.URL != ts.url(r.URL.Path) {
+        return nil, fmt.Errorf("url mismatch: %s", header.URL)
+    }
+
+    ts.mu.Lock()
+    defer ts.mu.Unlock()
+    if !ts.nonces[header.Nonce] {
+        return nil, fmt.Errorf("bad nonce %q", header.Nonce)
+    }
+    delete(ts.nonces, header.Nonce)
+
+    if header.JWK != nil {
+        x, _ := base64.RawURLEncoding.DecodeString(header.JWK["x"])
+        y, _ := base64.RawURLEncoding.DecodeString(header.JWK["y"])
+        ts.accountKey = &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+    } else if header.Kid != ts.url("/acct/1") {
+        return nil, fmt.Errorf("unknown kid %q", header.Kid)
+    }
+
+    sig, err := base64.RawURLEncoding.DecodeString(jws.Signature)
+    if err != nil || len(sig) != 64 {
+        return nil, fmt.Errorf("malformed signature")
+    }
+    digest := sha256.Sum256([]byte(jws.Protected + "." + jws.Payload))
+    if !ecdsa.Verify(ts.accountKey, digest[:], new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:])) {
+        return nil, fmt.Errorf("invalid signature")
+    }
+    return base64.RawURLEncoding.DecodeString(jws.Payload)
+}
+
+func (ts *testServer) keyAuth() string {
+    ts.mu.Lock()
+    defer ts.mu.Unlock()
+    thumb, _ := thumbprint(ts.accountKey)
+    return ts.token + "." + thumb
+}
+
+// validate 依驗證類型回連客戶端
+func (ts *testServer) validate(typ string) error {
+    want := ts.keyAuth()
+    switch typ {
+    case "http-01":
+        resp, err := http.Get("http://" + ts.httpAddr + httpChallengePath + ts.token)
+        if err != nil {
+            return err
+        }
+        defer resp.Body.Close()
+        body, _ := io.ReadAll(resp.Body)
+        if string(body) != want {
+            return fmt.Errorf("unexpected key authorization %q", body)
+        }
+        return nil
+    case "tls-alpn-01":
+        conn, err := tls.Dial("tcp", ts.tlsAddr, &tls.Config{
+            ServerName:         ts.domain,
+            NextProtos:         []string{ALPNProto},
+            InsecureSkipVerify: true,
+        })
+        if err != nil {
+            return err
+        }
+        defer conn.Close()
+        state := conn.ConnectionState()
+        if state.NegotiatedProtocol != ALPNProto {
+            return fmt.Errorf("unexpected ALPN %q", state.NegotiatedProtocol)
+        }
+        sum := sha256.Sum256([]byte(want))
+        for _, ext := range state.PeerCertificates[0].Extensions {
+            if ext.Id.Equal(idPeAcmeIdentifier) {
+                var got []byte
+                if _, err := asn1.Unmarshal(ext.Value, &got); err != nil {
+                    return err
+                }
+                if !bytes.Equal(got, sum[:]) {
+                    return fmt.Errorf("acmeIdentifier mismatch")
+                }
+                return nil
+            }
+        }
+        return fmt.Errorf("acmeIdentifier extension missing")
+    }
+    return fmt.Errorf("unsupported challenge %s", typ)
+}
+
+func (ts *testServer) issue(csrB64 string) error {
+    der, err := base64.RawURLEncoding.DecodeString(csrB64)
+    if err != nil {
+        return err
+    }
+    csr, err := x509.ParseCertificateRequest(der)
+    if err != nil {
+        return err
+    }
+    tmpl := &x509.Certificate{
+        SerialNumber: big.NewInt(time.Now().UnixNano()),
+        Subject:      csr.Subject,
+        DNSNames:     csr.DNSNames,
+        NotBefore:    time.Now().Add(-time.Hour),
+        NotAfter:     time.Now().Add(90 * 24 * time.Hour),
+        ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+    }
+    leaf, err := x509.CreateCertificate(rand.Reader, tmpl, ts.caCert, csr.PublicKey, ts.caKey)
+    if err != nil {
+        return err
+    }
+    ts.mu.Lock()
+    defer ts.mu.Unlock()
+    ts.certPEM = append(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: leaf}),
+        pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ts.caCert.Raw})...)
+    return nil
+}
+
+// freeAddr 取得一個可用的本機位址
+func freeAddr(t *testing.T) string {
+    ln, err := net.Listen("tcp", "127.0.0.1:0")
+    require.NoError(t, err)
+    addr := ln.Addr().String()
+    ln.Close()
+    return addr
+}
+
+func TestObtainOrRenewHTTP01(t *testing.T) {
+    ts := newTestServer(t)
+    ts.httpAddr = freeAddr(t)
+    storage := Storage{Dir: t.TempDir()}
+
+    client := &Client{
+        DirectoryURL: ts.url("/directory"),
+        Solvers:      map[string]Solver{"http-01": &HTTP01Solver{Addr: ts.httpAddr}},
+        PollInterval: 10 * time.Millisecond,
+    }
+    issued, err := ObtainOrRenew(context.Background(), client, storage, "admin@example.com", "proxy.example.com", 30*24*time.Hour)
+    require.NoError(t, err)
+    assert.True(t, issued, "certificate should be issued on first run")
+    assert.Equal(t, []string{"http-01"}, ts.validated)
+
+    certPath, keyPath := storage.CertPaths("proxy.example.com")
+    pair, err := tls.LoadX509KeyPair(certPath, keyPath)
+    require.NoError(t, err, "saved certificate and key should match")
+    leaf, err := x509.ParseCertificate(pair.Certificate[0])
+    require.NoError(t, err)
+    assert.Equal(t, []string{"proxy.example.com"}, leaf.DNSNames)
+
+    // 憑證尚未接近到期，不應重新申請
+    issued, err = ObtainOrRenew(context.Background(), &Client{DirectoryURL: ts.url("/directory")}, storage, "", "proxy.example.com", 30*24*time.Hour)
+    require.NoError(t, err)
+    assert.False(t, issued, "valid certificate should not be renewed")
+}
+
+func TestObtainOrRenewTLSALPN01(t *testing.T) {
+    ts := newTestServer(t)
+    ts.tlsAddr = freeAddr(t)
+    storage := Storage{Dir: t.TempDir()}
+
+    client := &Client{
+        DirectoryURL: ts.url("/directory"),
+        Solvers:      map[string]Solver{"tls-alpn-01": &TLSALPN01Solver{Addr: ts.tlsAddr}},
+        PollInterval: 10 * time.Millisecond,
+    }
+    issued, err := ObtainOrRenew(context.Background(), client, storage, "", "proxy.example.com", 30*24*time.Hour)
+    require.NoError(t, err)
+    assert.True(t, issued)
+    assert.Equal(t, []string{"tls-alpn-01"}, ts.validated)
+
+    // 距到期時間小於 renewBefore 時應續期，並沿用同一帳戶金鑰
+    key, err := storage.AccountKey()
+    require.NoError(t, err)
+    client = &Client{
+        DirectoryURL: ts.url("/directory"),
+        Solvers:      map[string]Solver{"tls-alpn-01": &TLSALPN01Solver{Addr: ts.tlsAddr}},
+        PollInterval: 10 * time.Millisecond,
+    }
+    issued, err = ObtainOrRenew(context.Background(), client, storage, "", "proxy.example.com", 365*24*time.Hour)
+    require.NoError(t, err)
+    assert.True(t, issued, "certificate within renewal window should be renewed")
+    assert.True(t, key.Equal(client.Key), "account key should be reused from storage")
+}
+
+func TestObtainFailsOnInvalidChallenge(t *testing.T) {
+    ts := newTestServer(t)
+    ts.httpAddr = freeAddr(t) // 伺服器回連此位址，但 solver 監聽在另一個位址
+
+    client := &Client{
+        DirectoryURL: ts.url("/directory"),
+        Solvers:      map[string]Solver{"http-01": &HTTP01Solver{Addr: freeAddr(t)}},
+        PollInterval: 10 * time.Millisecond,
+    }
+    _, err := ObtainOrRenew(context.Background(), client, Storage{Dir: t.TempDir()}, "", "proxy.example.com", 0)
+    assert.Error(t, err)
+    assert.Contains(t, err.Error(), "invalid")
+}
+
+func TestDirectoryURL(t *testing.T) {
+    url, err := DirectoryURL("letsencrypt")
+    assert.NoError(t, err)
+    assert.Equal(t, LetsEncryptURL, url)
+
+    url, err = DirectoryURL("https://acme.internal/directory")
+    assert.NoError(t, err)
+    assert.Equal(t, "https://acme.internal/directory", url)
+
+    _, err = DirectoryURL("zerossl")
+    assert.Error(t, err)
+}
@@ -0,0 +1,134 @@
+package acme
+
+import (
+    "context"
+    "crypto/ecdsa"
+    "crypto/elliptic"
+    "crypto/rand"
+    "crypto/x509"
+    "encoding/pem"
+    "fmt"
+    "log"
+    "os"
+    "path/filepath"
+    "time"
+)
+
+// Storage 管理狀態目錄下的帳戶金鑰與憑證檔案
+type Storage struct {
+    Dir string
+}
+
+// AccountKey 讀取帳戶金鑰，不存在時生成並保存
+func (s Storage) AccountKey() (*ecdsa.PrivateKey, error) {
+    path := filepath.Join(s.Dir, "account.key")
+    data, err := os.ReadFile(path)
+    if err == nil {
+        return decodeECKey(data)
+    }
+    if !os.IsNotExist(err) {
+        return nil, err
+    }
+
+    key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+    if err != nil {
+        return nil, err
+    }
+    data, err = encodeECKey(key)
+    if err != nil {
+        return nil, err
+    }
+    if err := os.MkdirAll(s.Dir, 0700); err != nil {
+        return nil, err
+    }
+    if err := os.WriteFile(path, data, 0600); err != nil {
+        return nil, err
+    }
+    log.Printf("Generated ACME account key at %s", path)
+    return key, nil
+}
+
+// CertPaths 返回網域憑證鏈與私鑰的存放路徑
+func (s Storage) CertPaths(domain string) (certPath, keyPath string) {
+    dir := filepath.Join(s.Dir, "certs", domain)
+    return filepath.Join(dir, "fullchain.pem"), filepath.Join(dir, "privkey.pem")
+}
+
+// SaveCertificate 保存憑證鏈與私鑰
+func (s Storage) SaveCertificate(domain string, certPEM, keyPEM []byte) error {
+    certPath, keyPath := s.CertPaths(domain)
+    if err := os.MkdirAll(filepath.Dir(certPath), 0700); err != nil {
+        return err
+    }
+    if err := os.WriteFile(keyPath, keyPEM, 0600); err != nil {
+        return err
+    }
+    return os.WriteFile(certPath, certPEM, 0644)
+}
+
+// NotAfter 返回已保存憑證的到期時間
+func (s Storage) NotAfter(domain string) (time.Time, error) {
+    certPath, _ := s.CertPaths(domain)
+    data, err := os.ReadFile(certPath)
+    if err != nil {
+        return time.Time{}, err
+    }
+    block, _ := pem.Decode(data)
+    if block == nil {
+        return time.Time{}, fmt.Errorf("no PEM data in %s", certPath)
+    }
+    cert, err := x509.ParseCertificate(block.Bytes)
+    if err != nil {
+        return time.Time{}, err
+    }
+    return cert.NotAfter, nil
+}
+
+// ObtainOrRenew 在憑證不存在或距離到期少於 renewBefore 時申請新憑證，
+// 返回是否實際申請了新憑證
+func ObtainOrRenew(ctx context.Context, c *Client, s Storage, email, domain string, renewBefore time.Duration) (bool, error) {
+    if notAfter, err := s.NotAfter(domain); err == nil {
+        if time.Until(notAfter) > renewBefore {
+            log.Printf("Certificate for %s is valid until %s, no renewal needed.", domain, notAfter.Format(time.RFC3339))
+            return false, nil
+        }
+        log.Printf("Certificate for %s expires at %s, renewing...", domain, notAfter.Format(time.RFC3339))
+    }
+
+    if c.Key == nil {
+        key, err := s.AccountKey()
+        if err != nil {
+            return false, fmt.Errorf("failed to load ACME account key: %v", err)
+        }
+        c.Key = key
+    }
+    if err := c.Register(ctx, email); err != nil {
+        return false, err
+    }
+    certPEM, keyPEM, err := c.Obtain(ctx, []string{domain})
+    if err != nil {
+        return false, err
+    }
+    if err := s.SaveCertificate(domain, certPEM, keyPEM); err != nil {
+        return false, fmt.Errorf("failed to save certificate: %v", err)
+    }
+    certPath, _ := s.CertPaths(domain)
+    log.Printf("Certificate for %s saved to %s", domain, certPath)
+    return true, nil
+}
+
+func encodeECKey(key *ecdsa.PrivateKey) ([]byte, error) {
+    der, err := x509.MarshalECPrivateKey(key)
+    if err != nil {
+        return nil, err
+    }
+    return pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), nil
+}
+
+func decodeECKey(data []byte) (*ecdsa.PrivateKey, error) {
+    block, _ := pem.Decode(data)
+    if block == nil {
+        return nil, fmt.Errorf("invalid PEM key")
+    }
+    return x509.ParseECPrivateKey(block.Bytes)
+}
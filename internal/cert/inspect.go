@@ -0,0 +1,88 @@
+package cert
+
+import (
+    "crypto/sha256"
+    "crypto/tls"
+    "crypto/x509"
+    "encoding/hex"
+    "encoding/pem"
+    "fmt"
+    "os"
+    "time"
+)
+
+// Status 描述已安裝憑證的狀態
+type Status struct {
+    Path        string    `json:"path"`
+    Subject     string    `json:"subject"`
+    DNSNames    []string  `json:"dns_names"`
+    IPAddresses []string  `json:"ip_addresses"`
+    Issuer      string    `json:"issuer"`
+    NotBefore   time.Time `json:"not_before"`
+    NotAfter    time.Time `json:"not_after"`
+    Fingerprint string    `json:"fingerprint"` // 葉憑證 DER 的 SHA-256
+    KeyMatches  bool      `json:"key_matches"`
+}
+
+// ExpiresWithin 判斷憑證是否會在 d 時間內到期
+func (s Status) ExpiresWithin(d time.Duration) bool {
+    return time.Until(s.NotAfter) <= d
+}
+
+// Inspect 解析憑證並檢查私鑰是否匹配；keyPath 為空時不檢查私鑰
+func Inspect(certPath, keyPath string) (Status, error) {
+    status := Status{Path: certPath}
+    leaf, err := loadLeaf(certPath)
+    if err != nil {
+        return status, err
+    }
+
+    status.Subject = leaf.Subject.String()
+    status.DNSNames = leaf.DNSNames
+    for _, ip := range leaf.IPAddresses {
+        status.IPAddresses = append(status.IPAddresses, ip.String())
+    }
+    status.Issuer = leaf.Issuer.String()
+    status.NotBefore = leaf.NotBefore
+    status.NotAfter = leaf.NotAfter
+    sum := sha256.Sum256(leaf.Raw)
+    status.Fingerprint = hex.EncodeToString(sum[:])
+
+    if keyPath != "" {
+        status.KeyMatches = keyMatches(leaf, keyPath)
+    }
+    return status, nil
+}
+
+// Fingerprint 返回憑證檔案中葉憑證的 SHA-256 指紋，檔案不存在時返回空字串
+func Fingerprint(certPath string) string {
+    leaf, err := loadLeaf(certPath)
+    if err != nil {
+        return ""
+    }
+    sum := sha256.Sum256(leaf.Raw)
+    return hex.EncodeToString(sum[:])
+}
+
+func loadLeaf(certPath string) (*x509.Certificate, error) {
+    data, err := os.ReadFile(certPath)
+    if err != nil {
+        return nil, err
+    }
+    block, _ := pem.Decode(data)
+    if block == nil || block.Type != "CERTIFICATE" {
+        return nil, fmt.Errorf("no certificate found in %s", certPath)
+    }
+    return x509.ParseCertificate(block.Bytes)
+}
+
+// keyMatches 檢查私鑰是否對應憑證公鑰
+func keyMatches(leaf *x509.Certificate, keyPath string) bool {
+    data, err := os.ReadFile(keyPath)
+    if err != nil {
+        return false
+    }
+    // tls.X509KeyPair 會在私鑰與憑證公鑰不匹配時返回錯誤
+    _, err = tls.X509KeyPair(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: leaf.Raw}), data)
+    return err == nil
+}
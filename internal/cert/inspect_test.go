@@ -0,0 +1,75 @@
+package cert
+
+import (
+    "crypto/ecdsa"
+    "crypto/elliptic"
+    "crypto/rand"
+    "crypto/x509"
+    "crypto/x509/pkix"
+    "encoding/pem"
+    "math/big"
+    "net"
+    "os"
+    "path/filepath"
+    "testing"
+    "time"
+
+    "github.com/stretchr/testify/assert"
+    "github.com/stretchr/testify/require"
+)
+
+// writeTestCert 生成自簽憑證與私鑰並寫入臨時目錄
+func writeTestCert(t *testing.T, notAfter time.Time) (certPath, keyPath string) {
+    key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+    require.NoError(t, err)
+    tmpl := &x509.Certificate{
+        SerialNumber: big.NewInt(42),
+        Subject:      pkix.Name{CommonName: "proxy.example.com"},
+        DNSNames:     []string{"proxy.example.com"},
+        IPAddresses:  []net.IP{net.ParseIP("10.147.17.5")},
+        NotBefore:    time.Now().Add(-time.Hour),
+        NotAfter:     notAfter,
+    }
+    der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+    require.NoError(t, err)
+    keyDER, err := x509.MarshalECPrivateKey(key)
+    require.NoError(t, err)
+
+    dir := t.TempDir()
+    certPath = filepath.Join(dir, "fullchain.pem")
+    keyPath = filepath.Join(dir, "privkey.pem")
+    require.NoError(t, os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644))
+    require.NoError(t, os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600))
+    return certPath, keyPath
+}
+
+func TestInspect(t *testing.T) {
+    certPath, keyPath := writeTestCert(t, time.Now().Add(10*24*time.Hour))
+
+    status, err := Inspect(certPath, keyPath)
+    require.NoError(t, err)
+    assert.Equal(t, []string{"proxy.example.com"}, status.DNSNames)
+    assert.Equal(t, []string{"10.147.17.5"}, status.IPAddresses)
+    assert.Contains(t, status.Issuer, "proxy.example.com", "self-signed issuer should equal subject")
+    assert.True(t, status.KeyMatches, "key should match certificate")
+    assert.Len(t, status.Fingerprint, 64)
+    assert.Equal(t, status.Fingerprint, Fingerprint(certPath))
+
+    assert.True(t, status.ExpiresWithin(30*24*time.Hour))
+    assert.False(t, status.ExpiresWithin(5*24*time.Hour))
+}
+
+func TestInspectKeyMismatch(t *testing.T) {
+    certPath, _ := writeTestCert(t, time.Now().Add(time.Hour))
+    _, otherKey := writeTestCert(t, time.Now().Add(time.Hour))
+
+    status, err := Inspect(certPath, otherKey)
+    require.NoError(t, err)
+    assert.False(t, status.KeyMatches, "key from another certificate should not match")
+}
+
+func TestInspectMissingFile(t *testing.T) {
+    _, err := Inspect(filepath.Join(t.TempDir(), "missing.pem"), "")
+    assert.Error(t, err)
+    assert.Empty(t, Fingerprint(filepath.Join(t.TempDir(), "missing.pem")))
+}
@@ -0,0 +1,161 @@
+package cert
+
+import (
+    "crypto/ecdsa"
+    "crypto/elliptic"
+    "crypto/rand"
+    "crypto/x509"
+    "crypto/x509/pkix"
+    "encoding/pem"
+    "fmt"
+    "log"
+    "math/big"
+    "net"
+    "os"
+    "path/filepath"
+    "time"
+)
+
+const (
+    caValidity     = 10 * 365 * 24 * time.Hour
+    serverValidity = 397 * 24 * time.Hour // 多數客戶端接受的最長有效期
+)
+
+// CA 是用於實驗室與內網部署的本地憑證頒發機構
+type CA struct {
+    Cert     *x509.Certificate
+    Key      *ecdsa.PrivateKey
+    CertPath string
+}
+
+// LoadOrCreateCA 從 dir 讀取 CA，不存在時建立新的 CA
+func LoadOrCreateCA(dir, commonName string) (*CA, error) {
+    certPath := filepath.Join(dir, "ca.crt")
+    keyPath := filepath.Join(dir, "ca.key")
+
+    if _, err := os.Stat(certPath); err == nil {
+        caCert, err := loadLeaf(certPath)
+        if err != nil {
+            return nil, err
+        }
+        data, err := os.ReadFile(keyPath)
+        if err != nil {
+            return nil, err
+        }
+        block, _ := pem.Decode(data)
+        if block == nil {
+            return nil, fmt.Errorf("no PEM key found in %s", keyPath)
+        }
+        key, err := x509.ParseECPrivateKey(block.Bytes)
+        if err != nil {
+            return nil, err
+        }
+        return &CA{Cert: caCert, Key: key, CertPath: certPath}, nil
+    }
+
+    key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+    if err != nil {
+        return nil, err
+    }
+    serial, err := randomSerial()
+    if err != nil {
+        return nil, err
+    }
+    tmpl := &x509.Certificate{
+        SerialNumber:          serial,
+        Subject:               pkix.Name{CommonName: commonName, Organization: []string{"go-auto-proxy"}},
+        NotBefore:             time.Now().Add(-time.Hour),
+        NotAfter:              time.Now().Add(caValidity),
+        IsCA:                  true,
+        BasicConstraintsValid: true,
+        MaxPathLenZero:        true,
+        KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+    }
+    der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+    if err != nil {
+        return nil, fmt.Errorf("failed to create CA certificate: %v", err)
+    }
+    caCert, err := x509.ParseCertificate(der)
+    if err != nil {
+        return nil, err
+    }
+    keyPEM, err := encodeKey(key)
+    if err != nil {
+        return nil, err
+    }
+
+    if err := os.MkdirAll(dir, 0700); err != nil {
+        return nil, err
+    }
+    if err := os.WriteFile(keyPath, keyPEM, 0600); err != nil {
+        return nil, err
+    }
+    if err := os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644); err != nil {
+        return nil, err
+    }
+    log.Printf("Created local CA at %s", certPath)
+    return &CA{Cert: caCert, Key: key, CertPath: certPath}, nil
+}
+
+// IssueServerCert 由 CA 簽發伺服器憑證，返回包含 CA 的憑證鏈與私鑰
+func (ca *CA) IssueServerCert(dnsNames []string, ips []net.IP) (certPEM, keyPEM []byte, err error) {
+    if len(dnsNames) == 0 && len(ips) == 0 {
+        return nil, nil, fmt.Errorf("at least one DNS name or IP address is required")
+    }
+    key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+    if err != nil {
+        return nil, nil, err
+    }
+    serial, err := randomSerial()
+    if err != nil {
+        return nil, nil, err
+    }
+    cn := ""
+    if len(dnsNames) > 0 {
+        cn = dnsNames[0]
+    } else {
+        cn = ips[0].String()
+    }
+    notAfter := time.Now().Add(serverValidity)
+    if notAfter.After(ca.Cert.NotAfter) {
+        notAfter = ca.Cert.NotAfter
+    }
+    tmpl := &x509.Certificate{
+        SerialNumber: serial,
+        Subject:      pkix.Name{CommonName: cn},
+        DNSNames:     dnsNames,
+        IPAddresses:  ips,
+        NotBefore:    time.Now().Add(-time.Hour),
+        NotAfter:     notAfter,
+        KeyUsage:     x509.KeyUsageDigitalSignature,
+        ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+    }
+    der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.Cert, &key.PublicKey, ca.Key)
+    if err != nil {
+        return nil, nil, fmt.Errorf("failed to issue server certificate: %v", err)
+    }
+    certPEM = append(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+        pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.Cert.Raw})...)
+    keyPEM, err = encodeKey(key)
+    if err != nil {
+        return nil, nil, err
+    }
+    return certPEM, keyPEM, nil
+}
+
+// PEM 返回 CA 憑證的 PEM 編碼，供客戶端匯入或固定
+func (ca *CA) PEM() []byte {
+    return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.Cert.Raw})
+}
+
+func encodeKey(key *ecdsa.PrivateKey) ([]byte, error) {
+    der, err := x509.MarshalECPrivateKey(key)
+    if err != nil {
+        return nil, err
+    }
+    return pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), nil
+}
+
+func randomSerial() (*big.Int, error) {
+    return rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+}
@@ -0,0 +1,48 @@
+package cert
+
+import (
+    "crypto/x509"
+    "encoding/pem"
+    "net"
+    "testing"
+
+    "github.com/stretchr/testify/assert"
+    "github.com/stretchr/testify/require"
+)
+
+func TestSelfSignedCA(t *testing.T) {
+    dir := t.TempDir()
+    ca, err := LoadOrCreateCA(dir, "test CA")
+    require.NoError(t, err)
+    assert.True(t, ca.Cert.IsCA)
+
+    // 再次讀取應得到同一個 CA
+    again, err := LoadOrCreateCA(dir, "test CA")
+    require.NoError(t, err)
+    assert.Equal(t, ca.Cert.Raw, again.Cert.Raw, "existing CA should be reused")
+
+    certPEM, keyPEM, err := again.IssueServerCert([]string{"proxy.lan"}, []net.IP{net.ParseIP("10.147.17.5")})
+    require.NoError(t, err)
+    assert.Contains(t, string(keyPEM), "PRIVATE KEY")
+
+    block, rest := pem.Decode(certPEM)
+    require.NotNil(t, block)
+    leaf, err := x509.ParseCertificate(block.Bytes)
+    require.NoError(t, err)
+    chainCA, _ := pem.Decode(rest)
+    require.NotNil(t, chainCA, "chain should include the CA certificate")
+
+    roots := x509.NewCertPool()
+    roots.AppendCertsFromPEM(ca.PEM())
+    for _, name := range []string{"proxy.lan", "10.147.17.5"} {
+        _, err := leaf.Verify(x509.VerifyOptions{DNSName: name, Roots: roots})
+        assert.NoError(t, err, "certificate should verify for %s against the exported CA", name)
+    }
+}
+
+func TestIssueServerCertRequiresNames(t *testing.T) {
+    ca, err := LoadOrCreateCA(t.TempDir(), "test CA")
+    require.NoError(t, err)
+    _, _, err = ca.IssueServerCert(nil, nil)
+    assert.Error(t, err)
+}
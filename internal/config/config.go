@@ -2,20 +2,65 @@ package config
 
 import (
     "encoding/json"
+    "go-auto-proxy/internal/secret"
     "go-auto-proxy/internal/system"
     "os"
 )
 
+const (
+    configFile = "config.json"
+    // StateDir 工具的狀態目錄，存放 ACME 帳戶金鑰與憑證等
+    StateDir = ".go-auto-proxy"
+    // ReportFile 是 init 的結果報告，與 config.json 放在同一目錄
+    ReportFile = "init-report.json"
+)
+
+// Path 返回 config.json 的路徑
+func Path() string {
+    return configFile
+}
+
+// WriteConfig 寫入 config.json，檔案僅允許擁有者讀寫；有可用的金鑰時（見 secret.LoadKey）
+// Secret 欄位以 AES-GCM 加密，否則以原值寫入
 func WriteConfig(info system.SystemInfo) error {
-    file, err := os.Create("config.json")
+    key, err := secret.LoadKey()
     if err != nil {
         return err
     }
-    defer file.Close()
+    return writeConfig(info, key)
+}
+
+// WritePlainConfig 以原值寫入 Secret 欄位，用於停用加密
+func WritePlainConfig(info system.SystemInfo) error {
+    return writeConfig(info, nil)
+}
 
-    encoder := json.NewEncoder(file)
-    encoder.SetIndent("", "  ")
-    return encoder.Encode(map[string]interface{}{
-        "system": info,
-    })
-}
\ No newline at end of file
+func writeConfig(info system.SystemInfo, key []byte) error {
+    v := map[string]interface{}{"system": info}
+    var data []byte
+    var err error
+    if key != nil {
+        data, err = secret.MarshalIndentSealed(v, key, "", "  ")
+    } else {
+        data, err = secret.MarshalIndentRevealed(v, "", "  ")
+    }
+    if err != nil {
+        return err
+    }
+    return secret.WriteFile(configFile, append(data, '\n'))
+}
+
+// ReadConfig 讀取 config.json 中的系統配置
+func ReadConfig() (system.SystemInfo, error) {
+    var cfg struct {
+        System system.SystemInfo `json:"system"`
+    }
+    data, err := os.ReadFile(configFile)
+    if err != nil {
+        return cfg.System, err
+    }
+    if err := json.Unmarshal(data, &cfg); err != nil {
+        return cfg.System, err
+    }
+    return cfg.System, nil
+}
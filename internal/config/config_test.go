@@ -2,8 +2,10 @@ package config
 
 import (
     "encoding/json"
+    "go-auto-proxy/internal/secret"
     "go-auto-proxy/internal/system"
     "os"
+    "path/filepath"
     "testing"
 
     "github.com/stretchr/testify/assert"
@@ -38,4 +40,75 @@ func TestWriteConfig(t *testing.T) {
     assert.Equal(t, info.Architecture, sys.Architecture, "Architecture should match")
     assert.Equal(t, info.ExternalIP, sys.ExternalIP, "ExternalIP should match")
     assert.Equal(t, info.InternalIP, sys.InternalIP, "InternalIP should match")
-}
\ No newline at end of file
+}
+
+func TestReadConfig(t *testing.T) {
+    info := system.SystemInfo{OS: "linux", ExternalIP: "35.185.174.224"}
+    info.AcmeSH.Client = "native"
+    info.AcmeSH.Domain = "proxy.example.com"
+    info.TrojanGo.Password = "5636f0c2"
+    info.DNS.APIToken = "cf-token"
+
+    assert.NoError(t, WriteConfig(info))
+    defer os.Remove("config.json")
+
+    st, err := os.Stat("config.json")
+    assert.NoError(t, err)
+    assert.Equal(t, os.FileMode(0600), st.Mode().Perm(), "config.json holds credentials and must be owner-only")
+
+    got, err := ReadConfig()
+    assert.NoError(t, err)
+    assert.Equal(t, info.ExternalIP, got.ExternalIP, "ExternalIP should round-trip")
+    assert.Equal(t, "native", got.AcmeSH.Client, "ACME client should round-trip")
+    assert.Equal(t, "proxy.example.com", got.AcmeSH.Domain, "Domain should round-trip")
+    assert.Equal(t, "5636f0c2", got.TrojanGo.Password.Reveal(), "secrets are stored in clear text in the owner-only file")
+    assert.Equal(t, "cf-token", got.DNS.APIToken.Reveal())
+}
+
+func TestWriteConfigEncrypted(t *testing.T) {
+    key, err := secret.GenerateKey()
+    assert.NoError(t, err)
+    t.Setenv(secret.KeyEnv, key)
+    info := system.SystemInfo{OS: "linux"}
+    info.TrojanGo.Password = "5636f0c2"
+
+    assert.NoError(t, WriteConfig(info))
+    defer os.Remove("config.json")
+    data, err := os.ReadFile("config.json")
+    assert.NoError(t, err)
+    assert.NotContains(t, string(data), "5636f0c2")
+    assert.Contains(t, string(data), `"password": "enc:v1:`)
+
+    got, err := ReadConfig()
+    assert.NoError(t, err)
+    assert.Equal(t, "5636f0c2", got.TrojanGo.Password.Reveal(), "secrets are decrypted transparently")
+
+    t.Setenv(secret.KeyEnv, "")
+    _, err = ReadConfig()
+    assert.Error(t, err, "encrypted config cannot be read without the key")
+}
+
+func TestInitState(t *testing.T) {
+    original := InitStatePath
+    InitStatePath = filepath.Join(t.TempDir(), "state", "init-state.json")
+    defer func() { InitStatePath = original }()
+
+    _, err := ReadInitState()
+    assert.True(t, os.IsNotExist(err))
+
+    state := InitState{Step: "install/nginx", Completed: []string{"apt", "trojan-go"}, Interrupted: true, Error: "context canceled"}
+    assert.NoError(t, WriteInitState(state))
+
+    got, err := ReadInitState()
+    assert.NoError(t, err)
+    assert.Equal(t, "install/nginx", got.Step)
+    assert.True(t, got.Interrupted)
+    assert.True(t, got.IsCompleted("trojan-go"))
+    assert.False(t, got.IsCompleted("nginx"))
+    assert.False(t, got.UpdatedAt.IsZero())
+
+    assert.NoError(t, ClearInitState())
+    assert.NoError(t, ClearInitState())
+    _, err = ReadInitState()
+    assert.True(t, os.IsNotExist(err))
+}
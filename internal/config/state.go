@@ -0,0 +1,66 @@
+package config
+
+import (
+    "encoding/json"
+    "fmt"
+    "go-auto-proxy/internal/secret"
+    "os"
+    "path/filepath"
+    "time"
+)
+
+// InitStatePath 記錄 init 的進度，中斷或失敗後供 init --resume 使用
+var InitStatePath = filepath.Join(StateDir, "init-state.json")
+
+// InitState 是 init 的進度
+type InitState struct {
+    Step        string    `json:"step"`            // 中斷或失敗時所在的步驟
+    Completed   []string  `json:"completed"`       // 已完成的安裝步驟，繼續時略過
+    Interrupted bool      `json:"interrupted"`     // 因 SIGINT/SIGTERM 中止
+    Error       string    `json:"error,omitempty"` // 失敗原因
+    UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// IsCompleted 判斷安裝步驟是否已完成
+func (s InitState) IsCompleted(step string) bool {
+    for _, name := range s.Completed {
+        if name == step {
+            return true
+        }
+    }
+    return false
+}
+
+// ReadInitState 讀取 init 的進度；沒有進度檔時返回 os.ErrNotExist
+func ReadInitState() (InitState, error) {
+    var state InitState
+    data, err := os.ReadFile(InitStatePath)
+    if err != nil {
+        return state, err
+    }
+    if err := json.Unmarshal(data, &state); err != nil {
+        return state, fmt.Errorf("invalid %s: %v", InitStatePath, err)
+    }
+    return state, nil
+}
+
+// WriteInitState 寫入 init 的進度
+func WriteInitState(state InitState) error {
+    if err := os.MkdirAll(filepath.Dir(InitStatePath), 0700); err != nil {
+        return err
+    }
+    state.UpdatedAt = time.Now()
+    data, err := json.MarshalIndent(state, "", "  ")
+    if err != nil {
+        return err
+    }
+    return secret.WriteFile(InitStatePath, append(data, '\n'))
+}
+
+// ClearInitState 在 init 完成後刪除進度檔
+func ClearInitState() error {
+    if err := os.Remove(InitStatePath); err != nil && !os.IsNotExist(err) {
+        return err
+    }
+    return nil
+}
@@ -0,0 +1,190 @@
+package ddns
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "go-auto-proxy/internal/dns"
+    "go-auto-proxy/internal/secret"
+    "go-auto-proxy/internal/system"
+    "log"
+    "net"
+    "os"
+    "path/filepath"
+    "strings"
+    "time"
+)
+
+// State 是持久化到狀態目錄的最後已知 IP
+type State struct {
+    LastIP    string    `json:"last_ip"`
+    LastIPv6  string    `json:"last_ipv6,omitempty"`
+    CheckedAt time.Time `json:"checked_at"`
+    UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Updater 比較對外 IP 與 DNS 記錄，不一致時更新記錄；
+// IPv4 對應 A 記錄、IPv6 對應 AAAA 記錄，雙棧主機兩者都會同步
+type Updater struct {
+    Provider  dns.Provider
+    Name      string // 要維護的記錄名稱，如 proxy.example.com
+    TTL       int
+    Detect    func(ctx context.Context) (system.ExternalIPs, error)
+    StatePath string
+    // OnChange 在記錄更新後呼叫，可用於同步其他配置
+    OnChange func(ips system.ExternalIPs) error
+}
+
+// Run 執行一次檢查，返回記錄是否被更新；沒有偵測到的位址族不會修改對應的記錄
+func (u *Updater) Run(ctx context.Context) (bool, error) {
+    detected, err := u.Detect(ctx)
+    if err != nil {
+        return false, fmt.Errorf("failed to detect external IP: %v", err)
+    }
+    ipv4, err := parseFamily(detected.IPv4, true)
+    if err != nil {
+        return false, err
+    }
+    ipv6, err := parseFamily(detected.IPv6, false)
+    if err != nil {
+        return false, err
+    }
+    if ipv4 == "" && ipv6 == "" {
+        return false, fmt.Errorf("no external IPv4 or IPv6 address detected")
+    }
+
+    state, err := LoadState(u.StatePath)
+    if err != nil {
+        return false, err
+    }
+    if ipv4 != "" && state.LastIP != "" && state.LastIP != ipv4 {
+        log.Printf("External IPv4 changed: %s -> %s", state.LastIP, ipv4)
+    }
+    if ipv6 != "" && state.LastIPv6 != "" && state.LastIPv6 != ipv6 {
+        log.Printf("External IPv6 changed: %s -> %s", state.LastIPv6, ipv6)
+    }
+
+    // 一個位址族失敗時仍同步另一個，錯誤一併返回
+    changed := false
+    var errs []string
+    for _, r := range []struct{ typ, ip string }{{"A", ipv4}, {"AAAA", ipv6}} {
+        if r.ip == "" {
+            continue
+        }
+        updated, err := u.sync(ctx, r.typ, r.ip)
+        if err != nil {
+            errs = append(errs, err.Error())
+            continue
+        }
+        changed = changed || updated
+    }
+
+    if ipv4 != "" {
+        state.LastIP = ipv4
+    }
+    if ipv6 != "" {
+        state.LastIPv6 = ipv6
+    }
+    state.CheckedAt = time.Now()
+    if changed {
+        state.UpdatedAt = state.CheckedAt
+    }
+    if err := SaveState(u.StatePath, state); err != nil {
+        errs = append(errs, fmt.Sprintf("failed to save DDNS state: %v", err))
+    }
+    if changed && u.OnChange != nil {
+        if err := u.OnChange(system.ExternalIPs{IPv4: ipv4, IPv6: ipv6}); err != nil {
+            errs = append(errs, err.Error())
+        }
+    }
+    if len(errs) > 0 {
+        return changed, fmt.Errorf("%s", strings.Join(errs, "; "))
+    }
+    return changed, nil
+}
+
+// sync 讓 typ 記錄指向 ip，返回記錄是否被建立或更新
+func (u *Updater) sync(ctx context.Context, typ, ip string) (bool, error) {
+    records, err := u.Provider.FindRecords(ctx, u.Name, typ)
+    if err != nil {
+        return false, fmt.Errorf("failed to look up %s %s record: %v", u.Name, typ, err)
+    }
+    switch {
+    case len(records) == 0:
+        if _, err := u.Provider.CreateRecord(ctx, dns.Record{Name: u.Name, Type: typ, Value: ip, TTL: u.TTL}); err != nil {
+            return false, fmt.Errorf("failed to create %s %s record: %v", u.Name, typ, err)
+        }
+        log.Printf("DDNS: created %s %s -> %s", u.Name, typ, ip)
+        return true, nil
+    case records[0].Value != ip:
+        old := records[0].Value
+        records[0].Value = ip
+        if u.TTL > 0 {
+            records[0].TTL = u.TTL
+        }
+        if err := u.Provider.UpdateRecord(ctx, records[0]); err != nil {
+            return false, fmt.Errorf("failed to update %s %s record: %v", u.Name, typ, err)
+        }
+        log.Printf("DDNS: updated %s %s %s -> %s", u.Name, typ, old, ip)
+        return true, nil
+    }
+    log.Printf("DDNS: %s %s already points to %s", u.Name, typ, ip)
+    return false, nil
+}
+
+// parseFamily 驗證偵測到的位址屬於預期的位址族，空字串表示未偵測到
+func parseFamily(raw string, v4 bool) (string, error) {
+    raw = strings.TrimSpace(raw)
+    if raw == "" {
+        return "", nil
+    }
+    ip := net.ParseIP(raw)
+    if ip == nil || (ip.To4() != nil) != v4 {
+        return "", fmt.Errorf("detected external IP %q is not a valid address", raw)
+    }
+    return ip.String(), nil
+}
+
+// Loop 每隔 interval 執行一次，直到 ctx 結束；單次失敗只記錄不中止
+func (u *Updater) Loop(ctx context.Context, interval time.Duration) error {
+    ticker := time.NewTicker(interval)
+    defer ticker.Stop()
+    for {
+        if _, err := u.Run(ctx); err != nil {
+            log.Printf("DDNS check failed: %v", err)
+        }
+        select {
+        case <-ctx.Done():
+            return ctx.Err()
+        case <-ticker.C:
+        }
+    }
+}
+
+// LoadState 讀取狀態檔，不存在時返回空狀態
+func LoadState(path string) (State, error) {
+    var state State
+    data, err := os.ReadFile(path)
+    if os.IsNotExist(err) {
+        return state, nil
+    }
+    if err != nil {
+        return state, err
+    }
+    if err := json.Unmarshal(data, &state); err != nil {
+        return state, fmt.Errorf("invalid DDNS state in %s: %v", path, err)
+    }
+    return state, nil
+}
+
+// SaveState 寫入狀態檔
+func SaveState(path string, state State) error {
+    data, err := json.MarshalIndent(state, "", "  ")
+    if err != nil {
+        return err
+    }
+    if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+        return err
+    }
+    return secret.WriteFile(path, data)
+}
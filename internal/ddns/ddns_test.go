@@ -0,0 +1,156 @@
+package ddns
+
+import (
+    "context"
+    "go-auto-proxy/internal/dns"
+    "go-auto-proxy/internal/system"
+    "path/filepath"
+    "testing"
+
+    "github.com/stretchr/testify/assert"
+    "github.com/stretchr/testify/require"
+)
+
+// memoryProvider 是記憶體中的 dns.Provider
+type memoryProvider struct {
+    records []dns.Record
+    updates int
+}
+
+func (m *memoryProvider) FindRecords(ctx context.Context, name, typ string) ([]dns.Record, error) {
+    var out []dns.Record
+    for _, r := range m.records {
+        if r.Name == name && r.Type == typ {
+            out = append(out, r)
+        }
+    }
+    return out, nil
+}
+
+func (m *memoryProvider) CreateRecord(ctx context.Context, r dns.Record) (dns.Record, error) {
+    r.ID = "1"
+    m.records = append(m.records, r)
+    return r, nil
+}
+
+func (m *memoryProvider) UpdateRecord(ctx context.Context, r dns.Record) error {
+    m.updates++
+    for i := range m.records {
+        if m.records[i].ID == r.ID {
+            m.records[i] = r
+        }
+    }
+    return nil
+}
+
+func (m *memoryProvider) DeleteRecord(ctx context.Context, r dns.Record) error {
+    return nil
+}
+
+func TestUpdaterRun(t *testing.T) {
+    provider := &memoryProvider{}
+    ip := "35.185.174.224"
+    statePath := filepath.Join(t.TempDir(), "ddns.json")
+    u := &Updater{
+        Provider:  provider,
+        Name:      "proxy.example.com",
+        Detect:    func(ctx context.Context) (system.ExternalIPs, error) { return system.ExternalIPs{IPv4: ip}, nil },
+        StatePath: statePath,
+    }
+    ctx := context.Background()
+
+    changed, err := u.Run(ctx)
+    require.NoError(t, err)
+    assert.True(t, changed, "missing record should be created")
+    require.Len(t, provider.records, 1)
+    assert.Equal(t, "A", provider.records[0].Type)
+
+    changed, err = u.Run(ctx)
+    require.NoError(t, err)
+    assert.False(t, changed, "matching record should not be updated")
+    assert.Equal(t, 0, provider.updates)
+
+    ip = "35.185.174.225"
+    changed, err = u.Run(ctx)
+    require.NoError(t, err)
+    assert.True(t, changed)
+    assert.Equal(t, "35.185.174.225", provider.records[0].Value)
+
+    state, err := LoadState(statePath)
+    require.NoError(t, err)
+    assert.Equal(t, "35.185.174.225", state.LastIP, "last known IP should be persisted")
+    assert.False(t, state.UpdatedAt.IsZero())
+}
+
+func TestUpdaterIPv6(t *testing.T) {
+    provider := &memoryProvider{}
+    u := &Updater{
+        Provider:  provider,
+        Name:      "proxy.example.com",
+        Detect:    func(ctx context.Context) (system.ExternalIPs, error) { return system.ExternalIPs{IPv6: "2001:db8::1\n"}, nil },
+        StatePath: filepath.Join(t.TempDir(), "ddns.json"),
+    }
+    _, err := u.Run(context.Background())
+    require.NoError(t, err)
+    require.Len(t, provider.records, 1)
+    assert.Equal(t, "AAAA", provider.records[0].Type)
+}
+
+func TestUpdaterDualStack(t *testing.T) {
+    provider := &memoryProvider{records: []dns.Record{
+        {ID: "a", Name: "proxy.example.com", Type: "A", Value: "35.185.174.224"},
+        {ID: "aaaa", Name: "proxy.example.com", Type: "AAAA", Value: "2001:db8::1"},
+    }}
+    ips := system.ExternalIPs{IPv4: "35.185.174.224", IPv6: "2001:db8::2"}
+    statePath := filepath.Join(t.TempDir(), "ddns.json")
+    u := &Updater{
+        Provider:  provider,
+        Name:      "proxy.example.com",
+        Detect:    func(ctx context.Context) (system.ExternalIPs, error) { return ips, nil },
+        StatePath: statePath,
+    }
+    changed, err := u.Run(context.Background())
+    require.NoError(t, err)
+    assert.True(t, changed, "the AAAA record is synced even though the A record is current")
+    assert.Equal(t, "35.185.174.224", provider.records[0].Value)
+    assert.Equal(t, "2001:db8::2", provider.records[1].Value)
+    assert.Equal(t, 1, provider.updates)
+
+    ips = system.ExternalIPs{IPv4: "35.185.174.225"}
+    _, err = u.Run(context.Background())
+    require.NoError(t, err)
+    assert.Equal(t, "35.185.174.225", provider.records[0].Value)
+    assert.Equal(t, "2001:db8::2", provider.records[1].Value, "a missing IPv6 result leaves the AAAA record alone")
+
+    state, err := LoadState(statePath)
+    require.NoError(t, err)
+    assert.Equal(t, "35.185.174.225", state.LastIP)
+    assert.Equal(t, "2001:db8::2", state.LastIPv6)
+}
+
+func TestUpdaterRejectsInvalidIP(t *testing.T) {
+    provider := &memoryProvider{}
+    u := &Updater{
+        Provider:  provider,
+        Name:      "proxy.example.com",
+        Detect:    func(ctx context.Context) (system.ExternalIPs, error) { return system.ExternalIPs{IPv4: "<html>502 Bad Gateway</html>"}, nil },
+        StatePath: filepath.Join(t.TempDir(), "ddns.json"),
+    }
+    _, err := u.Run(context.Background())
+    assert.Error(t, err)
+    assert.Empty(t, provider.records, "invalid responses must never reach DNS")
+}
+
+func TestUpdaterOnChange(t *testing.T) {
+    var notified []system.ExternalIPs
+    u := &Updater{
+        Provider:  &memoryProvider{},
+        Name:      "proxy.example.com",
+        Detect:    func(ctx context.Context) (system.ExternalIPs, error) { return system.ExternalIPs{IPv4: "35.185.174.224"}, nil },
+        StatePath: filepath.Join(t.TempDir(), "ddns.json"),
+        OnChange:  func(ips system.ExternalIPs) error { notified = append(notified, ips); return nil },
+    }
+    u.Run(context.Background())
+    u.Run(context.Background())
+    assert.Equal(t, []system.ExternalIPs{{IPv4: "35.185.174.224"}}, notified, "OnChange should fire only when the record changes")
+}
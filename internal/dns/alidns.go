@@ -0,0 +1,217 @@
+package dns
+
+import (
+    "context"
+    "crypto/hmac"
+    "crypto/rand"
+    "crypto/sha1"
+    "encoding/base64"
+    "encoding/hex"
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "net/url"
+    "sort"
+    "strconv"
+    "strings"
+    "time"
+)
+
+const aliDNSBaseURL = "https://alidns.aliyuncs.com/"
+
+// AliDNS 使用阿里雲雲解析 RPC API（2015-01-09）管理記錄
+type AliDNS struct {
+    AccessKeyID     string
+    AccessKeySecret string
+    Zone            string
+    BaseURL         string
+    HTTPClient      *http.Client
+
+    zones []string // 未配置 Zone 時快取帳號下的網域
+}
+
+type aliRecord struct {
+    RecordID   string `json:"RecordId"`
+    DomainName string `json:"DomainName"`
+    RR         string `json:"RR"`
+    Type       string `json:"Type"`
+    Value      string `json:"Value"`
+    TTL        int    `json:"TTL"`
+}
+
+func (a *AliDNS) FindRecords(ctx context.Context, name, typ string) ([]Record, error) {
+    var result struct {
+        DomainRecords struct {
+            Record []aliRecord `json:"Record"`
+        } `json:"DomainRecords"`
+    }
+    params := url.Values{"SubDomain": {name}, "Type": {typ}}
+    if a.Zone != "" {
+        params.Set("DomainName", a.Zone)
+    }
+    if err := a.call(ctx, "DescribeSubDomainRecords", params, &result); err != nil {
+        return nil, err
+    }
+    records := make([]Record, 0, len(result.DomainRecords.Record))
+    for _, r := range result.DomainRecords.Record {
+        records = append(records, Record{
+            ID:    r.RecordID,
+            Name:  joinName(r.RR, r.DomainName),
+            Type:  r.Type,
+            Value: r.Value,
+            TTL:   r.TTL,
+        })
+    }
+    return records, nil
+}
+
+func (a *AliDNS) CreateRecord(ctx context.Context, r Record) (Record, error) {
+    zone, err := a.zoneFor(ctx, r.Name)
+    if err != nil {
+        return r, err
+    }
+    zone, rr, err := splitName(r.Name, zone)
+    if err != nil {
+        return r, err
+    }
+    params := url.Values{"DomainName": {zone}, "RR": {rr}, "Type": {r.Type}, "Value": {r.Value}}
+    if r.TTL > 0 {
+        params.Set("TTL", strconv.Itoa(r.TTL))
+    }
+    var result struct {
+        RecordID string `json:"RecordId"`
+    }
+    if err := a.call(ctx, "AddDomainRecord", params, &result); err != nil {
+        return r, err
+    }
+    r.ID = result.RecordID
+    return r, nil
+}
+
+func (a *AliDNS) UpdateRecord(ctx context.Context, r Record) error {
+    zone, err := a.zoneFor(ctx, r.Name)
+    if err != nil {
+        return err
+    }
+    _, rr, err := splitName(r.Name, zone)
+    if err != nil {
+        return err
+    }
+    params := url.Values{"RecordId": {r.ID}, "RR": {rr}, "Type": {r.Type}, "Value": {r.Value}}
+    if r.TTL > 0 {
+        params.Set("TTL", strconv.Itoa(r.TTL))
+    }
+    return a.call(ctx, "UpdateDomainRecord", params, nil)
+}
+
+func (a *AliDNS) DeleteRecord(ctx context.Context, r Record) error {
+    return a.call(ctx, "DeleteDomainRecord", url.Values{"RecordId": {r.ID}}, nil)
+}
+
+// zoneFor 返回名稱所屬的網域：優先使用配置的 Zone，否則以 DescribeDomains 列出帳號下的網域並取最長的匹配
+func (a *AliDNS) zoneFor(ctx context.Context, name string) (string, error) {
+    if a.Zone != "" {
+        return a.Zone, nil
+    }
+    if a.zones == nil {
+        var zones []string
+        for page := 1; ; page++ {
+            var result struct {
+                TotalCount int `json:"TotalCount"`
+                Domains    struct {
+                    Domain []struct {
+                        DomainName string `json:"DomainName"`
+                    } `json:"Domain"`
+                } `json:"Domains"`
+            }
+            params := url.Values{"PageNumber": {strconv.Itoa(page)}, "PageSize": {"100"}}
+            if err := a.call(ctx, "DescribeDomains", params, &result); err != nil {
+                return "", fmt.Errorf("failed to list alidns domains: %v", err)
+            }
+            for _, d := range result.Domains.Domain {
+                zones = append(zones, d.DomainName)
+            }
+            if len(result.Domains.Domain) == 0 || len(zones) >= result.TotalCount {
+                break
+            }
+        }
+        a.zones = zones
+    }
+    return longestZone(name, a.zones)
+}
+
+// call 以 HMAC-SHA1 簽名呼叫 RPC API
+func (a *AliDNS) call(ctx context.Context, action string, params url.Values, result interface{}) error {
+    nonce := make([]byte, 16)
+    if _, err := rand.Read(nonce); err != nil {
+        return err
+    }
+    params.Set("Action", action)
+    params.Set("Format", "JSON")
+    params.Set("Version", "2015-01-09")
+    params.Set("AccessKeyId", a.AccessKeyID)
+    params.Set("SignatureMethod", "HMAC-SHA1")
+    params.Set("SignatureVersion", "1.0")
+    params.Set("SignatureNonce", hex.EncodeToString(nonce))
+    params.Set("Timestamp", time.Now().UTC().Format("2006-01-02T15:04:05Z"))
+    params.Set("Signature", aliSignature(http.MethodGet, params, a.AccessKeySecret))
+
+    base := a.BaseURL
+    if base == "" {
+        base = aliDNSBaseURL
+    }
+    req, err := http.NewRequestWithContext(ctx, http.MethodGet, base+"?"+params.Encode(), nil)
+    if err != nil {
+        return err
+    }
+    resp, err := httpClient(a.HTTPClient).Do(req)
+    if err != nil {
+        return fmt.Errorf("alidns request failed: %v", err)
+    }
+    defer resp.Body.Close()
+
+    var body json.RawMessage
+    if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+        return fmt.Errorf("failed to decode alidns response (HTTP %d): %v", resp.StatusCode, err)
+    }
+    if resp.StatusCode != http.StatusOK {
+        var apiErr struct {
+            Code    string `json:"Code"`
+            Message string `json:"Message"`
+        }
+        json.Unmarshal(body, &apiErr)
+        return fmt.Errorf("alidns API error (HTTP %d): %s: %s", resp.StatusCode, apiErr.Code, apiErr.Message)
+    }
+    if result != nil {
+        return json.Unmarshal(body, result)
+    }
+    return nil
+}
+
+// aliSignature 依阿里雲 RPC 簽名規範計算簽名（不含 Signature 參數本身）
+func aliSignature(method string, params url.Values, secret string) string {
+    keys := make([]string, 0, len(params))
+    for k := range params {
+        if k != "Signature" {
+            keys = append(keys, k)
+        }
+    }
+    sort.Strings(keys)
+    pairs := make([]string, 0, len(keys))
+    for _, k := range keys {
+        pairs = append(pairs, aliEscape(k)+"="+aliEscape(params.Get(k)))
+    }
+    stringToSign := method + "&" + aliEscape("/") + "&" + aliEscape(strings.Join(pairs, "&"))
+
+    mac := hmac.New(sha1.New, []byte(secret+"&"))
+    mac.Write([]byte(stringToSign))
+    return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// aliEscape 是阿里雲要求的 RFC 3986 百分比編碼
+func aliEscape(s string) string {
+    s = url.QueryEscape(s)
+    s = strings.ReplaceAll(s, "+", "%20")
+    s = strings.ReplaceAll(s, "*", "%2A")
+    return strings.ReplaceAll(s, "%7E", "~")
+}
@@ -0,0 +1,170 @@
+package dns
+
+import (
+    "bytes"
+    "context"
+    "encoding/json"
+    "fmt"
+    "io"
+    "net/http"
+    "net/url"
+    "strings"
+)
+
+const cloudflareBaseURL = "https://api.cloudflare.com/client/v4"
+
+// Cloudflare 使用 Cloudflare API v4 與 API token 管理記錄
+type Cloudflare struct {
+    Token      string
+    Zone       string
+    BaseURL    string
+    HTTPClient *http.Client
+
+    zoneIDs map[string]string
+}
+
+type cloudflareRecord struct {
+    ID      string `json:"id,omitempty"`
+    Type    string `json:"type"`
+    Name    string `json:"name"`
+    Content string `json:"content"`
+    TTL     int    `json:"ttl"`
+}
+
+func (c *Cloudflare) FindRecords(ctx context.Context, name, typ string) ([]Record, error) {
+    zoneID, err := c.zoneID(ctx, name)
+    if err != nil {
+        return nil, err
+    }
+    q := url.Values{"name": {name}, "type": {typ}}
+    var result []cloudflareRecord
+    if err := c.do(ctx, http.MethodGet, "/zones/"+zoneID+"/dns_records?"+q.Encode(), nil, &result); err != nil {
+        return nil, err
+    }
+    records := make([]Record, 0, len(result))
+    for _, r := range result {
+        records = append(records, Record{ID: r.ID, Name: r.Name, Type: r.Type, Value: r.Content, TTL: r.TTL})
+    }
+    return records, nil
+}
+
+func (c *Cloudflare) CreateRecord(ctx context.Context, r Record) (Record, error) {
+    zoneID, err := c.zoneID(ctx, r.Name)
+    if err != nil {
+        return r, err
+    }
+    var created cloudflareRecord
+    err = c.do(ctx, http.MethodPost, "/zones/"+zoneID+"/dns_records", toCloudflare(r), &created)
+    if err != nil {
+        return r, err
+    }
+    r.ID = created.ID
+    return r, nil
+}
+
+func (c *Cloudflare) UpdateRecord(ctx context.Context, r Record) error {
+    zoneID, err := c.zoneID(ctx, r.Name)
+    if err != nil {
+        return err
+    }
+    return c.do(ctx, http.MethodPut, "/zones/"+zoneID+"/dns_records/"+r.ID, toCloudflare(r), nil)
+}
+
+func (c *Cloudflare) DeleteRecord(ctx context.Context, r Record) error {
+    zoneID, err := c.zoneID(ctx, r.Name)
+    if err != nil {
+        return err
+    }
+    return c.do(ctx, http.MethodDelete, "/zones/"+zoneID+"/dns_records/"+r.ID, nil, nil)
+}
+
+func toCloudflare(r Record) cloudflareRecord {
+    ttl := r.TTL
+    if ttl == 0 {
+        ttl = 1 // 1 表示自動
+    }
+    return cloudflareRecord{Type: r.Type, Name: r.Name, Content: r.Value, TTL: ttl}
+}
+
+// zoneID 查詢名稱所屬 zone 的 ID；未配置 zone 時由長到短嘗試各級父網域
+func (c *Cloudflare) zoneID(ctx context.Context, name string) (string, error) {
+    candidates := []string{c.Zone}
+    if c.Zone == "" {
+        candidates = nil
+        labels := strings.Split(strings.TrimSuffix(name, "."), ".")
+        for i := 0; i < len(labels)-1; i++ {
+            candidates = append(candidates, strings.Join(labels[i:], "."))
+        }
+    }
+
+    for _, zone := range candidates {
+        if id, ok := c.zoneIDs[zone]; ok {
+            return id, nil
+        }
+        var zones []struct {
+            ID string `json:"id"`
+        }
+        if err := c.do(ctx, http.MethodGet, "/zones?"+url.Values{"name": {zone}}.Encode(), nil, &zones); err != nil {
+            return "", err
+        }
+        if len(zones) > 0 {
+            if c.zoneIDs == nil {
+                c.zoneIDs = make(map[string]string)
+            }
+            c.zoneIDs[zone] = zones[0].ID
+            return zones[0].ID, nil
+        }
+    }
+    return "", fmt.Errorf("no cloudflare zone found for %s", name)
+}
+
+// do 發送 API 請求並解開 Cloudflare 的回應信封
+func (c *Cloudflare) do(ctx context.Context, method, path string, body, result interface{}) error {
+    base := c.BaseURL
+    if base == "" {
+        base = cloudflareBaseURL
+    }
+    var reader io.Reader
+    if body != nil {
+        data, err := json.Marshal(body)
+        if err != nil {
+            return err
+        }
+        reader = bytes.NewReader(data)
+    }
+    req, err := http.NewRequestWithContext(ctx, method, base+path, reader)
+    if err != nil {
+        return err
+    }
+    req.Header.Set("Authorization", "Bearer "+c.Token)
+    req.Header.Set("Content-Type", "application/json")
+
+    resp, err := httpClient(c.HTTPClient).Do(req)
+    if err != nil {
+        return fmt.Errorf("cloudflare request failed: %v", err)
+    }
+    defer resp.Body.Close()
+
+    var envelope struct {
+        Success bool `json:"success"`
+        Errors  []struct {
+            Code    int    `json:"code"`
+            Message string `json:"message"`
+        } `json:"errors"`
+        Result json.RawMessage `json:"result"`
+    }
+    if err := json.NewDecoder(resp.Body).Decode(&envelope); err != nil {
+        return fmt.Errorf("failed to decode cloudflare response (HTTP %d): %v", resp.StatusCode, err)
+    }
+    if !envelope.Success {
+        msgs := make([]string, 0, len(envelope.Errors))
+        for _, e := range envelope.Errors {
+            msgs = append(msgs, fmt.Sprintf("%d: %s", e.Code, e.Message))
+        }
+        return fmt.Errorf("cloudflare API error (HTTP %d): %s", resp.StatusCode, strings.Join(msgs, "; "))
+    }
+    if result != nil {
+        return json.Unmarshal(envelope.Result, result)
+    }
+    return nil
+}
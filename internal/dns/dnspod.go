@@ -0,0 +1,192 @@
+package dns
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "net/url"
+    "strconv"
+    "strings"
+)
+
+const dnspodBaseURL = "https://dnsapi.cn/"
+
+// DNSPod 使用 DNSPod API（dnsapi.cn）與 "ID,Token" 形式的 login token 管理記錄
+type DNSPod struct {
+    LoginToken string
+    Zone       string
+    BaseURL    string
+    HTTPClient *http.Client
+
+    zones []string // 未配置 Zone 時快取帳號下的網域
+}
+
+// dnspodStatus 是每個回應都帶有的狀態，code 為 "1" 表示成功
+type dnspodStatus struct {
+    Code    string `json:"code"`
+    Message string `json:"message"`
+}
+
+func (d *DNSPod) FindRecords(ctx context.Context, name, typ string) ([]Record, error) {
+    zone, err := d.zoneFor(ctx, name)
+    if err != nil {
+        return nil, err
+    }
+    zone, rr, err := splitName(name, zone)
+    if err != nil {
+        return nil, err
+    }
+    var result struct {
+        Status  dnspodStatus `json:"status"`
+        Records []struct {
+            ID    string `json:"id"`
+            Name  string `json:"name"`
+            Type  string `json:"type"`
+            Value string `json:"value"`
+            TTL   string `json:"ttl"`
+        } `json:"records"`
+    }
+    params := url.Values{"domain": {zone}, "sub_domain": {rr}, "record_type": {typ}}
+    if err := d.call(ctx, "Record.List", params, &result, &result.Status); err != nil {
+        // 代碼 10 表示沒有記錄
+        if result.Status.Code == "10" {
+            return nil, nil
+        }
+        return nil, err
+    }
+    records := make([]Record, 0, len(result.Records))
+    for _, r := range result.Records {
+        ttl, _ := strconv.Atoi(r.TTL)
+        records = append(records, Record{ID: r.ID, Name: joinName(r.Name, zone), Type: r.Type, Value: r.Value, TTL: ttl})
+    }
+    return records, nil
+}
+
+func (d *DNSPod) CreateRecord(ctx context.Context, r Record) (Record, error) {
+    params, err := d.recordParams(ctx, r)
+    if err != nil {
+        return r, err
+    }
+    var result struct {
+        Status dnspodStatus `json:"status"`
+        Record struct {
+            ID string `json:"id"`
+        } `json:"record"`
+    }
+    if err := d.call(ctx, "Record.Create", params, &result, &result.Status); err != nil {
+        return r, err
+    }
+    r.ID = result.Record.ID
+    return r, nil
+}
+
+func (d *DNSPod) UpdateRecord(ctx context.Context, r Record) error {
+    params, err := d.recordParams(ctx, r)
+    if err != nil {
+        return err
+    }
+    params.Set("record_id", r.ID)
+    var result struct {
+        Status dnspodStatus `json:"status"`
+    }
+    return d.call(ctx, "Record.Modify", params, &result, &result.Status)
+}
+
+func (d *DNSPod) DeleteRecord(ctx context.Context, r Record) error {
+    zone, err := d.zoneFor(ctx, r.Name)
+    if err != nil {
+        return err
+    }
+    var result struct {
+        Status dnspodStatus `json:"status"`
+    }
+    return d.call(ctx, "Record.Remove", url.Values{"domain": {zone}, "record_id": {r.ID}}, &result, &result.Status)
+}
+
+func (d *DNSPod) recordParams(ctx context.Context, r Record) (url.Values, error) {
+    zone, err := d.zoneFor(ctx, r.Name)
+    if err != nil {
+        return nil, err
+    }
+    zone, rr, err := splitName(r.Name, zone)
+    if err != nil {
+        return nil, err
+    }
+    params := url.Values{
+        "domain":      {zone},
+        "sub_domain":  {rr},
+        "record_type": {r.Type},
+        "record_line": {"默认"},
+        "value":       {r.Value},
+    }
+    if r.TTL > 0 {
+        params.Set("ttl", strconv.Itoa(r.TTL))
+    }
+    return params, nil
+}
+
+// zoneFor 返回名稱所屬的網域：優先使用配置的 Zone，否則以 Domain.List 列出帳號下的網域並取最長的匹配
+func (d *DNSPod) zoneFor(ctx context.Context, name string) (string, error) {
+    if d.Zone != "" {
+        return d.Zone, nil
+    }
+    if d.zones == nil {
+        var zones []string
+        for offset := 0; ; {
+            var result struct {
+                Status dnspodStatus `json:"status"`
+                Info   struct {
+                    DomainTotal json.Number `json:"domain_total"`
+                } `json:"info"`
+                Domains []struct {
+                    Name string `json:"name"`
+                } `json:"domains"`
+            }
+            params := url.Values{"offset": {strconv.Itoa(offset)}, "length": {"100"}}
+            if err := d.call(ctx, "Domain.List", params, &result, &result.Status); err != nil {
+                return "", fmt.Errorf("failed to list dnspod domains: %v", err)
+            }
+            for _, dom := range result.Domains {
+                zones = append(zones, dom.Name)
+            }
+            offset += len(result.Domains)
+            total, _ := result.Info.DomainTotal.Int64()
+            if len(result.Domains) == 0 || int64(offset) >= total {
+                break
+            }
+        }
+        d.zones = zones
+    }
+    return longestZone(name, d.zones)
+}
+
+// call 以表單 POST 呼叫 API，並檢查回應中的 status
+func (d *DNSPod) call(ctx context.Context, action string, params url.Values, result interface{}, status *dnspodStatus) error {
+    params.Set("login_token", d.LoginToken)
+    params.Set("format", "json")
+
+    base := d.BaseURL
+    if base == "" {
+        base = dnspodBaseURL
+    }
+    req, err := http.NewRequestWithContext(ctx, http.MethodPost, base+action, strings.NewReader(params.Encode()))
+    if err != nil {
+        return err
+    }
+    req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+    req.Header.Set("User-Agent", "go-auto-proxy/1.0")
+
+    resp, err := httpClient(d.HTTPClient).Do(req)
+    if err != nil {
+        return fmt.Errorf("dnspod request failed: %v", err)
+    }
+    defer resp.Body.Close()
+    if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+        return fmt.Errorf("failed to decode dnspod response (HTTP %d): %v", resp.StatusCode, err)
+    }
+    if status.Code != "1" {
+        return fmt.Errorf("dnspod API error: %s: %s", status.Code, status.Message)
+    }
+    return nil
+}
@@ -0,0 +1,126 @@
+package dns
+
+import (
+    "context"
+    "fmt"
+    "go-auto-proxy/internal/secret"
+    "net/http"
+    "strings"
+    "time"
+)
+
+// Record 是一筆 DNS 記錄，Name 為完整網域名稱（不含結尾的點）
+type Record struct {
+    ID    string
+    Name  string
+    Type  string
+    Value string
+    TTL   int
+}
+
+// Provider 是 DNS 服務商 API 的抽象，供 DNS-01 驗證與動態 DNS 使用
+type Provider interface {
+    // FindRecords 查詢指定名稱與類型的記錄
+    FindRecords(ctx context.Context, name, typ string) ([]Record, error)
+    CreateRecord(ctx context.Context, r Record) (Record, error)
+    UpdateRecord(ctx context.Context, r Record) error
+    DeleteRecord(ctx context.Context, r Record) error
+}
+
+// Config 描述 DNS 服務商與其憑證
+type Config struct {
+    Provider        string `json:"provider"`          // cloudflare、alidns 或 dnspod
+    Zone            string `json:"zone"`              // 網域所屬的 zone，如 example.com；留空時從帳號的 zone 列表取最長的匹配
+    APIToken        secret.Secret `json:"api_token"`         // Cloudflare API token 或 DNSPod 的 "ID,Token"
+    AccessKeyID     string        `json:"access_key_id"`     // 阿里雲 AccessKey ID
+    AccessKeySecret secret.Secret `json:"access_key_secret"` // 阿里雲 AccessKey Secret
+}
+
+// New 依配置建立 Provider
+func New(cfg Config) (Provider, error) {
+    client := &http.Client{Timeout: 30 * time.Second}
+    switch cfg.Provider {
+    case "cloudflare":
+        if cfg.APIToken == "" {
+            return nil, fmt.Errorf("cloudflare requires an API token")
+        }
+        return &Cloudflare{Token: cfg.APIToken.Reveal(), Zone: cfg.Zone, HTTPClient: client}, nil
+    case "alidns":
+        if cfg.AccessKeyID == "" || cfg.AccessKeySecret == "" {
+            return nil, fmt.Errorf("alidns requires an access key ID and secret")
+        }
+        return &AliDNS{AccessKeyID: cfg.AccessKeyID, AccessKeySecret: cfg.AccessKeySecret.Reveal(), Zone: cfg.Zone, HTTPClient: client}, nil
+    case "dnspod":
+        if cfg.APIToken == "" {
+            return nil, fmt.Errorf("dnspod requires an API token in the form ID,Token")
+        }
+        return &DNSPod{LoginToken: cfg.APIToken.Reveal(), Zone: cfg.Zone, HTTPClient: client}, nil
+    case "":
+        return nil, fmt.Errorf("no DNS provider configured")
+    }
+    return nil, fmt.Errorf("unsupported DNS provider %q", cfg.Provider)
+}
+
+// splitName 將完整名稱拆成 zone 與子網域（RR），zone 為根時 RR 為 "@"
+func splitName(name, zone string) (string, string, error) {
+    name = strings.TrimSuffix(name, ".")
+    zone = strings.TrimSuffix(zone, ".")
+    if zone == "" {
+        return "", "", fmt.Errorf("cannot determine zone for %q, set dns.zone in config.json", name)
+    }
+    if name == zone {
+        return zone, "@", nil
+    }
+    if !strings.HasSuffix(name, "."+zone) {
+        return "", "", fmt.Errorf("%q is not within zone %q", name, zone)
+    }
+    return zone, strings.TrimSuffix(name, "."+zone), nil
+}
+
+// longestZone 從帳號下的 zone 中選出包含 name 的最長者；
+// 不能只看最後兩段，否則 proxy.example.co.uk 會被當成 co.uk
+func longestZone(name string, zones []string) (string, error) {
+    name = strings.TrimSuffix(name, ".")
+    best := ""
+    for _, zone := range zones {
+        zone = strings.TrimSuffix(zone, ".")
+        if (name == zone || strings.HasSuffix(name, "."+zone)) && len(zone) > len(best) {
+            best = zone
+        }
+    }
+    if best == "" {
+        return "", fmt.Errorf("no zone in the DNS account contains %q, check the domain or set dns.zone in config.json", name)
+    }
+    return best, nil
+}
+
+func httpClient(c *http.Client) *http.Client {
+    if c != nil {
+        return c
+    }
+    return http.DefaultClient
+}
+
+func joinName(rr, zone string) string {
+    if rr == "@" || rr == "" {
+        return zone
+    }
+    return rr + "." + zone
+}
+
+// AcmeSHHook 返回 acme.sh 對應的 DNS API hook 名稱與所需的環境變數
+func AcmeSHHook(cfg Config) (string, []string, error) {
+    switch cfg.Provider {
+    case "cloudflare":
+        return "dns_cf", []string{"CF_Token=" + cfg.APIToken.Reveal()}, nil
+    case "alidns":
+        return "dns_ali", []string{"Ali_Key=" + cfg.AccessKeyID, "Ali_Secret=" + cfg.AccessKeySecret.Reveal()}, nil
+    case "dnspod":
+        id, token, ok := strings.Cut(cfg.APIToken.Reveal(), ",")
+        if !ok {
+            return "", nil, fmt.Errorf("dnspod token must be in the form ID,Token")
+        }
+        return "dns_dp", []string{"DP_Id=" + id, "DP_Key=" + token}, nil
+    }
+    return "", nil, fmt.Errorf("unsupported DNS provider %q", cfg.Provider)
+}
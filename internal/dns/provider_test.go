@@ -0,0 +1,309 @@
+package dns
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "go-auto-proxy/internal/acme"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "sync"
+    "testing"
+
+    "github.com/stretchr/testify/assert"
+    "github.com/stretchr/testify/require"
+)
+
+// cloudflareStub 模擬 Cloudflare API v4 的 zone 與 dns_records 端點
+type cloudflareStub struct {
+    mu      sync.Mutex
+    nextID  int
+    records map[string]cloudflareRecord
+}
+
+func newCloudflareStub(t *testing.T) (*cloudflareStub, *httptest.Server) {
+    stub := &cloudflareStub{records: map[string]cloudflareRecord{}}
+    srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        if r.Header.Get("Authorization") != "Bearer test-token" {
+            w.WriteHeader(http.StatusForbidden)
+            json.NewEncoder(w).Encode(map[string]interface{}{
+                "success": false,
+                "errors":  []map[string]interface{}{{"code": 10000, "message": "Authentication error"}},
+            })
+            return
+        }
+        stub.mu.Lock()
+        defer stub.mu.Unlock()
+
+        var result interface{}
+        switch {
+        case r.URL.Path == "/zones":
+            zones := []map[string]string{}
+            if r.URL.Query().Get("name") == "example.com" {
+                zones = append(zones, map[string]string{"id": "zone1"})
+            }
+            result = zones
+        case r.URL.Path == "/zones/zone1/dns_records" && r.Method == http.MethodGet:
+            list := []cloudflareRecord{}
+            for _, rec := range stub.records {
+                if rec.Name == r.URL.Query().Get("name") && rec.Type == r.URL.Query().Get("type") {
+                    list = append(list, rec)
+                }
+            }
+            result = list
+        case r.URL.Path == "/zones/zone1/dns_records" && r.Method == http.MethodPost:
+            var rec cloudflareRecord
+            json.NewDecoder(r.Body).Decode(&rec)
+            stub.nextID++
+            rec.ID = fmt.Sprintf("rec%d", stub.nextID)
+            stub.records[rec.ID] = rec
+            result = rec
+        case strings.HasPrefix(r.URL.Path, "/zones/zone1/dns_records/"):
+            id := strings.TrimPrefix(r.URL.Path, "/zones/zone1/dns_records/")
+            if _, ok := stub.records[id]; !ok {
+                http.NotFound(w, r)
+                return
+            }
+            if r.Method == http.MethodDelete {
+                delete(stub.records, id)
+            } else {
+                var rec cloudflareRecord
+                json.NewDecoder(r.Body).Decode(&rec)
+                rec.ID = id
+                stub.records[id] = rec
+            }
+            result = map[string]string{"id": id}
+        default:
+            http.NotFound(w, r)
+            return
+        }
+        json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "errors": []interface{}{}, "result": result})
+    }))
+    t.Cleanup(srv.Close)
+    return stub, srv
+}
+
+func TestCloudflareRecords(t *testing.T) {
+    stub, srv := newCloudflareStub(t)
+    cf := &Cloudflare{Token: "test-token", BaseURL: srv.URL}
+    ctx := context.Background()
+
+    created, err := cf.CreateRecord(ctx, Record{Name: "proxy.example.com", Type: "A", Value: "35.185.174.224"})
+    require.NoError(t, err)
+    assert.NotEmpty(t, created.ID)
+
+    records, err := cf.FindRecords(ctx, "proxy.example.com", "A")
+    require.NoError(t, err)
+    require.Len(t, records, 1)
+    assert.Equal(t, "35.185.174.224", records[0].Value)
+
+    records[0].Value = "35.185.174.225"
+    require.NoError(t, cf.UpdateRecord(ctx, records[0]))
+    assert.Equal(t, "35.185.174.225", stub.records[created.ID].Content)
+
+    require.NoError(t, cf.DeleteRecord(ctx, records[0]))
+    assert.Empty(t, stub.records)
+}
+
+func TestCloudflareAuthError(t *testing.T) {
+    _, srv := newCloudflareStub(t)
+    cf := &Cloudflare{Token: "wrong", BaseURL: srv.URL}
+    _, err := cf.FindRecords(context.Background(), "proxy.example.com", "A")
+    assert.Error(t, err)
+    assert.Contains(t, err.Error(), "Authentication error")
+}
+
+func TestChallengeSolver(t *testing.T) {
+    stub, srv := newCloudflareStub(t)
+    solver := &ChallengeSolver{Provider: &Cloudflare{Token: "test-token", Zone: "example.com", BaseURL: srv.URL}}
+    ctx := context.Background()
+
+    require.NoError(t, solver.Present(ctx, "proxy.example.com", "token", "token.thumb"))
+    require.Len(t, stub.records, 1)
+    for _, rec := range stub.records {
+        assert.Equal(t, "_acme-challenge.proxy.example.com", rec.Name)
+        assert.Equal(t, "TXT", rec.Type)
+        assert.Equal(t, acme.DNS01Value("token.thumb"), rec.Content)
+    }
+
+    require.NoError(t, solver.CleanUp(ctx, "proxy.example.com", "token", "token.thumb"))
+    assert.Empty(t, stub.records, "challenge record should be removed")
+}
+
+func TestAliDNS(t *testing.T) {
+    var actions []string
+    srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        q := r.URL.Query()
+        if q.Get("Signature") != aliSignature(http.MethodGet, q, "secret") {
+            w.WriteHeader(http.StatusBadRequest)
+            w.Write([]byte(`{"Code":"SignatureDoesNotMatch","Message":"bad signature"}`))
+            return
+        }
+        actions = append(actions, q.Get("Action"))
+        switch q.Get("Action") {
+        case "AddDomainRecord":
+            assert.Equal(t, "example.com", q.Get("DomainName"))
+            assert.Equal(t, "_acme-challenge.proxy", q.Get("RR"))
+            w.Write([]byte(`{"RecordId":"9999"}`))
+        case "DescribeSubDomainRecords":
+            w.Write([]byte(`{"DomainRecords":{"Record":[{"RecordId":"9999","DomainName":"example.com","RR":"_acme-challenge.proxy","Type":"TXT","Value":"v","TTL":600}]}}`))
+        default:
+            w.Write([]byte(`{}`))
+        }
+    }))
+    defer srv.Close()
+
+    ali := &AliDNS{AccessKeyID: "id", AccessKeySecret: "secret", Zone: "example.com", BaseURL: srv.URL + "/"}
+    ctx := context.Background()
+    rec, err := ali.CreateRecord(ctx, Record{Name: "_acme-challenge.proxy.example.com", Type: "TXT", Value: "v"})
+    require.NoError(t, err)
+    assert.Equal(t, "9999", rec.ID)
+
+    records, err := ali.FindRecords(ctx, "_acme-challenge.proxy.example.com", "TXT")
+    require.NoError(t, err)
+    require.Len(t, records, 1)
+    assert.Equal(t, "_acme-challenge.proxy.example.com", records[0].Name)
+
+    require.NoError(t, ali.DeleteRecord(ctx, records[0]))
+    assert.Equal(t, []string{"AddDomainRecord", "DescribeSubDomainRecords", "DeleteDomainRecord"}, actions)
+
+    bad := &AliDNS{AccessKeyID: "id", AccessKeySecret: "wrong", Zone: "example.com", BaseURL: srv.URL + "/"}
+    _, err = bad.FindRecords(ctx, "proxy.example.com", "A")
+    assert.ErrorContains(t, err, "SignatureDoesNotMatch")
+}
+
+func TestDNSPod(t *testing.T) {
+    srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        r.ParseForm()
+        assert.Equal(t, "123,abc", r.PostForm.Get("login_token"))
+        switch r.URL.Path {
+        case "/Record.List":
+            if r.PostForm.Get("sub_domain") == "missing" {
+                w.Write([]byte(`{"status":{"code":"10","message":"No records"}}`))
+                return
+            }
+            w.Write([]byte(`{"status":{"code":"1","message":"ok"},"records":[{"id":"42","name":"proxy","type":"A","value":"1.2.3.4","ttl":"600"}]}`))
+        case "/Record.Modify":
+            assert.Equal(t, "42", r.PostForm.Get("record_id"))
+            assert.Equal(t, "默认", r.PostForm.Get("record_line"))
+            w.Write([]byte(`{"status":{"code":"1","message":"ok"}}`))
+        default:
+            w.Write([]byte(`{"status":{"code":"-1","message":"unknown action"}}`))
+        }
+    }))
+    defer srv.Close()
+
+    dp := &DNSPod{LoginToken: "123,abc", Zone: "example.com", BaseURL: srv.URL + "/"}
+    ctx := context.Background()
+    records, err := dp.FindRecords(ctx, "proxy.example.com", "A")
+    require.NoError(t, err)
+    require.Len(t, records, 1)
+    assert.Equal(t, Record{ID: "42", Name: "proxy.example.com", Type: "A", Value: "1.2.3.4", TTL: 600}, records[0])
+
+    records[0].Value = "5.6.7.8"
+    assert.NoError(t, dp.UpdateRecord(ctx, records[0]))
+
+    records, err = dp.FindRecords(ctx, "missing.example.com", "A")
+    assert.NoError(t, err, "no records is not an error")
+    assert.Empty(t, records)
+
+    err = dp.DeleteRecord(ctx, Record{ID: "42", Name: "proxy.example.com"})
+    assert.ErrorContains(t, err, "unknown action")
+}
+
+func TestSplitName(t *testing.T) {
+    zone, rr, err := splitName("_acme-challenge.proxy.example.com", "example.com")
+    assert.NoError(t, err)
+    assert.Equal(t, "example.com", zone)
+    assert.Equal(t, "_acme-challenge.proxy", rr)
+
+    _, _, err = splitName("proxy.example.co.uk", "")
+    assert.Error(t, err, "the zone is never guessed from the last labels")
+
+    _, rr, err = splitName("example.co.uk", "example.co.uk")
+    assert.NoError(t, err)
+    assert.Equal(t, "@", rr)
+
+    _, _, err = splitName("proxy.other.com", "example.com")
+    assert.Error(t, err)
+}
+
+func TestLongestZone(t *testing.T) {
+    zones := []string{"co.uk", "example.co.uk", "example.com"}
+    zone, err := longestZone("proxy.example.co.uk", zones)
+    assert.NoError(t, err)
+    assert.Equal(t, "example.co.uk", zone)
+
+    zone, err = longestZone("example.com.", zones)
+    assert.NoError(t, err)
+    assert.Equal(t, "example.com", zone)
+
+    _, err = longestZone("proxy.notexample.com", zones)
+    assert.Error(t, err)
+}
+
+func TestZoneFromDomainList(t *testing.T) {
+    var aliPages []string
+    ali := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        q := r.URL.Query()
+        switch q.Get("Action") {
+        case "DescribeDomains":
+            aliPages = append(aliPages, q.Get("PageNumber"))
+            if q.Get("PageNumber") == "1" {
+                w.Write([]byte(`{"TotalCount":2,"Domains":{"Domain":[{"DomainName":"co.uk"}]}}`))
+            } else {
+                w.Write([]byte(`{"TotalCount":2,"Domains":{"Domain":[{"DomainName":"example.co.uk"}]}}`))
+            }
+        case "AddDomainRecord":
+            assert.Equal(t, "example.co.uk", q.Get("DomainName"))
+            assert.Equal(t, "proxy", q.Get("RR"))
+            w.Write([]byte(`{"RecordId":"1"}`))
+        default:
+            w.Write([]byte(`{}`))
+        }
+    }))
+    defer ali.Close()
+
+    a := &AliDNS{AccessKeyID: "id", AccessKeySecret: "secret", BaseURL: ali.URL + "/"}
+    ctx := context.Background()
+    _, err := a.CreateRecord(ctx, Record{Name: "proxy.example.co.uk", Type: "A", Value: "1.2.3.4"})
+    require.NoError(t, err)
+    require.NoError(t, a.UpdateRecord(ctx, Record{ID: "1", Name: "proxy.example.co.uk", Type: "A", Value: "1.2.3.5"}))
+    assert.Equal(t, []string{"1", "2"}, aliPages, "the domain list is fetched once and cached")
+
+    dnspod := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        r.ParseForm()
+        switch r.URL.Path {
+        case "/Domain.List":
+            w.Write([]byte(`{"status":{"code":"1","message":"ok"},"info":{"domain_total":2},"domains":[{"name":"co.uk"},{"name":"example.co.uk"}]}`))
+        case "/Record.List":
+            assert.Equal(t, "example.co.uk", r.PostForm.Get("domain"))
+            assert.Equal(t, "proxy", r.PostForm.Get("sub_domain"))
+            w.Write([]byte(`{"status":{"code":"10","message":"No records"}}`))
+        default:
+            w.Write([]byte(`{"status":{"code":"-1","message":"unknown action"}}`))
+        }
+    }))
+    defer dnspod.Close()
+
+    dp := &DNSPod{LoginToken: "123,abc", BaseURL: dnspod.URL + "/"}
+    records, err := dp.FindRecords(ctx, "proxy.example.co.uk", "A")
+    require.NoError(t, err)
+    assert.Empty(t, records)
+}
+
+func TestAcmeSHHook(t *testing.T) {
+    hook, env, err := AcmeSHHook(Config{Provider: "dnspod", APIToken: "123,abc"})
+    assert.NoError(t, err)
+    assert.Equal(t, "dns_dp", hook)
+    assert.Equal(t, []string{"DP_Id=123", "DP_Key=abc"}, env)
+
+    hook, env, err = AcmeSHHook(Config{Provider: "cloudflare", APIToken: "tok"})
+    assert.NoError(t, err)
+    assert.Equal(t, "dns_cf", hook)
+    assert.Equal(t, []string{"CF_Token=tok"}, env)
+
+    _, _, err = AcmeSHHook(Config{Provider: "route53"})
+    assert.Error(t, err)
+}
@@ -0,0 +1,81 @@
+package dns
+
+import (
+    "context"
+    "fmt"
+    "go-auto-proxy/internal/acme"
+    "log"
+    "net"
+    "strings"
+    "time"
+)
+
+// ChallengeSolver 透過 Provider 建立 _acme-challenge TXT 記錄完成 DNS-01 驗證
+type ChallengeSolver struct {
+    Provider Provider
+    // PropagationTimeout 等待記錄可被解析的最長時間，0 表示不等待
+    PropagationTimeout time.Duration
+    Resolver           *net.Resolver
+}
+
+// challengeName 返回網域對應的驗證記錄名稱，萬用字元網域使用其父網域
+func challengeName(domain string) string {
+    return "_acme-challenge." + strings.TrimPrefix(domain, "*.")
+}
+
+func (s *ChallengeSolver) Present(ctx context.Context, domain, token, keyAuth string) error {
+    name, value := challengeName(domain), acme.DNS01Value(keyAuth)
+    if _, err := s.Provider.CreateRecord(ctx, Record{Name: name, Type: "TXT", Value: value, TTL: 120}); err != nil {
+        return fmt.Errorf("failed to create TXT record %s: %v", name, err)
+    }
+    log.Printf("Created TXT record %s", name)
+    return s.waitPropagation(ctx, name, value)
+}
+
+func (s *ChallengeSolver) CleanUp(ctx context.Context, domain, token, keyAuth string) error {
+    name, value := challengeName(domain), acme.DNS01Value(keyAuth)
+    records, err := s.Provider.FindRecords(ctx, name, "TXT")
+    if err != nil {
+        return err
+    }
+    for _, r := range records {
+        if strings.Trim(r.Value, `"`) != value {
+            continue
+        }
+        if err := s.Provider.DeleteRecord(ctx, r); err != nil {
+            return fmt.Errorf("failed to delete TXT record %s: %v", name, err)
+        }
+        log.Printf("Deleted TXT record %s", name)
+    }
+    return nil
+}
+
+// waitPropagation 輪詢直到 TXT 記錄可被解析或超時；超時僅記錄警告，交由 ACME 伺服器判斷
+func (s *ChallengeSolver) waitPropagation(ctx context.Context, name, value string) error {
+    if s.PropagationTimeout <= 0 {
+        return nil
+    }
+    resolver := s.Resolver
+    if resolver == nil {
+        resolver = net.DefaultResolver
+    }
+    deadline := time.Now().Add(s.PropagationTimeout)
+    for {
+        txts, _ := resolver.LookupTXT(ctx, name)
+        for _, txt := range txts {
+            if txt == value {
+                log.Printf("TXT record %s is visible.", name)
+                return nil
+            }
+        }
+        if time.Now().After(deadline) {
+            log.Printf("Warning: TXT record %s not visible after %v, continuing anyway.", name, s.PropagationTimeout)
+            return nil
+        }
+        select {
+        case <-ctx.Done():
+            return ctx.Err()
+        case <-time.After(5 * time.Second):
+        }
+    }
+}
@@ -0,0 +1,277 @@
+package doctor
+
+import (
+    "bufio"
+    "bytes"
+    "context"
+    "fmt"
+    "go-auto-proxy/internal/cert"
+    "go-auto-proxy/internal/sudo"
+    "go-auto-proxy/internal/system"
+    "net"
+    "net/http"
+    "os"
+    "path/filepath"
+    "strconv"
+    "strings"
+    "syscall"
+    "time"
+)
+
+var (
+    // ProcDir 可在測試中替換
+    ProcDir = "/proc"
+    // LookupHost 解析網域，可在測試中替換
+    LookupHost = net.DefaultResolver.LookupHost
+)
+
+// PortCheck 檢查端口是否空閒，或已被 owners 中的程式占用
+func PortCheck(port int, owners ...string) Check {
+    return Check{Name: fmt.Sprintf("port %d", port), Run: func(ctx context.Context) Result {
+        listeners, err := system.PortListeners(port)
+        if err != nil {
+            return warn("", "cannot inspect listening sockets: %v", err)
+        }
+        if len(listeners) == 0 {
+            return pass("port %d is free", port)
+        }
+        var others []string
+        for _, l := range listeners {
+            owned := false
+            for _, owner := range owners {
+                if l.Process == owner {
+                    owned = true
+                }
+            }
+            switch {
+            case owned:
+                return pass("port %d is served by %s", port, l.Process)
+            case l.PID == 0:
+                others = append(others, "an unknown process")
+            default:
+                others = append(others, fmt.Sprintf("%s (pid %d)", l.Process, l.PID))
+            }
+        }
+        return fail(fmt.Sprintf("stop the conflicting service, run doctor as root to identify it, or move %s with 'go-auto-proxy trojan port auto'", strings.Join(owners, "/")),
+            "port %d is held by %s", port, strings.Join(others, ", "))
+    }}
+}
+
+// DomainCheck 檢查網域是否解析到對外 IP
+func DomainCheck(domain, externalIP string) Check {
+    return Check{Name: "domain resolves", Run: func(ctx context.Context) Result {
+        if domain == "" {
+            return warn("pass --domain to init or set acme_sh.domain in config.json", "no domain configured")
+        }
+        addrs, err := LookupHost(ctx, domain)
+        if err != nil {
+            return fail("create an A/AAAA record, or run 'go-auto-proxy ddns run'", "%s does not resolve: %v", domain, err)
+        }
+        for _, addr := range addrs {
+            if addr == externalIP {
+                return pass("%s resolves to %s", domain, externalIP)
+            }
+        }
+        return fail("update the DNS record, or run 'go-auto-proxy ddns run'", "%s resolves to %s, not the external IP %s", domain, strings.Join(addrs, ", "), externalIP)
+    }}
+}
+
+// ClockCheck 比較本機時間與 url 回應的 Date 標頭，時間偏差會導致 TLS 與 ACME 失敗
+func ClockCheck(url string) Check {
+    return Check{Name: "clock skew", Run: func(ctx context.Context) Result {
+        ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+        defer cancel()
+        req, err := http.NewRequestWithContext(ctx, http.MethodHead, url, nil)
+        if err != nil {
+            return warn("", "cannot build request: %v", err)
+        }
+        resp, err := http.DefaultClient.Do(req)
+        if err != nil {
+            return warn("check network connectivity", "cannot reach %s to compare clocks: %v", url, err)
+        }
+        resp.Body.Close()
+        remote, err := http.ParseTime(resp.Header.Get("Date"))
+        if err != nil {
+            return warn("", "%s returned no usable Date header", url)
+        }
+        skew := time.Since(remote).Round(time.Second)
+        if skew < 0 {
+            skew = -skew
+        }
+        hint := "enable time sync with 'sudo timedatectl set-ntp true'"
+        switch {
+        case skew > time.Minute:
+            return fail(hint, "clock is off by %s", skew)
+        case skew > 5*time.Second:
+            return warn(hint, "clock is off by %s", skew)
+        }
+        return pass("clock is within %s of %s", skew, url)
+    }}
+}
+
+// DiskCheck 檢查 path 所在檔案系統的可用空間
+func DiskCheck(path string, warnBelow, failBelow uint64) Check {
+    return Check{Name: "disk space", Run: func(ctx context.Context) Result {
+        var st syscall.Statfs_t
+        if err := syscall.Statfs(path, &st); err != nil {
+            return warn("", "cannot stat %s: %v", path, err)
+        }
+        free := st.Bavail * uint64(st.Bsize)
+        hint := "free up disk space (e.g. 'sudo apt clean', 'sudo journalctl --vacuum-size=100M')"
+        switch {
+        case free < failBelow:
+            return fail(hint, "only %s free on %s", humanBytes(free), path)
+        case free < warnBelow:
+            return warn(hint, "only %s free on %s", humanBytes(free), path)
+        }
+        return pass("%s free on %s", humanBytes(free), path)
+    }}
+}
+
+// MemoryCheck 讀取 /proc/meminfo 的 MemAvailable
+func MemoryCheck(warnBelow, failBelow uint64) Check {
+    return Check{Name: "memory", Run: func(ctx context.Context) Result {
+        data, err := os.ReadFile(filepath.Join(ProcDir, "meminfo"))
+        if err != nil {
+            return warn("", "cannot read meminfo: %v", err)
+        }
+        var available uint64
+        scanner := bufio.NewScanner(bytes.NewReader(data))
+        for scanner.Scan() {
+            fields := strings.Fields(scanner.Text())
+            if len(fields) >= 2 && fields[0] == "MemAvailable:" {
+                kb, _ := strconv.ParseUint(fields[1], 10, 64)
+                available = kb * 1024
+            }
+        }
+        hint := "add swap or use a larger instance"
+        switch {
+        case available < failBelow:
+            return fail(hint, "only %s memory available", humanBytes(available))
+        case available < warnBelow:
+            return warn(hint, "only %s memory available", humanBytes(available))
+        }
+        return pass("%s memory available", humanBytes(available))
+    }}
+}
+
+// BBRCheck 檢查核心是否支援並啟用 BBR 擁塞控制
+func BBRCheck() Check {
+    return Check{Name: "tcp bbr", Run: func(ctx context.Context) Result {
+        available, err := os.ReadFile(filepath.Join(ProcDir, "sys/net/ipv4/tcp_available_congestion_control"))
+        if err != nil {
+            return warn("", "cannot read congestion control settings: %v", err)
+        }
+        current, _ := os.ReadFile(filepath.Join(ProcDir, "sys/net/ipv4/tcp_congestion_control"))
+        algo := strings.TrimSpace(string(current))
+        switch {
+        case algo == "bbr":
+            return pass("bbr is enabled")
+        case strings.Contains(" "+string(available)+" ", " bbr "):
+            return warn("run 'go-auto-proxy tune apply'", "bbr is available but %s is in use", algo)
+        }
+        return warn("load it with 'sudo modprobe tcp_bbr' (kernel 4.9+)", "bbr is not loaded, %s is in use", algo)
+    }}
+}
+
+// SudoCheck 檢查是否為 root 或具備免密碼 sudo
+func SudoCheck() Check {
+    return Check{Name: "sudo", Run: func(ctx context.Context) Result {
+        if os.Geteuid() == 0 {
+            return pass("running as root")
+        }
+        if err := sudo.DefaultCommand("sudo", "-n", "true").Run(); err != nil {
+            return fail("add 'NOPASSWD: ALL' for this user with 'sudo visudo', or run as root", "passwordless sudo is not available")
+        }
+        return pass("passwordless sudo is available")
+    }}
+}
+
+// ReachabilityCheck 檢查能否以 TCP 連線到下載所需的主機
+func ReachabilityCheck(hosts ...string) Check {
+    return Check{Name: "download hosts", Run: func(ctx context.Context) Result {
+        var failed []string
+        for _, host := range hosts {
+            addr := host
+            if _, _, err := net.SplitHostPort(host); err != nil {
+                addr = net.JoinHostPort(host, "443")
+            }
+            dialer := &net.Dialer{Timeout: 5 * time.Second}
+            conn, err := dialer.DialContext(ctx, "tcp", addr)
+            if err != nil {
+                failed = append(failed, host)
+                continue
+            }
+            conn.Close()
+        }
+        if len(failed) == len(hosts) {
+            return fail("check outbound firewall rules, DNS and proxy settings", "none of %s are reachable", strings.Join(hosts, ", "))
+        }
+        if len(failed) > 0 {
+            return warn("installation steps using these hosts will fail", "unreachable: %s", strings.Join(failed, ", "))
+        }
+        return pass("%d hosts reachable", len(hosts))
+    }}
+}
+
+// CertCheck 檢查憑證是否存在、與私鑰匹配且未即將到期
+func CertCheck(certPath, keyPath string, warnWithin time.Duration) Check {
+    return Check{Name: "certificate", Run: func(ctx context.Context) Result {
+        if certPath == "" {
+            return warn("run 'go-auto-proxy cert issue' or 'go-auto-proxy cert selfsign'", "no certificate configured")
+        }
+        status, err := cert.Inspect(certPath, keyPath)
+        if err != nil {
+            return fail("run 'go-auto-proxy cert issue'", "cannot read certificate: %v", err)
+        }
+        days := int(time.Until(status.NotAfter).Hours() / 24)
+        switch {
+        case time.Now().After(status.NotAfter):
+            return fail("run 'go-auto-proxy cert renew'", "certificate expired on %s", status.NotAfter.Format("2006-01-02"))
+        case keyPath != "" && !status.KeyMatches:
+            return fail("re-issue the certificate with 'go-auto-proxy cert issue --force'", "private key does not match the certificate")
+        case status.ExpiresWithin(warnWithin):
+            return warn("run 'go-auto-proxy cert renew' or 'go-auto-proxy cert install-timer'", "certificate expires in %d days", days)
+        }
+        return pass("certificate valid for %d more days", days)
+    }}
+}
+
+// ServiceCheck 以 systemctl is-active 檢查服務狀態；尚未安裝的 unit（如 init 之前，
+// 或尚未簽發憑證而沒有 trojan-go.service）只發出警告，已安裝但未執行的才算失敗
+func ServiceCheck(units ...string) Check {
+    return Check{Name: "services", Run: func(ctx context.Context) Result {
+        var inactive, missing, active []string
+        for _, unit := range units {
+            out, _ := sudo.DefaultCommand("systemctl", "show", "-p", "LoadState", "--value", unit).Output()
+            if strings.TrimSpace(string(out)) != "loaded" {
+                missing = append(missing, unit)
+                continue
+            }
+            out, _ = sudo.DefaultCommand("systemctl", "is-active", unit).Output()
+            if state := strings.TrimSpace(string(out)); state != "active" {
+                if state == "" {
+                    state = "unknown"
+                }
+                inactive = append(inactive, fmt.Sprintf("%s (%s)", unit, state))
+                continue
+            }
+            active = append(active, unit)
+        }
+        if len(inactive) > 0 {
+            return fail("inspect with 'journalctl -u <unit>' and start with 'sudo systemctl enable --now <unit>'", "not running: %s", strings.Join(inactive, ", "))
+        }
+        if len(missing) > 0 {
+            return warn("run 'go-auto-proxy init'; trojan-go.service is installed with the first certificate or by 'go-auto-proxy trojan install-service'", "not installed: %s", strings.Join(missing, ", "))
+        }
+        return pass("%s active", strings.Join(active, ", "))
+    }}
+}
+
+// humanBytes 以 MiB/GiB 顯示容量
+func humanBytes(n uint64) string {
+    if n >= 1<<30 {
+        return fmt.Sprintf("%.1f GiB", float64(n)/(1<<30))
+    }
+    return fmt.Sprintf("%d MiB", n>>20)
+}
@@ -0,0 +1,68 @@
+package doctor
+
+import (
+    "context"
+    "fmt"
+    "sync"
+)
+
+// 檢查結果的狀態
+const (
+    Pass = "pass"
+    Warn = "warn"
+    Fail = "fail"
+)
+
+// Result 是單項檢查的結果；Hint 為修復建議
+type Result struct {
+    Name    string `json:"name"`
+    Status  string `json:"status"`
+    Message string `json:"message"`
+    Hint    string `json:"hint,omitempty"`
+}
+
+// Check 是一項可執行的檢查
+type Check struct {
+    Name string
+    Run  func(ctx context.Context) Result
+}
+
+// Run 並行執行所有檢查，結果順序與 checks 相同
+func Run(ctx context.Context, checks []Check) []Result {
+    results := make([]Result, len(checks))
+    var wg sync.WaitGroup
+    for i, check := range checks {
+        wg.Add(1)
+        go func(i int, check Check) {
+            defer wg.Done()
+            result := check.Run(ctx)
+            result.Name = check.Name
+            results[i] = result
+        }(i, check)
+    }
+    wg.Wait()
+    return results
+}
+
+// Count 統計指定狀態的結果數量
+func Count(results []Result, status string) int {
+    n := 0
+    for _, r := range results {
+        if r.Status == status {
+            n++
+        }
+    }
+    return n
+}
+
+func pass(format string, args ...interface{}) Result {
+    return Result{Status: Pass, Message: fmt.Sprintf(format, args...)}
+}
+
+func warn(hint, format string, args ...interface{}) Result {
+    return Result{Status: Warn, Message: fmt.Sprintf(format, args...), Hint: hint}
+}
+
+func fail(hint, format string, args ...interface{}) Result {
+    return Result{Status: Fail, Message: fmt.Sprintf(format, args...), Hint: hint}
+}
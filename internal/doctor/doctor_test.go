@@ -0,0 +1,150 @@
+package doctor
+
+import (
+    "context"
+    "errors"
+    "go-auto-proxy/internal/cert"
+    "go-auto-proxy/internal/sudo"
+    "net"
+    "net/http"
+    "net/http/httptest"
+    "os"
+    "os/exec"
+    "path/filepath"
+    "strings"
+    "testing"
+    "time"
+
+    "github.com/stretchr/testify/assert"
+    "github.com/stretchr/testify/require"
+)
+
+func TestRunKeepsOrder(t *testing.T) {
+    checks := []Check{
+        {Name: "slow", Run: func(ctx context.Context) Result { time.Sleep(20 * time.Millisecond); return pass("ok") }},
+        {Name: "fast", Run: func(ctx context.Context) Result { return fail("fix it", "broken") }},
+    }
+    results := Run(context.Background(), checks)
+    require.Len(t, results, 2)
+    assert.Equal(t, "slow", results[0].Name)
+    assert.Equal(t, Fail, results[1].Status)
+    assert.Equal(t, "fix it", results[1].Hint)
+    assert.Equal(t, 1, Count(results, Fail))
+}
+
+func TestDomainCheck(t *testing.T) {
+    original := LookupHost
+    defer func() { LookupHost = original }()
+    LookupHost = func(ctx context.Context, host string) ([]string, error) {
+        if host == "missing.example.com" {
+            return nil, errors.New("no such host")
+        }
+        return []string{"35.185.174.224"}, nil
+    }
+    ctx := context.Background()
+
+    assert.Equal(t, Pass, DomainCheck("proxy.example.com", "35.185.174.224").Run(ctx).Status)
+    assert.Equal(t, Fail, DomainCheck("proxy.example.com", "203.0.113.9").Run(ctx).Status)
+    assert.Equal(t, Fail, DomainCheck("missing.example.com", "203.0.113.9").Run(ctx).Status)
+    assert.Equal(t, Warn, DomainCheck("", "203.0.113.9").Run(ctx).Status)
+}
+
+func TestPortCheck(t *testing.T) {
+    ln, err := net.Listen("tcp", ":0")
+    require.NoError(t, err)
+    defer ln.Close()
+    port := ln.Addr().(*net.TCPAddr).Port
+    self, err := os.ReadFile("/proc/self/comm")
+    require.NoError(t, err)
+    ctx := context.Background()
+
+    assert.Equal(t, Pass, PortCheck(port, strings.TrimSpace(string(self))).Run(ctx).Status, "the expected owner may hold the port")
+    result := PortCheck(port, "nginx").Run(ctx)
+    assert.Equal(t, Fail, result.Status)
+    assert.Contains(t, result.Message, strings.TrimSpace(string(self)))
+
+    ln.Close()
+    assert.Equal(t, Pass, PortCheck(port, "nginx").Run(ctx).Status)
+}
+
+func TestClockCheck(t *testing.T) {
+    skew := time.Duration(0)
+    srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        w.Header().Set("Date", time.Now().Add(skew).UTC().Format(http.TimeFormat))
+    }))
+    defer srv.Close()
+
+    assert.Equal(t, Pass, ClockCheck(srv.URL).Run(context.Background()).Status)
+    skew = 10 * time.Minute
+    assert.Equal(t, Fail, ClockCheck(srv.URL).Run(context.Background()).Status)
+}
+
+func TestProcChecks(t *testing.T) {
+    dir := t.TempDir()
+    original := ProcDir
+    ProcDir = dir
+    defer func() { ProcDir = original }()
+    ctx := context.Background()
+
+    require.NoError(t, os.WriteFile(filepath.Join(dir, "meminfo"), []byte("MemTotal:  1014564 kB\nMemAvailable:  102400 kB\n"), 0644))
+    result := MemoryCheck(256<<20, 64<<20).Run(ctx)
+    assert.Equal(t, Warn, result.Status)
+    assert.Contains(t, result.Message, "100 MiB")
+
+    sysctl := filepath.Join(dir, "sys", "net", "ipv4")
+    require.NoError(t, os.MkdirAll(sysctl, 0755))
+    require.NoError(t, os.WriteFile(filepath.Join(sysctl, "tcp_available_congestion_control"), []byte("reno cubic bbr\n"), 0644))
+    require.NoError(t, os.WriteFile(filepath.Join(sysctl, "tcp_congestion_control"), []byte("cubic\n"), 0644))
+    assert.Equal(t, Warn, BBRCheck().Run(ctx).Status)
+    require.NoError(t, os.WriteFile(filepath.Join(sysctl, "tcp_congestion_control"), []byte("bbr\n"), 0644))
+    assert.Equal(t, Pass, BBRCheck().Run(ctx).Status)
+
+    assert.Equal(t, Pass, DiskCheck(dir, 0, 0).Run(ctx).Status)
+    assert.Equal(t, Fail, DiskCheck(dir, 1<<62, 1<<62).Run(ctx).Status)
+}
+
+func TestCertCheck(t *testing.T) {
+    dir := t.TempDir()
+    ca, err := cert.LoadOrCreateCA(dir, "Test CA")
+    require.NoError(t, err)
+    certPEM, keyPEM, err := ca.IssueServerCert([]string{"proxy.example.com"}, nil)
+    require.NoError(t, err)
+    certPath, keyPath := filepath.Join(dir, "server.crt"), filepath.Join(dir, "server.key")
+    require.NoError(t, os.WriteFile(certPath, certPEM, 0644))
+    require.NoError(t, os.WriteFile(keyPath, keyPEM, 0600))
+    ctx := context.Background()
+
+    assert.Equal(t, Pass, CertCheck(certPath, keyPath, 14*24*time.Hour).Run(ctx).Status)
+    assert.Equal(t, Warn, CertCheck(certPath, keyPath, 100*365*24*time.Hour).Run(ctx).Status)
+    assert.Equal(t, Warn, CertCheck("", "", time.Hour).Run(ctx).Status)
+    assert.Equal(t, Fail, CertCheck(filepath.Join(dir, "missing.crt"), "", time.Hour).Run(ctx).Status)
+    assert.Equal(t, Fail, CertCheck(certPath, filepath.Join(dir, "ca.key"), time.Hour).Run(ctx).Status, "mismatched key")
+}
+
+func TestServiceCheck(t *testing.T) {
+    original := sudo.DefaultCommand
+    defer func() { sudo.DefaultCommand = original }()
+    sudo.DefaultCommand = func(name string, args ...string) *exec.Cmd {
+        unit := args[len(args)-1]
+        if args[0] == "show" {
+            if unit == "trojan-go" {
+                return exec.Command("echo", "not-found")
+            }
+            return exec.Command("echo", "loaded")
+        }
+        if unit == "nginx" {
+            return exec.Command("echo", "inactive")
+        }
+        return exec.Command("echo", "active")
+    }
+    ctx := context.Background()
+
+    result := ServiceCheck("trojan-go", "nginx").Run(ctx)
+    assert.Equal(t, Fail, result.Status)
+    assert.Contains(t, result.Message, "nginx (inactive)")
+
+    result = ServiceCheck("trojan-go", "fail2ban").Run(ctx)
+    assert.Equal(t, Warn, result.Status, "units that are not installed yet only warn")
+    assert.Contains(t, result.Message, "not installed: trojan-go")
+    assert.Equal(t, Pass, ServiceCheck("fail2ban").Run(ctx).Status)
+}
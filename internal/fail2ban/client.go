@@ -0,0 +1,129 @@
+package fail2ban
+
+import (
+    "fmt"
+    "go-auto-proxy/internal/sudo"
+    "net"
+    "strconv"
+    "strings"
+)
+
+// JailStatus 是 fail2ban-client status <jail> 的解析結果
+type JailStatus struct {
+    Name            string   `json:"name"`
+    CurrentlyFailed int      `json:"currently_failed"`
+    TotalFailed     int      `json:"total_failed"`
+    CurrentlyBanned int      `json:"currently_banned"`
+    TotalBanned     int      `json:"total_banned"`
+    BannedIPs       []string `json:"banned_ips"`
+}
+
+// statusFields 將 fail2ban-client 的樹狀輸出解析為鍵值對：
+//
+//	Status for the jail: sshd
+//	|- Filter
+//	|  |- Currently failed:	0
+//	...
+//	   `- Banned IP list:	1.2.3.4 5.6.7.8
+func statusFields(out string) map[string]string {
+    fields := make(map[string]string)
+    for _, line := range strings.Split(out, "\n") {
+        line = strings.TrimLeft(line, " |`-")
+        key, value, ok := strings.Cut(line, ":")
+        if !ok {
+            continue
+        }
+        fields[strings.TrimSpace(key)] = strings.TrimSpace(value)
+    }
+    return fields
+}
+
+// ParseJailList 解析 fail2ban-client status 輸出中的 jail 列表
+func ParseJailList(out string) []string {
+    var jails []string
+    for _, name := range strings.Split(statusFields(out)["Jail list"], ",") {
+        if name = strings.TrimSpace(name); name != "" {
+            jails = append(jails, name)
+        }
+    }
+    return jails
+}
+
+// ParseStatus 解析 fail2ban-client status <jail> 的輸出
+func ParseStatus(jail, out string) (JailStatus, error) {
+    fields := statusFields(out)
+    status := JailStatus{Name: jail, BannedIPs: strings.Fields(fields["Banned IP list"])}
+    counters := []struct {
+        key   string
+        value *int
+    }{
+        {"Currently failed", &status.CurrentlyFailed},
+        {"Total failed", &status.TotalFailed},
+        {"Currently banned", &status.CurrentlyBanned},
+        {"Total banned", &status.TotalBanned},
+    }
+    for _, c := range counters {
+        raw, ok := fields[c.key]
+        if !ok {
+            return status, fmt.Errorf("unexpected fail2ban status output for %s: missing %q", jail, c.key)
+        }
+        n, err := strconv.Atoi(raw)
+        if err != nil {
+            return status, fmt.Errorf("invalid %q in fail2ban status for %s: %v", c.key, jail, err)
+        }
+        *c.value = n
+    }
+    return status, nil
+}
+
+// Jails 返回 fail2ban 目前啟用的 jail
+func Jails() ([]string, error) {
+    out, err := sudo.Run("fail2ban-client", "status")
+    if err != nil {
+        return nil, err
+    }
+    return ParseJailList(string(out)), nil
+}
+
+// Status 查詢單個 jail 的狀態
+func Status(jail string) (JailStatus, error) {
+    out, err := sudo.Run("fail2ban-client", "status", jail)
+    if err != nil {
+        return JailStatus{Name: jail}, err
+    }
+    return ParseStatus(jail, string(out))
+}
+
+// Ban 在指定 jail 中封鎖 IP
+func Ban(jail, ip string) error {
+    _, err := sudo.Run("fail2ban-client", "set", jail, "banip", ip)
+    return err
+}
+
+// Unban 解除封鎖；jail 為空時從所有 jail 中解除
+func Unban(jail, ip string) error {
+    if jail == "" {
+        _, err := sudo.Run("fail2ban-client", "unban", ip)
+        return err
+    }
+    _, err := sudo.Run("fail2ban-client", "set", jail, "unbanip", ip)
+    return err
+}
+
+// Allowed 檢查 IP 是否在允許清單中，清單可包含單個 IP 或 CIDR
+func Allowed(ip string, allowlist []string) bool {
+    addr := net.ParseIP(ip)
+    if addr == nil {
+        return false
+    }
+    for _, entry := range allowlist {
+        if _, network, err := net.ParseCIDR(entry); err == nil {
+            if network.Contains(addr) {
+                return true
+            }
+        } else if other := net.ParseIP(entry); other != nil && other.Equal(addr) {
+            return true
+        }
+    }
+    return false
+}
@@ -0,0 +1,66 @@
+package fail2ban
+
+import (
+    "testing"
+
+    "github.com/stretchr/testify/assert"
+    "github.com/stretchr/testify/require"
+)
+
+const statusOutput = `Status
+|- Number of jail:	2
+` + "`" + `- Jail list:	sshd, go-auto-proxy-trojan-go
+`
+
+const jailStatusOutput = `Status for the jail: sshd
+|- Filter
+|  |- Currently failed:	2
+|  |- Total failed:	57
+|  ` + "`" + `- Journal matches:	_SYSTEMD_UNIT=sshd.service + _COMM=sshd
+` + "`" + `- Actions
+   |- Currently banned:	2
+   |- Total banned:	9
+   ` + "`" + `- Banned IP list:	203.0.113.7 2001:db8::1
+`
+
+func TestParseJailList(t *testing.T) {
+    assert.Equal(t, []string{"sshd", "go-auto-proxy-trojan-go"}, ParseJailList(statusOutput))
+    assert.Empty(t, ParseJailList("Status\n|- Number of jail:\t0\n`- Jail list:\t\n"))
+}
+
+func TestParseStatus(t *testing.T) {
+    status, err := ParseStatus("sshd", jailStatusOutput)
+    require.NoError(t, err)
+    assert.Equal(t, JailStatus{
+        Name:            "sshd",
+        CurrentlyFailed: 2,
+        TotalFailed:     57,
+        CurrentlyBanned: 2,
+        TotalBanned:     9,
+        BannedIPs:       []string{"203.0.113.7", "2001:db8::1"},
+    }, status)
+
+    _, err = ParseStatus("sshd", "Sorry but the jail 'sshd' does not exist")
+    assert.Error(t, err)
+}
+
+func TestStatusCommand(t *testing.T) {
+    calls := withTempDirs(t, false)
+    _, err := Status("sshd")
+    assert.Error(t, err, "empty output from the stub cannot be parsed")
+    assert.Equal(t, []string{"status sshd"}, *calls)
+
+    require.NoError(t, Ban("sshd", "203.0.113.7"))
+    require.NoError(t, Unban("", "203.0.113.7"))
+    require.NoError(t, Unban("sshd", "203.0.113.7"))
+    assert.Equal(t, []string{"status sshd", "set sshd banip 203.0.113.7", "unban 203.0.113.7", "set sshd unbanip 203.0.113.7"}, *calls)
+}
+
+func TestAllowed(t *testing.T) {
+    allowlist := []string{"198.51.100.10", "10.147.17.0/24", "2001:db8::/32"}
+    assert.True(t, Allowed("198.51.100.10", allowlist))
+    assert.True(t, Allowed("10.147.17.42", allowlist))
+    assert.True(t, Allowed("2001:db8::1", allowlist))
+    assert.False(t, Allowed("203.0.113.7", allowlist))
+    assert.False(t, Allowed("not-an-ip", allowlist))
+}
@@ -0,0 +1,219 @@
+package fail2ban
+
+import (
+    "bytes"
+    "fmt"
+    "go-auto-proxy/internal/sudo"
+    "log"
+    "os"
+    "path/filepath"
+    "sort"
+    "strings"
+    "text/template"
+)
+
+// 生成的檔案以此前綴命名，方便辨識與清理
+const managedPrefix = "go-auto-proxy-"
+
+var (
+    // JailDir 與 FilterDir 為 fail2ban 的配置目錄
+    JailDir   = "/etc/fail2ban/jail.d"
+    FilterDir = "/etc/fail2ban/filter.d"
+)
+
+// Settings 是單個 jail 的封鎖參數，零值欄位不寫入 jail，沿用 fail2ban 的預設值
+type Settings struct {
+    BanTime  string `json:"bantime,omitempty"`  // 如 1h
+    FindTime string `json:"findtime,omitempty"` // 如 10m
+    MaxRetry int    `json:"maxretry,omitempty"`
+}
+
+// Options 描述要生成的 jail
+type Options struct {
+    // Items 為 config.json 中的 monitored_items，如 ssh、nginx、trojan-go
+    Items      []string
+    Defaults   Settings
+    Overrides  map[string]Settings // 以 jail 名稱為鍵
+    TrojanPort int
+    IgnoreIP   []string
+}
+
+// Jail 是渲染到 jail.d 的一個 jail
+type Jail struct {
+    Name         string
+    Filter       string
+    Port         string
+    Backend      string
+    LogPath      string
+    JournalMatch string
+    Settings     Settings
+    IgnoreIP     string
+}
+
+// jailsFor 將監控項目對應到 jail
+func jailsFor(item string, trojanPort int) ([]Jail, error) {
+    switch item {
+    case "ssh", "sshd":
+        return []Jail{{Name: "sshd", Filter: "sshd", Port: "ssh", Backend: "systemd"}}, nil
+    case "nginx":
+        return []Jail{
+            {Name: "nginx-http-auth", Filter: "nginx-http-auth", Port: "http,https", LogPath: "/var/log/nginx/error.log"},
+            {Name: "nginx-botsearch", Filter: "nginx-botsearch", Port: "http,https", LogPath: "/var/log/nginx/access.log"},
+        }, nil
+    case "trojan-go":
+        return []Jail{{
+            Name:         "trojan-go",
+            Filter:       "go-auto-proxy-trojan-go",
+            Port:         fmt.Sprint(trojanPort),
+            Backend:      "systemd",
+            JournalMatch: "_SYSTEMD_UNIT=trojan-go.service",
+        }}, nil
+    }
+    return nil, fmt.Errorf("unsupported fail2ban monitored item %q (use ssh, nginx or trojan-go)", item)
+}
+
+// trojanGoFilter 比對 trojan-go 驗證失敗時記錄的日誌：
+// "connection with invalid trojan header from 1.2.3.4:5678"
+const trojanGoFilter = `# Managed by go-auto-proxy
+[Definition]
+failregex = connection with invalid trojan header from \[?<HOST>\]?:\d+
+ignoreregex =
+`
+
+var jailTemplate = template.Must(template.New("jail").Parse(`# Managed by go-auto-proxy
+[{{.Name}}]
+enabled  = true
+filter   = {{.Filter}}
+port     = {{.Port}}
+{{- if .Backend}}
+backend  = {{.Backend}}
+{{- end}}
+{{- if .LogPath}}
+logpath  = {{.LogPath}}
+{{- end}}
+{{- if .JournalMatch}}
+journalmatch = {{.JournalMatch}}
+{{- end}}
+{{- if .Settings.BanTime}}
+bantime  = {{.Settings.BanTime}}
+{{- end}}
+{{- if .Settings.FindTime}}
+findtime = {{.Settings.FindTime}}
+{{- end}}
+{{- if .Settings.MaxRetry}}
+maxretry = {{.Settings.MaxRetry}}
+{{- end}}
+{{- if .IgnoreIP}}
+ignoreip = {{.IgnoreIP}}
+{{- end}}
+`))
+
+// merge 以 override 中的非零欄位覆蓋預設值
+func merge(defaults, override Settings) Settings {
+    if override.BanTime != "" {
+        defaults.BanTime = override.BanTime
+    }
+    if override.FindTime != "" {
+        defaults.FindTime = override.FindTime
+    }
+    if override.MaxRetry > 0 {
+        defaults.MaxRetry = override.MaxRetry
+    }
+    return defaults
+}
+
+// Render 返回要寫入的檔案路徑與內容
+func Render(opts Options) (map[string][]byte, error) {
+    files := make(map[string][]byte)
+    ignoreIP := ""
+    if len(opts.IgnoreIP) > 0 {
+        ignoreIP = "127.0.0.1/8 ::1 " + strings.Join(opts.IgnoreIP, " ")
+    }
+
+    for _, item := range opts.Items {
+        jails, err := jailsFor(item, opts.TrojanPort)
+        if err != nil {
+            return nil, err
+        }
+        for _, jail := range jails {
+            jail.Settings = merge(opts.Defaults, opts.Overrides[jail.Name])
+            jail.IgnoreIP = ignoreIP
+            var buf bytes.Buffer
+            if err := jailTemplate.Execute(&buf, jail); err != nil {
+                return nil, err
+            }
+            files[filepath.Join(JailDir, managedPrefix+jail.Name+".conf")] = buf.Bytes()
+            if jail.Name == "trojan-go" {
+                files[filepath.Join(FilterDir, jail.Filter+".conf")] = []byte(trojanGoFilter)
+            }
+        }
+    }
+    return files, nil
+}
+
+// Apply 寫入 jail 與 filter，移除不再監控的舊檔案，
+// 以 fail2ban-client -t 驗證後才重新載入；驗證失敗時還原原有檔案
+func Apply(opts Options) error {
+    files, err := Render(opts)
+    if err != nil {
+        return err
+    }
+
+    // 記錄原有的受管檔案以便還原
+    previous := make(map[string][]byte)
+    for _, dir := range []string{JailDir, FilterDir} {
+        matches, _ := filepath.Glob(filepath.Join(dir, managedPrefix+"*.conf"))
+        for _, path := range matches {
+            if data, err := os.ReadFile(path); err == nil {
+                previous[path] = data
+            }
+        }
+    }
+
+    paths := make([]string, 0, len(files))
+    for path := range files {
+        paths = append(paths, path)
+    }
+    sort.Strings(paths)
+    for _, path := range paths {
+        if err := sudo.WriteFile(path, files[path], 0644); err != nil {
+            return err
+        }
+        log.Printf("Wrote %s", path)
+    }
+    for path := range previous {
+        if _, ok := files[path]; !ok {
+            if err := sudo.RemoveFile(path); err != nil {
+                return err
+            }
+            log.Printf("Removed stale %s", path)
+        }
+    }
+
+    if _, err := sudo.Run("fail2ban-client", "-t"); err != nil {
+        log.Println("fail2ban configuration test failed, restoring previous files...")
+        restore(files, previous)
+        return fmt.Errorf("fail2ban configuration is invalid: %v", err)
+    }
+    if _, err := sudo.Run("fail2ban-client", "reload"); err != nil {
+        return fmt.Errorf("failed to reload fail2ban: %v", err)
+    }
+    log.Printf("fail2ban reloaded with %d managed file(s).", len(files))
+    return nil
+}
+
+// restore 將受管檔案還原為寫入前的狀態
+func restore(written, previous map[string][]byte) {
+    for path := range written {
+        if _, ok := previous[path]; !ok {
+            if err := sudo.RemoveFile(path); err != nil {
+                log.Printf("Failed to remove %s: %v", path, err)
+            }
+        }
+    }
+    for path, data := range previous {
+        if err := sudo.WriteFile(path, data, 0644); err != nil {
+            log.Printf("Failed to restore %s: %v", path, err)
+        }
+    }
+}
@@ -0,0 +1,115 @@
+package fail2ban
+
+import (
+    "go-auto-proxy/internal/sudo"
+    "os"
+    "os/exec"
+    "path/filepath"
+    "strings"
+    "testing"
+
+    "github.com/stretchr/testify/assert"
+    "github.com/stretchr/testify/require"
+)
+
+// withTempDirs 將配置目錄指向臨時目錄，並攔截 fail2ban-client
+func withTempDirs(t *testing.T, testFails bool) *[]string {
+    originalJail, originalFilter := JailDir, FilterDir
+    JailDir, FilterDir = t.TempDir(), t.TempDir()
+
+    var calls []string
+    original := sudo.DefaultCommand
+    sudo.DefaultCommand = func(name string, args ...string) *exec.Cmd {
+        if name == "sudo" {
+            name, args = args[0], args[1:]
+        }
+        if name == "fail2ban-client" {
+            calls = append(calls, strings.Join(args, " "))
+            if testFails && args[0] == "-t" {
+                return exec.Command("false")
+            }
+            return exec.Command("true")
+        }
+        return exec.Command(name, args...)
+    }
+    t.Cleanup(func() {
+        JailDir, FilterDir = originalJail, originalFilter
+        sudo.DefaultCommand = original
+    })
+    return &calls
+}
+
+func TestRender(t *testing.T) {
+    files, err := Render(Options{
+        Items:      []string{"ssh", "nginx", "trojan-go"},
+        Defaults:   Settings{BanTime: "1h", FindTime: "10m", MaxRetry: 5},
+        Overrides:  map[string]Settings{"trojan-go": {BanTime: "24h", MaxRetry: 3}},
+        TrojanPort: 443,
+        IgnoreIP:   []string{"10.147.17.0/24"},
+    })
+    require.NoError(t, err)
+    assert.Len(t, files, 5)
+
+    sshd := string(files[filepath.Join(JailDir, "go-auto-proxy-sshd.conf")])
+    assert.Contains(t, sshd, "[sshd]")
+    assert.Contains(t, sshd, "backend  = systemd")
+    assert.Contains(t, sshd, "bantime  = 1h")
+    assert.Contains(t, sshd, "maxretry = 5")
+    assert.Contains(t, sshd, "ignoreip = 127.0.0.1/8 ::1 10.147.17.0/24")
+
+    auth := string(files[filepath.Join(JailDir, "go-auto-proxy-nginx-http-auth.conf")])
+    assert.Contains(t, auth, "logpath  = /var/log/nginx/error.log")
+    assert.NotContains(t, auth, "backend")
+    assert.Contains(t, string(files[filepath.Join(JailDir, "go-auto-proxy-nginx-botsearch.conf")]), "filter   = nginx-botsearch")
+
+    trojan := string(files[filepath.Join(JailDir, "go-auto-proxy-trojan-go.conf")])
+    assert.Contains(t, trojan, "filter   = go-auto-proxy-trojan-go")
+    assert.Contains(t, trojan, "port     = 443")
+    assert.Contains(t, trojan, "journalmatch = _SYSTEMD_UNIT=trojan-go.service")
+    assert.Contains(t, trojan, "bantime  = 24h")
+    assert.Contains(t, trojan, "findtime = 10m", "unset override fields keep the default")
+    assert.Contains(t, trojan, "maxretry = 3")
+    assert.Contains(t, string(files[filepath.Join(FilterDir, "go-auto-proxy-trojan-go.conf")]), "<HOST>")
+
+    // 較舊的 config.json 沒有 bantime 等設定，不能渲染成 maxretry = 0（第一次失敗就封鎖）
+    files, err = Render(Options{Items: []string{"ssh"}})
+    require.NoError(t, err)
+    sshd = string(files[filepath.Join(JailDir, "go-auto-proxy-sshd.conf")])
+    assert.NotContains(t, sshd, "bantime")
+    assert.NotContains(t, sshd, "findtime")
+    assert.NotContains(t, sshd, "maxretry")
+
+    _, err = Render(Options{Items: []string{"postfix"}})
+    assert.ErrorContains(t, err, "unsupported")
+}
+
+func TestApply(t *testing.T) {
+    calls := withTempDirs(t, false)
+    stale := filepath.Join(JailDir, "go-auto-proxy-nginx-http-auth.conf")
+    require.NoError(t, os.WriteFile(stale, []byte("old"), 0644))
+    unmanaged := filepath.Join(JailDir, "defaults-debian.conf")
+    require.NoError(t, os.WriteFile(unmanaged, []byte("keep"), 0644))
+
+    err := Apply(Options{Items: []string{"ssh"}, Defaults: Settings{BanTime: "1h", FindTime: "10m", MaxRetry: 5}})
+    require.NoError(t, err)
+    assert.Equal(t, []string{"-t", "reload"}, *calls)
+    assert.FileExists(t, filepath.Join(JailDir, "go-auto-proxy-sshd.conf"))
+    assert.NoFileExists(t, stale, "jails no longer monitored are removed")
+    assert.FileExists(t, unmanaged)
+}
+
+func TestApplyRestoresOnInvalidConfig(t *testing.T) {
+    calls := withTempDirs(t, true)
+    existing := filepath.Join(JailDir, "go-auto-proxy-sshd.conf")
+    require.NoError(t, os.WriteFile(existing, []byte("previous"), 0644))
+
+    err := Apply(Options{Items: []string{"ssh", "trojan-go"}, TrojanPort: 443})
+    assert.ErrorContains(t, err, "invalid")
+    assert.Equal(t, []string{"-t"}, *calls, "fail2ban must not be reloaded")
+
+    data, err := os.ReadFile(existing)
+    require.NoError(t, err)
+    assert.Equal(t, "previous", string(data))
+    assert.NoFileExists(t, filepath.Join(JailDir, "go-auto-proxy-trojan-go.conf"))
+    assert.NoFileExists(t, filepath.Join(FilterDir, "go-auto-proxy-trojan-go.conf"))
+}
@@ -0,0 +1,165 @@
+package firewall
+
+import (
+    "bufio"
+    "bytes"
+    "fmt"
+    "go-auto-proxy/internal/systemd"
+    "log"
+    "os"
+    "os/exec"
+    "strconv"
+    "strings"
+)
+
+// Tag 寫入每條規則的註解，用於辨識本工具新增的規則
+const Tag = "go-auto-proxy"
+
+var (
+    // SSHDConfig 用於找出 sshd 監聽的端口，可在測試中替換
+    SSHDConfig = "/etc/ssh/sshd_config"
+    // LookPath 檢查防火牆工具是否已安裝，可在測試中替換
+    LookPath = exec.LookPath
+)
+
+// Rule 是一條放行規則
+type Rule struct {
+    Port      int
+    Proto     string // tcp 或 udp
+    Source    string // IP 或 CIDR，留空表示任何來源
+    Interface string // 只放行從此介面（如 ZeroTier 的 zt*）進入的流量，留空表示任何介面
+}
+
+func (r Rule) String() string {
+    s := fmt.Sprintf("%d/%s", r.Port, r.Proto)
+    if r.Source != "" {
+        s += " from " + r.Source
+    }
+    if r.Interface != "" {
+        s += " on " + r.Interface
+    }
+    return s
+}
+
+// ipv6 表示來源限定為 IPv6 位址
+func (r Rule) ipv6() bool {
+    return strings.Contains(r.Source, ":")
+}
+
+// Backend 是一種主機防火牆；Allow 與 Remove 皆為冪等操作，
+// 只會新增或刪除帶有 Tag 的規則
+type Backend interface {
+    Name() string
+    // Active 表示此防火牆已啟用並會過濾入站流量
+    Active() bool
+    Allow(rule Rule) error
+    Remove(rule Rule) error
+    // RemoveAll 刪除所有帶有 Tag 的規則
+    RemoveAll() error
+    // Save 保存目前的規則，使其在重開機後仍然有效；無法保存時返回錯誤
+    Save() error
+}
+
+// backends 依偵測優先順序排列：ufw 與 firewalld 建立在 iptables/nftables 之上，必須先判斷
+var backends = []Backend{ufw{}, firewalld{}, nftables{}, iptables{}}
+
+// commands 是各防火牆的命令名稱
+var commands = map[string]string{
+    "ufw":       "ufw",
+    "firewalld": "firewall-cmd",
+    "nftables":  "nft",
+    "iptables":  "iptables",
+}
+
+// Detect 返回指定名稱的防火牆；name 為 auto 或空字串時依序偵測第一個啟用中的防火牆。
+// 沒有啟用任何防火牆或 name 為 none 時返回 nil
+func Detect(name string) (Backend, error) {
+    switch name {
+    case "none":
+        return nil, nil
+    case "", "auto":
+        for _, b := range backends {
+            if _, err := LookPath(commands[b.Name()]); err != nil {
+                continue
+            }
+            if b.Active() {
+                return b, nil
+            }
+        }
+        return nil, nil
+    }
+    for _, b := range backends {
+        if b.Name() == name {
+            return b, nil
+        }
+    }
+    return nil, fmt.Errorf("unsupported firewall %q (use auto, ufw, firewalld, nftables, iptables or none)", name)
+}
+
+// Apply 在防火牆上放行 rules；b 為 nil 時表示沒有啟用防火牆，不做任何事
+func Apply(b Backend, rules []Rule) error {
+    if b == nil {
+        log.Println("No active host firewall detected, no rules needed.")
+        return nil
+    }
+    for _, rule := range rules {
+        if err := b.Allow(rule); err != nil {
+            return fmt.Errorf("failed to allow %s with %s: %v", rule, b.Name(), err)
+        }
+    }
+    if err := b.Save(); err != nil {
+        return fmt.Errorf("rules were applied but will be lost at reboot: %v", err)
+    }
+    log.Printf("Firewall (%s) allows %d rules tagged %q.", b.Name(), len(rules), Tag)
+    return nil
+}
+
+// Cleanup 從所有已安裝的防火牆刪除帶有 Tag 的規則，供解除安裝使用
+func Cleanup() error {
+    var errs []string
+    for _, b := range backends {
+        if _, err := LookPath(commands[b.Name()]); err != nil {
+            continue
+        }
+        if err := b.RemoveAll(); err != nil {
+            errs = append(errs, fmt.Sprintf("%s: %v", b.Name(), err))
+            continue
+        }
+        if b.Active() {
+            if err := b.Save(); err != nil {
+                errs = append(errs, fmt.Sprintf("%s: %v", b.Name(), err))
+            }
+        }
+    }
+    if err := systemd.RemoveService(restrictUnit); err != nil {
+        errs = append(errs, err.Error())
+    }
+    if len(errs) > 0 {
+        return fmt.Errorf("failed to remove firewall rules: %s", strings.Join(errs, "; "))
+    }
+    log.Printf("Removed all firewall rules tagged %q.", Tag)
+    return nil
+}
+
+// SSHPorts 讀取 sshd_config 的 Port 設定，未設定或無法讀取時返回 22
+func SSHPorts() []int {
+    data, err := os.ReadFile(SSHDConfig)
+    if err != nil {
+        return []int{22}
+    }
+    var ports []int
+    scanner := bufio.NewScanner(bytes.NewReader(data))
+    for scanner.Scan() {
+        fields := strings.Fields(scanner.Text())
+        if len(fields) < 2 || !strings.EqualFold(fields[0], "Port") {
+            continue
+        }
+        if port, err := strconv.Atoi(fields[1]); err == nil {
+            ports = append(ports, port)
+        }
+    }
+    if len(ports) == 0 {
+        return []int{22}
+    }
+    return ports
+}
@@ -0,0 +1,413 @@
+package firewall
+
+import (
+    "errors"
+    "go-auto-proxy/internal/sudo"
+    "go-auto-proxy/internal/systemd"
+    "os"
+    "os/exec"
+    "path/filepath"
+    "strings"
+    "testing"
+
+    "github.com/stretchr/testify/assert"
+    "github.com/stretchr/testify/require"
+)
+
+// fakeIPTables 以記憶體中的 INPUT 鏈模擬 iptables 與 ip6tables；
+// systemd unit 寫入暫存目錄，systemctl 不實際執行
+func fakeIPTables(t *testing.T) map[string][]string {
+    chains := map[string][]string{}
+    original, originalDir := sudo.DefaultCommand, systemd.UnitDir
+    systemd.UnitDir = t.TempDir()
+    sudo.DefaultCommand = func(name string, args ...string) *exec.Cmd {
+        if name == "sudo" {
+            name, args = args[0], args[1:]
+        }
+        switch name {
+        case "systemctl":
+            return exec.Command("true")
+        case "tee", "chmod", "rm":
+            return exec.Command(name, args...)
+        }
+        require.Contains(t, tools, name)
+        op, rest := args[0], args[1:]
+        switch op {
+        case "-C", "-D":
+            rule := strings.Join(rest[1:], " ")
+            for i, existing := range chains[name] {
+                if existing == rule {
+                    if op == "-D" {
+                        chains[name] = append(chains[name][:i], chains[name][i+1:]...)
+                    }
+                    return exec.Command("true")
+                }
+            }
+            return exec.Command("false")
+        case "-S":
+            out := "-P INPUT ACCEPT\n"
+            for _, rule := range chains[name] {
+                out += "-A INPUT " + rule + "\n"
+            }
+            return exec.Command("printf", "%s", out)
+        case "-I":
+            rule := strings.Join(rest[2:], " ")
+            chains[name] = append([]string{rule}, chains[name]...)
+        }
+        return exec.Command("true")
+    }
+    t.Cleanup(func() {
+        sudo.DefaultCommand = original
+        systemd.UnitDir = originalDir
+    })
+    return chains
+}
+
+func TestRestrictToInterface(t *testing.T) {
+    chains := fakeIPTables(t)
+
+    require.NoError(t, RestrictToInterface(443, "ztabcdef12"))
+    require.NoError(t, RestrictToInterface(443, "ztabcdef12"), "applying twice should be idempotent")
+    for _, tool := range tools {
+        assert.Equal(t, []string{
+            "-i lo -p tcp --dport 443 -m comment --comment go-auto-proxy -j ACCEPT",
+            "! -i ztabcdef12 -p tcp --dport 443 -m comment --comment go-auto-proxy -j DROP",
+        }, chains[tool])
+    }
+    unit, err := os.ReadFile(filepath.Join(systemd.UnitDir, restrictUnit+".service"))
+    require.NoError(t, err, "the restriction is reapplied at boot")
+    assert.Contains(t, string(unit), "After=network-pre.target ufw.service")
+    assert.Contains(t, string(unit), "ExecStart=/bin/sh -c 'ip6tables -C INPUT ! -i ztabcdef12 -p tcp --dport 443 -m comment --comment go-auto-proxy -j DROP || ip6tables -I INPUT 1 ! -i ztabcdef12 -p tcp --dport 443 -m comment --comment go-auto-proxy -j DROP'")
+
+    require.NoError(t, RemoveInterfaceRestriction(443, "ztabcdef12"))
+    for _, tool := range tools {
+        assert.Empty(t, chains[tool])
+    }
+    _, err = os.Stat(filepath.Join(systemd.UnitDir, restrictUnit+".service"))
+    assert.True(t, os.IsNotExist(err), "the boot unit is removed with the restriction")
+
+    assert.Error(t, RestrictToInterface(443, ""))
+}
+
+func TestIPTablesBackend(t *testing.T) {
+    chains := fakeIPTables(t)
+    b := iptables{}
+
+    require.NoError(t, b.Allow(Rule{Port: 8443, Proto: "tcp"}))
+    require.NoError(t, b.Allow(Rule{Port: 8443, Proto: "tcp"}))
+    require.NoError(t, b.Allow(Rule{Port: 9993, Proto: "udp", Source: "10.0.0.0/8"}))
+    assert.Equal(t, []string{
+        "-p udp -s 10.0.0.0/8 --dport 9993 -m comment --comment go-auto-proxy -j ACCEPT",
+        "-p tcp --dport 8443 -m comment --comment go-auto-proxy -j ACCEPT",
+    }, chains["iptables"])
+    assert.Equal(t, []string{"-p tcp --dport 8443 -m comment --comment go-auto-proxy -j ACCEPT"}, chains["ip6tables"], "IPv4 sources only go to iptables")
+
+    require.NoError(t, b.Allow(Rule{Port: 443, Proto: "tcp", Interface: "ztabcdef12"}))
+    assert.Equal(t, "-i ztabcdef12 -p tcp --dport 443 -m comment --comment go-auto-proxy -j ACCEPT", chains["ip6tables"][0])
+    require.NoError(t, b.Remove(Rule{Port: 443, Proto: "tcp", Interface: "ztabcdef12"}))
+
+    require.NoError(t, b.Remove(Rule{Port: 8443, Proto: "tcp"}))
+    require.NoError(t, b.Remove(Rule{Port: 8443, Proto: "tcp"}), "removing a missing rule is a no-op")
+    assert.Len(t, chains["iptables"], 1)
+    assert.Empty(t, chains["ip6tables"])
+
+    require.NoError(t, RestrictToInterface(443, "ztabcdef12"))
+    chains["iptables"] = append(chains["iptables"], "-p tcp --dport 22 -j ACCEPT")
+    require.NoError(t, b.RemoveAll())
+    assert.Equal(t, []string{"-p tcp --dport 22 -j ACCEPT"}, chains["iptables"], "only tagged rules are removed")
+    assert.Empty(t, chains["ip6tables"])
+}
+
+const ufwStatus = `Status: active
+
+     To                         Action      From
+     --                         ------      ----
+[ 1] 22/tcp                     ALLOW IN    Anywhere
+[ 2] 443/tcp                    ALLOW IN    Anywhere                   # go-auto-proxy
+[ 3] 9993/udp                   ALLOW IN    10.0.0.0/8                 # go-auto-proxy
+[ 4] 22/tcp (v6)                ALLOW IN    Anywhere (v6)
+[ 5] 443/tcp (v6)               ALLOW IN    Anywhere (v6)              # go-auto-proxy
+[ 6] 8443/tcp on ztabcdef12     ALLOW IN    Anywhere                   # go-auto-proxy
+[ 7] 8443/tcp (v6) on ztabcdef12 ALLOW IN    Anywhere (v6)              # go-auto-proxy
+`
+
+// fakeCommand 記錄 sudo 執行的命令，並以 respond 決定輸出與結果；
+// 寫入檔案的 tee、chmod 與 mkdir 實際執行，測試應將路徑指向暫存目錄
+func fakeCommand(t *testing.T, respond func(cmd string) (string, bool)) *[]string {
+    var calls []string
+    original := sudo.DefaultCommand
+    sudo.DefaultCommand = func(name string, args ...string) *exec.Cmd {
+        if name == "sudo" {
+            name, args = args[0], args[1:]
+        }
+        cmd := strings.Join(append([]string{name}, args...), " ")
+        calls = append(calls, cmd)
+        switch name {
+        case "tee", "chmod", "mkdir":
+            return exec.Command(name, args...)
+        }
+        out, ok := respond(cmd)
+        if !ok {
+            return exec.Command("sh", "-c", "echo \"$0\"; exit 1", out)
+        }
+        return exec.Command("printf", "%s", out)
+    }
+    t.Cleanup(func() { sudo.DefaultCommand = original })
+    return &calls
+}
+
+func TestUFWBackend(t *testing.T) {
+    entries := parseUFWStatus(ufwStatus)
+    require.Len(t, entries, 7)
+    assert.Equal(t, ufwEntry{Num: 3, To: "9993/udp", From: "10.0.0.0/8", Comment: Tag}, entries[2])
+    assert.Equal(t, ufwEntry{Num: 4, To: "22/tcp", From: "Anywhere"}, entries[3])
+    assert.Equal(t, ufwEntry{Num: 7, To: "8443/tcp", Interface: "ztabcdef12", From: "Anywhere", Comment: Tag}, entries[6])
+    assert.False(t, entries[5].matches(Rule{Port: 8443, Proto: "tcp"}), "an interface rule does not open the port publicly")
+
+    calls := fakeCommand(t, func(cmd string) (string, bool) { return ufwStatus, true })
+    b := ufw{}
+    require.NoError(t, b.Allow(Rule{Port: 22, Proto: "tcp"}))
+    require.NoError(t, b.Allow(Rule{Port: 80, Proto: "tcp"}))
+    require.NoError(t, b.Allow(Rule{Port: 9993, Proto: "udp", Source: "192.168.0.0/16"}))
+    require.NoError(t, b.Allow(Rule{Port: 8443, Proto: "tcp", Interface: "ztabcdef12"}))
+    require.NoError(t, b.Allow(Rule{Port: 443, Proto: "tcp", Interface: "ztabcdef12"}))
+    require.NoError(t, b.RemoveAll())
+    var changes []string
+    for _, call := range *calls {
+        if !strings.HasPrefix(call, "ufw status") {
+            changes = append(changes, call)
+        }
+    }
+    assert.Equal(t, []string{
+        "ufw allow 80/tcp comment go-auto-proxy",
+        "ufw allow proto udp from 192.168.0.0/16 to any port 9993 comment go-auto-proxy",
+        "ufw allow in on ztabcdef12 proto tcp to any port 443 comment go-auto-proxy",
+        "ufw --force delete 7",
+        "ufw --force delete 6",
+        "ufw --force delete 5",
+        "ufw --force delete 3",
+        "ufw --force delete 2",
+    }, changes, "existing SSH rule is kept and untagged rules are never deleted")
+}
+
+const nftChains = `table inet filter {
+	chain input {
+		type filter hook input priority filter; policy drop;
+	}
+	chain forward {
+		type filter hook forward priority filter; policy drop;
+	}
+}
+table ip filter {
+	chain INPUT {
+		type filter hook input priority filter; policy accept;
+	}
+}
+`
+
+const nftInput = `table inet filter {
+	chain input { # handle 1
+		type filter hook input priority filter; policy drop;
+		tcp dport 443 accept comment "go-auto-proxy" # handle 7
+		ct state established,related accept # handle 4
+		tcp dport 22 accept # handle 5
+	}
+}
+`
+
+func TestNFTablesBackend(t *testing.T) {
+    assert.Equal(t, []nftChain{{Family: "inet", Table: "filter", Name: "input"}}, parseNFTChains(nftChains), "iptables-nft chains are left to the iptables backend")
+    assert.Equal(t, `ip6 saddr fd00::/8 udp dport 9993 accept comment "go-auto-proxy"`, nftExpr(Rule{Port: 9993, Proto: "udp", Source: "fd00::/8"}))
+    assert.Equal(t, `iifname "ztabcdef12" tcp dport 443 accept comment "go-auto-proxy"`, nftExpr(Rule{Port: 443, Proto: "tcp", Interface: "ztabcdef12"}))
+
+    calls := fakeCommand(t, func(cmd string) (string, bool) {
+        if cmd == "nft list chains" {
+            return nftChains, true
+        }
+        return nftInput, true
+    })
+    b := nftables{}
+    assert.True(t, b.Active())
+    require.NoError(t, b.Allow(Rule{Port: 443, Proto: "tcp"}))
+    require.NoError(t, b.Allow(Rule{Port: 80, Proto: "tcp"}))
+    require.NoError(t, b.RemoveAll())
+    assert.Contains(t, *calls, `nft insert rule inet filter input tcp dport 80 accept comment "go-auto-proxy"`)
+    assert.NotContains(t, *calls, `nft insert rule inet filter input tcp dport 443 accept comment "go-auto-proxy"`)
+    assert.Contains(t, *calls, "nft delete rule inet filter input handle 7")
+    assert.NotContains(t, *calls, "nft delete rule inet filter input handle 5")
+}
+
+func TestNFTablesSave(t *testing.T) {
+    dir := t.TempDir()
+    originalConf, originalFile := NFTConf, NFTRulesFile
+    NFTConf, NFTRulesFile = filepath.Join(dir, "nftables.conf"), filepath.Join(dir, "nftables.d", "go-auto-proxy.nft")
+    defer func() { NFTConf, NFTRulesFile = originalConf, originalFile }()
+    fakeCommand(t, func(cmd string) (string, bool) {
+        if cmd == "nft list chains" {
+            return nftChains, true
+        }
+        return `table inet filter {
+	chain input { # handle 1
+		type filter hook input priority filter; policy drop;
+		iifname "ztabcdef12" tcp dport 443 accept comment "go-auto-proxy" # handle 8
+		tcp dport 80 accept comment "go-auto-proxy" # handle 7
+		tcp dport 22 accept # handle 5
+	}
+}
+`, true
+    })
+    b := nftables{}
+
+    assert.Error(t, b.Save(), "rules cannot be persisted without nftables.conf")
+
+    require.NoError(t, os.WriteFile(NFTConf, []byte("#!/usr/sbin/nft -f\nflush ruleset\n"), 0755))
+    require.NoError(t, b.Save())
+    require.NoError(t, b.Save(), "saving twice should not include the file twice")
+    conf, err := os.ReadFile(NFTConf)
+    require.NoError(t, err)
+    assert.Equal(t, "#!/usr/sbin/nft -f\nflush ruleset\ninclude \""+NFTRulesFile+"\"\n", string(conf))
+    info, err := os.Stat(NFTConf)
+    require.NoError(t, err)
+    assert.Equal(t, os.FileMode(0755), info.Mode().Perm())
+    rules, err := os.ReadFile(NFTRulesFile)
+    require.NoError(t, err)
+    assert.Equal(t, `# Managed by go-auto-proxy
+insert rule inet filter input tcp dport 80 accept comment "go-auto-proxy"
+insert rule inet filter input iifname "ztabcdef12" tcp dport 443 accept comment "go-auto-proxy"
+`, string(rules), "untagged rules stay with the user's own config")
+}
+
+func TestIPTablesSave(t *testing.T) {
+    originalLookPath, originalDir := LookPath, IPTablesSysconfigDir
+    defer func() { LookPath, IPTablesSysconfigDir = originalLookPath, originalDir }()
+    IPTablesSysconfigDir = t.TempDir()
+    persistent, services := false, false
+    LookPath = func(file string) (string, error) {
+        if file == "netfilter-persistent" && persistent {
+            return "/usr/sbin/netfilter-persistent", nil
+        }
+        return "", errors.New("not found")
+    }
+    calls := fakeCommand(t, func(cmd string) (string, bool) {
+        switch {
+        case strings.HasPrefix(cmd, "systemctl list-unit-files"):
+            if services {
+                return "iptables.service disabled enabled\n", true
+            }
+            return "", true
+        case strings.HasSuffix(cmd, "-save"):
+            return "*filter\n-A INPUT -p tcp --dport 443 -j ACCEPT\nCOMMIT\n", true
+        }
+        return "", true
+    })
+    b := iptables{}
+
+    assert.ErrorContains(t, b.Save(), "cannot be persisted")
+
+    services = true
+    require.NoError(t, b.Save())
+    data, err := os.ReadFile(filepath.Join(IPTablesSysconfigDir, "ip6tables"))
+    require.NoError(t, err)
+    assert.Contains(t, string(data), "--dport 443")
+
+    persistent = true
+    *calls = nil
+    require.NoError(t, b.Save())
+    assert.Equal(t, []string{"netfilter-persistent save"}, *calls)
+}
+
+func TestFirewalldBackend(t *testing.T) {
+    zone := map[string]bool{}
+    calls := fakeCommand(t, func(cmd string) (string, bool) {
+        arg := cmd[strings.LastIndex(cmd, " --")+1:]
+        switch {
+        case cmd == "firewall-cmd --state":
+            return "running", true
+        case strings.HasPrefix(arg, "--info-service="):
+            return "", false
+        case strings.HasPrefix(arg, "--query-"):
+            return "no", zone[strings.SplitN(arg, "=", 2)[1]]
+        case strings.HasPrefix(arg, "--add-service="), strings.HasPrefix(arg, "--add-rich-rule="):
+            zone[strings.SplitN(arg, "=", 2)[1]] = true
+        case arg == "--list-services":
+            return "ssh dhcpv6-client go-auto-proxy-443-tcp", true
+        case arg == "--get-services":
+            return "http https ssh go-auto-proxy-443-tcp go-auto-proxy-9993-udp", true
+        case arg == "--get-zones":
+            return "block public trusted go-auto-proxy", true
+        case arg == "--list-rich-rules":
+            return `rule family="ipv4" source address="10.0.0.0/8" service name="go-auto-proxy-9993-udp" accept` + "\n", true
+        }
+        return "success", true
+    })
+    b := firewalld{}
+    require.NoError(t, b.Allow(Rule{Port: 443, Proto: "tcp"}))
+    require.NoError(t, b.Allow(Rule{Port: 9993, Proto: "udp", Source: "10.0.0.0/8"}))
+    assert.Contains(t, *calls, "firewall-cmd --permanent --service=go-auto-proxy-443-tcp --add-port=443/tcp")
+    assert.Contains(t, *calls, "firewall-cmd --permanent --add-service=go-auto-proxy-443-tcp")
+    assert.Contains(t, *calls, `firewall-cmd --permanent --add-rich-rule=rule family="ipv4" source address="10.0.0.0/8" service name="go-auto-proxy-9993-udp" accept`)
+
+    *calls = nil
+    require.NoError(t, b.Allow(Rule{Port: 8443, Proto: "tcp", Interface: "ztabcdef12"}))
+    assert.Contains(t, *calls, "firewall-cmd --permanent --zone=go-auto-proxy --change-interface=ztabcdef12")
+    assert.Contains(t, *calls, "firewall-cmd --permanent --zone=go-auto-proxy --add-service=go-auto-proxy-8443-tcp")
+    assert.NotContains(t, *calls, "firewall-cmd --permanent --add-service=go-auto-proxy-8443-tcp", "the port is only opened in the ZeroTier zone")
+
+    *calls = nil
+    require.NoError(t, b.RemoveAll())
+    assert.Equal(t, []string{
+        "firewall-cmd --state",
+        "firewall-cmd --permanent --list-rich-rules",
+        `firewall-cmd --permanent --remove-rich-rule=rule family="ipv4" source address="10.0.0.0/8" service name="go-auto-proxy-9993-udp" accept`,
+        "firewall-cmd --permanent --list-services",
+        "firewall-cmd --permanent --remove-service=go-auto-proxy-443-tcp",
+        "firewall-cmd --permanent --get-zones",
+        "firewall-cmd --permanent --delete-zone=go-auto-proxy",
+        "firewall-cmd --permanent --get-services",
+        "firewall-cmd --permanent --delete-service=go-auto-proxy-443-tcp",
+        "firewall-cmd --permanent --delete-service=go-auto-proxy-9993-udp",
+        "firewall-cmd --reload",
+    }, *calls)
+}
+
+func TestDetect(t *testing.T) {
+    original := LookPath
+    defer func() { LookPath = original }()
+    LookPath = func(file string) (string, error) {
+        if file == "ufw" {
+            return "", errors.New("not found")
+        }
+        return "/usr/sbin/" + file, nil
+    }
+    fakeCommand(t, func(cmd string) (string, bool) {
+        switch cmd {
+        case "firewall-cmd --state":
+            return "not running", false
+        case "nft list chains":
+            return "", true
+        }
+        return "-P INPUT ACCEPT\n-A INPUT -j REJECT --reject-with icmp-host-prohibited\n", true
+    })
+
+    b, err := Detect("auto")
+    require.NoError(t, err)
+    require.NotNil(t, b)
+    assert.Equal(t, "iptables", b.Name())
+
+    b, err = Detect("none")
+    assert.NoError(t, err)
+    assert.Nil(t, b)
+    _, err = Detect("pf")
+    assert.Error(t, err)
+}
+
+func TestSSHPorts(t *testing.T) {
+    path := filepath.Join(t.TempDir(), "sshd_config")
+    original := SSHDConfig
+    SSHDConfig = path
+    defer func() { SSHDConfig = original }()
+
+    assert.Equal(t, []int{22}, SSHPorts(), "missing config falls back to 22")
+    require.NoError(t, os.WriteFile(path, []byte("#Port 22\nPort 2222\nport 22022\nPermitRootLogin no\n"), 0644))
+    assert.Equal(t, []int{2222, 22022}, SSHPorts())
+}
@@ -0,0 +1,143 @@
+package firewall
+
+import (
+    "fmt"
+    "go-auto-proxy/internal/sudo"
+    "strings"
+)
+
+// firewalld 的規則沒有註解欄位，因此每個端口建立一個名稱以 Tag 開頭的 service，
+// 再把 service（或引用它的 rich rule）加入預設 zone；刪除時只需找出這些 service。
+// 限定介面的規則放在名為 Tag 的 zone，並把介面綁定到該 zone
+type firewalld struct{}
+
+func (firewalld) Name() string { return "firewalld" }
+
+func (firewalld) Active() bool {
+    out, err := sudo.Run("firewall-cmd", "--state")
+    return err == nil && strings.TrimSpace(string(out)) == "running"
+}
+
+// serviceName 返回規則對應的 service，如 go-auto-proxy-443-tcp
+func serviceName(rule Rule) string {
+    return fmt.Sprintf("%s-%d-%s", Tag, rule.Port, rule.Proto)
+}
+
+// richRule 返回只允許 rule.Source 存取 service 的 rich rule
+func richRule(rule Rule) string {
+    family := "ipv4"
+    if rule.ipv6() {
+        family = "ipv6"
+    }
+    return fmt.Sprintf(`rule family="%s" source address="%s" service name="%s" accept`, family, rule.Source, serviceName(rule))
+}
+
+// zoneArgs 返回規則所在 zone 的參數；限定介面的規則使用名為 Tag 的 zone
+func zoneArgs(rule Rule) []string {
+    if rule.Interface == "" {
+        return []string{"--permanent"}
+    }
+    return []string{"--permanent", "--zone=" + Tag}
+}
+
+// bindZone 建立名為 Tag 的 zone 並把 iface 綁定到它。從 iface 進入的流量改由此 zone 過濾，
+// 原本 zone 放行的其他服務不再適用於這個介面
+func (firewalld) bindZone(iface string) error {
+    if _, err := sudo.Run("firewall-cmd", "--permanent", "--info-zone="+Tag); err != nil {
+        if _, err := sudo.Run("firewall-cmd", "--permanent", "--new-zone="+Tag); err != nil {
+            return err
+        }
+    }
+    if _, err := sudo.Run("firewall-cmd", "--permanent", "--zone="+Tag, "--query-interface="+iface); err == nil {
+        return nil
+    }
+    _, err := sudo.Run("firewall-cmd", "--permanent", "--zone="+Tag, "--change-interface="+iface)
+    return err
+}
+
+func (f firewalld) Allow(rule Rule) error {
+    name := serviceName(rule)
+    if _, err := sudo.Run("firewall-cmd", "--permanent", "--info-service="+name); err != nil {
+        if _, err := sudo.Run("firewall-cmd", "--permanent", "--new-service="+name); err != nil {
+            return err
+        }
+        if _, err := sudo.Run("firewall-cmd", "--permanent", "--service="+name, fmt.Sprintf("--add-port=%d/%s", rule.Port, rule.Proto)); err != nil {
+            return err
+        }
+    }
+    if rule.Interface != "" {
+        if err := f.bindZone(rule.Interface); err != nil {
+            return err
+        }
+    }
+    query, add := "--query-service="+name, "--add-service="+name
+    if rule.Source != "" {
+        query, add = "--query-rich-rule="+richRule(rule), "--add-rich-rule="+richRule(rule)
+    }
+    if _, err := sudo.Run("firewall-cmd", append(zoneArgs(rule), query)...); err == nil {
+        return nil
+    }
+    if _, err := sudo.Run("firewall-cmd", append(zoneArgs(rule), add)...); err != nil {
+        return err
+    }
+    return f.reload()
+}
+
+func (f firewalld) Remove(rule Rule) error {
+    query, remove := "--query-service="+serviceName(rule), "--remove-service="+serviceName(rule)
+    if rule.Source != "" {
+        query, remove = "--query-rich-rule="+richRule(rule), "--remove-rich-rule="+richRule(rule)
+    }
+    if _, err := sudo.Run("firewall-cmd", append(zoneArgs(rule), query)...); err != nil {
+        return nil
+    }
+    if _, err := sudo.Run("firewall-cmd", append(zoneArgs(rule), remove)...); err != nil {
+        return err
+    }
+    return f.reload()
+}
+
+// Save 不需要做任何事：所有變更都以 --permanent 寫入後才 reload
+func (firewalld) Save() error { return nil }
+
+// RemoveAll 移除引用本工具 service 的 rich rule 與 zone 設定，刪除名為 Tag 的 zone，
+// 再刪除 service 本身
+func (f firewalld) RemoveAll() error {
+    if !f.Active() {
+        return nil
+    }
+    out, err := sudo.Run("firewall-cmd", "--permanent", "--list-rich-rules")
+    if err != nil {
+        return err
+    }
+    for _, rich := range strings.Split(string(out), "\n") {
+        if rich = strings.TrimSpace(rich); strings.Contains(rich, `service name="`+Tag+"-") {
+            if _, err := sudo.Run("firewall-cmd", "--permanent", "--remove-rich-rule="+rich); err != nil {
+                return err
+            }
+        }
+    }
+    for _, step := range []struct{ list, remove string }{
+        {"--list-services", "--remove-service="},
+        {"--get-zones", "--delete-zone="},
+        {"--get-services", "--delete-service="},
+    } {
+        out, err := sudo.Run("firewall-cmd", "--permanent", step.list)
+        if err != nil {
+            return err
+        }
+        for _, name := range strings.Fields(string(out)) {
+            if name == Tag || strings.HasPrefix(name, Tag+"-") {
+                if _, err := sudo.Run("firewall-cmd", "--permanent", step.remove+name); err != nil {
+                    return err
+                }
+            }
+        }
+    }
+    return f.reload()
+}
+
+func (firewalld) reload() error {
+    _, err := sudo.Run("firewall-cmd", "--reload")
+    return err
+}
@@ -0,0 +1,209 @@
+package firewall
+
+import (
+    "fmt"
+    "go-auto-proxy/internal/sudo"
+    "go-auto-proxy/internal/systemd"
+    "log"
+    "path/filepath"
+    "strconv"
+    "strings"
+)
+
+// tools 同時處理 IPv4 與 IPv6
+var tools = []string{"iptables", "ip6tables"}
+
+// iptables 直接在 INPUT 鏈插入帶有註解的規則
+type iptables struct{}
+
+func (iptables) Name() string { return "iptables" }
+
+// Active 在 INPUT 鏈預設丟棄，或含有非本工具新增的 DROP/REJECT 規則時成立
+func (iptables) Active() bool {
+    out, err := sudo.Run("iptables", "-S", "INPUT")
+    if err != nil {
+        return false
+    }
+    for _, line := range strings.Split(string(out), "\n") {
+        if strings.Contains(line, Tag) {
+            continue
+        }
+        if strings.HasPrefix(line, "-P INPUT DROP") || strings.Contains(line, "-j DROP") || strings.Contains(line, "-j REJECT") {
+            return true
+        }
+    }
+    return false
+}
+
+func (iptables) Allow(rule Rule) error {
+    for _, tool := range ruleTools(rule) {
+        spec := ruleSpec(rule)
+        if exists(tool, spec) {
+            continue
+        }
+        // 插入到最前面，避免被既有的 REJECT 規則擋住
+        if _, err := sudo.Run(tool, append([]string{"-I", "INPUT", "1"}, spec...)...); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+func (iptables) Remove(rule Rule) error {
+    for _, tool := range ruleTools(rule) {
+        spec := ruleSpec(rule)
+        if !exists(tool, spec) {
+            continue
+        }
+        if _, err := sudo.Run(tool, append([]string{"-D", "INPUT"}, spec...)...); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// RemoveAll 以 -S 列出 INPUT 鏈，刪除所有帶有 Tag 註解的規則，包含介面限制規則
+func (iptables) RemoveAll() error {
+    for _, tool := range tools {
+        out, err := sudo.Run(tool, "-S", "INPUT")
+        if err != nil {
+            continue
+        }
+        for _, line := range strings.Split(string(out), "\n") {
+            fields := strings.Fields(line)
+            if len(fields) < 2 || fields[0] != "-A" || !strings.Contains(line, "--comment "+Tag) {
+                continue
+            }
+            fields[0] = "-D"
+            if _, err := sudo.Run(tool, fields...); err != nil {
+                return err
+            }
+        }
+    }
+    return nil
+}
+
+// IPTablesSysconfigDir 是 iptables-services 開機時載入規則的目錄，可在測試中替換
+var IPTablesSysconfigDir = "/etc/sysconfig"
+
+// Save 直接插入的規則重開機後就會消失：Debian 系以 netfilter-persistent 保存，
+// RHEL 系寫入 iptables.service 載入的 /etc/sysconfig/iptables；兩者都沒有時返回錯誤
+func (iptables) Save() error {
+    if _, err := LookPath("netfilter-persistent"); err == nil {
+        _, err := sudo.Run("netfilter-persistent", "save")
+        return err
+    }
+    if !systemd.Exists("iptables") {
+        return fmt.Errorf("iptables rules cannot be persisted, install iptables-persistent (Debian/Ubuntu) or iptables-services (RHEL) and run 'go-auto-proxy firewall apply' again")
+    }
+    for _, tool := range tools {
+        out, err := sudo.Command(tool + "-save").Output()
+        if err != nil {
+            return fmt.Errorf("%s-save failed: %v", tool, err)
+        }
+        if err := sudo.WriteFile(filepath.Join(IPTablesSysconfigDir, tool), out, 0600); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// ruleTools 依來源位址的類型選擇 iptables 或 ip6tables
+func ruleTools(rule Rule) []string {
+    switch {
+    case rule.Source == "":
+        return tools
+    case rule.ipv6():
+        return []string{"ip6tables"}
+    }
+    return []string{"iptables"}
+}
+
+// ruleSpec 返回放行規則的參數
+func ruleSpec(rule Rule) []string {
+    var spec []string
+    if rule.Interface != "" {
+        spec = append(spec, "-i", rule.Interface)
+    }
+    spec = append(spec, "-p", rule.Proto)
+    if rule.Source != "" {
+        spec = append(spec, "-s", rule.Source)
+    }
+    return append(spec, "--dport", strconv.Itoa(rule.Port), "-m", "comment", "--comment", Tag, "-j", "ACCEPT")
+}
+
+// interfaceRules 返回只允許從 iface 與 loopback 存取 TCP 端口的規則，
+// 順序即在 INPUT 鏈中的順序
+func interfaceRules(port int, iface string) [][]string {
+    p := strconv.Itoa(port)
+    return [][]string{
+        {"-i", "lo", "-p", "tcp", "--dport", p, "-m", "comment", "--comment", Tag, "-j", "ACCEPT"},
+        {"!", "-i", iface, "-p", "tcp", "--dport", p, "-m", "comment", "--comment", Tag, "-j", "DROP"},
+    }
+}
+
+// restrictUnit 是開機時重新套用介面限制的 systemd 服務；直接插入的 iptables 規則不會自行保存
+const restrictUnit = "go-auto-proxy-restrict"
+
+// RestrictToInterface 讓 TCP 端口只能從指定介面（如 ZeroTier）存取，其餘介面一律丟棄；
+// 規則插入 INPUT 鏈最前面，已存在時不重複新增。ufw、firewalld 與 nftables 都會經過
+// netfilter，這些規則對所有防火牆都有效。另外安裝開機服務在防火牆載入後重新套用，
+// 重開機後仍然有效
+func RestrictToInterface(port int, iface string) error {
+    if iface == "" {
+        return fmt.Errorf("no interface given to restrict port %d to", port)
+    }
+    rules := interfaceRules(port, iface)
+    var commands []string
+    for _, tool := range tools {
+        // 反向插入到第 1 條，使最終順序與 rules 相同
+        for i := len(rules) - 1; i >= 0; i-- {
+            spec := strings.Join(rules[i], " ")
+            commands = append(commands, fmt.Sprintf("/bin/sh -c '%s -C INPUT %s || %s -I INPUT 1 %s'", tool, spec, tool, spec))
+            if exists(tool, rules[i]) {
+                continue
+            }
+            args := append([]string{"-I", "INPUT", "1"}, rules[i]...)
+            if _, err := sudo.Run(tool, args...); err != nil {
+                return fmt.Errorf("failed to add firewall rule: %v", err)
+            }
+        }
+    }
+    err := systemd.InstallBootTask(systemd.BootTask{
+        Name:        restrictUnit,
+        Description: fmt.Sprintf("go-auto-proxy: allow %d/tcp only via %s", port, iface),
+        // 防火牆服務載入時可能清空規則，必須在它們之後套用
+        After:     []string{"network-pre.target", "ufw.service", "firewalld.service", "nftables.service", "netfilter-persistent.service", "iptables.service", "ip6tables.service"},
+        ExecStart: commands,
+    })
+    if err != nil {
+        return fmt.Errorf("failed to persist the interface restriction: %v", err)
+    }
+    log.Printf("Port %d/tcp is now only reachable via %s.", port, iface)
+    return nil
+}
+
+// RemoveInterfaceRestriction 移除 RestrictToInterface 新增的規則與開機服務
+func RemoveInterfaceRestriction(port int, iface string) error {
+    for _, tool := range tools {
+        for _, rule := range interfaceRules(port, iface) {
+            if !exists(tool, rule) {
+                continue
+            }
+            if _, err := sudo.Run(tool, append([]string{"-D", "INPUT"}, rule...)...); err != nil {
+                return fmt.Errorf("failed to remove firewall rule: %v", err)
+            }
+        }
+    }
+    if err := systemd.RemoveService(restrictUnit); err != nil {
+        return fmt.Errorf("failed to remove %s.service: %v", restrictUnit, err)
+    }
+    log.Printf("Removed the interface restriction on port %d/tcp.", port)
+    return nil
+}
+
+// exists 以 -C 檢查規則是否已存在
+func exists(tool string, rule []string) bool {
+    _, err := sudo.Run(tool, append([]string{"-C", "INPUT"}, rule...)...)
+    return err == nil
+}
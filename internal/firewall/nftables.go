@@ -0,0 +1,246 @@
+package firewall
+
+import (
+    "fmt"
+    "go-auto-proxy/internal/sudo"
+    "os"
+    "path/filepath"
+    "regexp"
+    "strings"
+)
+
+var (
+    // NFTConf 是 nftables.service 開機時載入的配置，可在測試中替換
+    NFTConf = "/etc/nftables.conf"
+    // NFTRulesFile 保存本工具的規則，由 NFTConf 以 include 載入
+    NFTRulesFile = "/etc/nftables.d/go-auto-proxy.nft"
+)
+
+// nftables 在每條 input hook 的基礎鏈中插入帶有 comment 的規則；
+// 封包必須通過所有基礎鏈，因此每條鏈都要放行
+type nftables struct{}
+
+// nftChain 是一條掛在 input hook 上的基礎鏈
+type nftChain struct {
+    Family, Table, Name string
+}
+
+// nftRule 是鏈中的一條規則與其 handle
+type nftRule struct {
+    Text, Handle string
+}
+
+// nftHandle 解析 nft -a 輸出行尾的 "# handle 7"
+var nftHandle = regexp.MustCompile(`^(.*?)\s+# handle (\d+)$`)
+
+func (nftables) Name() string { return "nftables" }
+
+// Active 在任一 input 鏈預設丟棄或含有 drop/reject 規則時成立
+func (n nftables) Active() bool {
+    chains, err := n.chains()
+    if err != nil {
+        return false
+    }
+    for _, c := range chains {
+        out, err := sudo.Run("nft", "list", "chain", c.Family, c.Table, c.Name)
+        if err != nil {
+            continue
+        }
+        for _, line := range strings.Split(string(out), "\n") {
+            if strings.Contains(line, Tag) {
+                continue
+            }
+            if strings.Contains(line, "policy drop") || strings.HasSuffix(strings.TrimSpace(line), "drop") || strings.Contains(line, "reject") {
+                return true
+            }
+        }
+    }
+    return false
+}
+
+func (n nftables) Allow(rule Rule) error {
+    chains, err := n.chains()
+    if err != nil {
+        return err
+    }
+    if len(chains) == 0 {
+        return fmt.Errorf("no nftables chain hooked on input")
+    }
+    expr := nftExpr(rule)
+    for _, c := range chains {
+        if !c.accepts(rule) {
+            continue
+        }
+        rules, err := c.rules()
+        if err != nil {
+            return err
+        }
+        if containsRule(rules, expr) {
+            continue
+        }
+        args := append([]string{"insert", "rule", c.Family, c.Table, c.Name}, strings.Fields(expr)...)
+        if _, err := sudo.Run("nft", args...); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+func (n nftables) Remove(rule Rule) error {
+    expr := nftExpr(rule)
+    return n.delete(func(text string) bool { return text == expr })
+}
+
+func (n nftables) RemoveAll() error {
+    return n.delete(func(text string) bool { return strings.Contains(text, `comment "`+Tag+`"`) })
+}
+
+// delete 依 handle 刪除各 input 鏈中符合條件的規則
+func (n nftables) delete(match func(text string) bool) error {
+    chains, err := n.chains()
+    if err != nil {
+        return nil
+    }
+    for _, c := range chains {
+        rules, err := c.rules()
+        if err != nil {
+            return err
+        }
+        for _, r := range rules {
+            if !match(r.Text) {
+                continue
+            }
+            if _, err := sudo.Run("nft", "delete", "rule", c.Family, c.Table, c.Name, "handle", r.Handle); err != nil {
+                return err
+            }
+        }
+    }
+    return nil
+}
+
+// Save 將帶有 Tag 的規則寫入 NFTRulesFile，並確認 NFTConf 會 include 它；
+// nftables.service 重新載入 NFTConf 時會清空 ruleset，未保存的規則都會消失
+func (n nftables) Save() error {
+    conf, err := os.Stat(NFTConf)
+    if err != nil {
+        return fmt.Errorf("nftables rules cannot be persisted: %v", err)
+    }
+    chains, err := n.chains()
+    if err != nil {
+        return err
+    }
+    var buf strings.Builder
+    buf.WriteString("# Managed by go-auto-proxy\n")
+    for _, c := range chains {
+        rules, err := c.rules()
+        if err != nil {
+            return err
+        }
+        // 反向 insert，使載入後的順序與目前相同
+        for i := len(rules) - 1; i >= 0; i-- {
+            if strings.Contains(rules[i].Text, `comment "`+Tag+`"`) {
+                fmt.Fprintf(&buf, "insert rule %s %s %s %s\n", c.Family, c.Table, c.Name, rules[i].Text)
+            }
+        }
+    }
+    if _, err := sudo.Run("mkdir", "-p", filepath.Dir(NFTRulesFile)); err != nil {
+        return err
+    }
+    if err := sudo.WriteFile(NFTRulesFile, []byte(buf.String()), 0644); err != nil {
+        return err
+    }
+
+    data, err := os.ReadFile(NFTConf)
+    if err != nil {
+        return fmt.Errorf("nftables rules cannot be persisted: %v", err)
+    }
+    include := fmt.Sprintf("include %q", NFTRulesFile)
+    for _, line := range strings.Split(string(data), "\n") {
+        if strings.TrimSpace(line) == include {
+            return nil
+        }
+    }
+    // include 必須在最後，等 NFTConf 建立的表與鏈都存在後才插入規則
+    if len(data) > 0 && !strings.HasSuffix(string(data), "\n") {
+        data = append(data, '\n')
+    }
+    data = append(data, include+"\n"...)
+    return sudo.WriteFile(NFTConf, data, conf.Mode().Perm())
+}
+
+func (nftables) chains() ([]nftChain, error) {
+    out, err := sudo.Run("nft", "list", "chains")
+    if err != nil {
+        return nil, err
+    }
+    return parseNFTChains(string(out)), nil
+}
+
+// parseNFTChains 從 nft list chains 的輸出找出 input hook 的基礎鏈；
+// iptables-nft 建立的 INPUT 鏈由 iptables 後端處理，這裡略過
+func parseNFTChains(out string) []nftChain {
+    var chains []nftChain
+    var family, table, chain string
+    for _, line := range strings.Split(out, "\n") {
+        fields := strings.Fields(line)
+        switch {
+        case len(fields) >= 3 && fields[0] == "table":
+            family, table = fields[1], fields[2]
+        case len(fields) >= 2 && fields[0] == "chain":
+            chain = fields[1]
+        case strings.Contains(line, "hook input") && chain != "INPUT":
+            chains = append(chains, nftChain{Family: family, Table: table, Name: chain})
+        }
+    }
+    return chains
+}
+
+// accepts 判斷來源位址的類型是否適用於此鏈的 family
+func (c nftChain) accepts(rule Rule) bool {
+    switch {
+    case rule.Source == "":
+        return true
+    case rule.ipv6():
+        return c.Family != "ip"
+    }
+    return c.Family != "ip6"
+}
+
+// rules 依鏈中的順序返回每條規則的文字與 handle
+func (c nftChain) rules() ([]nftRule, error) {
+    out, err := sudo.Run("nft", "-a", "list", "chain", c.Family, c.Table, c.Name)
+    if err != nil {
+        return nil, err
+    }
+    var rules []nftRule
+    for _, line := range strings.Split(string(out), "\n") {
+        if m := nftHandle.FindStringSubmatch(strings.TrimSpace(line)); m != nil {
+            rules = append(rules, nftRule{Text: m[1], Handle: m[2]})
+        }
+    }
+    return rules, nil
+}
+
+func containsRule(rules []nftRule, expr string) bool {
+    for _, r := range rules {
+        if r.Text == expr {
+            return true
+        }
+    }
+    return false
+}
+
+// nftExpr 返回與 nft list 輸出一致的規則文字
+func nftExpr(rule Rule) string {
+    expr := fmt.Sprintf(`%s dport %d accept comment "%s"`, rule.Proto, rule.Port, Tag)
+    if rule.Interface != "" {
+        expr = fmt.Sprintf(`iifname "%s" %s`, rule.Interface, expr)
+    }
+    switch {
+    case rule.Source == "":
+        return expr
+    case rule.ipv6():
+        return "ip6 saddr " + rule.Source + " " + expr
+    }
+    return "ip saddr " + rule.Source + " " + expr
+}
@@ -0,0 +1,123 @@
+package firewall
+
+import (
+    "go-auto-proxy/internal/sudo"
+    "regexp"
+    "sort"
+    "strconv"
+    "strings"
+)
+
+// ufw 以 comment 標記規則，刪除時依 ufw status numbered 的編號進行
+type ufw struct{}
+
+// ufwEntry 是 ufw status numbered 的一行
+type ufwEntry struct {
+    Num       int
+    To        string // 如 443/tcp
+    Interface string // "on zt0" 中的介面，沒有限制介面時為空
+    From      string // Anywhere 或來源位址
+    Comment   string
+}
+
+// ufwLine 解析形如 "[ 3] 443/tcp (v6) on zt0   ALLOW IN    Anywhere (v6)   # go-auto-proxy" 的行
+var ufwLine = regexp.MustCompile(`^\[\s*(\d+)\]\s+(\S+)(?:\s+\(v6\))?(?:\s+on\s+(\S+))?(?:\s+\(v6\))?\s+ALLOW(?:\s+IN)?\s+(\S+)(?:\s+\(v6\))?(?:\s+#\s*(.*))?$`)
+
+func (ufw) Name() string { return "ufw" }
+
+func (ufw) Active() bool {
+    out, err := sudo.Run("ufw", "status")
+    return err == nil && strings.Contains(string(out), "Status: active")
+}
+
+func (u ufw) Allow(rule Rule) error {
+    entries, err := u.list()
+    if err != nil {
+        return err
+    }
+    // 已有相同的規則（即使不是本工具新增的）時不再新增，以免解除安裝時誤刪使用者的規則
+    for _, e := range entries {
+        if e.matches(rule) {
+            return nil
+        }
+    }
+    args := []string{"allow"}
+    if rule.Interface != "" {
+        args = append(args, "in", "on", rule.Interface)
+    }
+    if rule.Source == "" && rule.Interface == "" {
+        args = append(args, strconv.Itoa(rule.Port)+"/"+rule.Proto)
+    } else {
+        args = append(args, "proto", rule.Proto)
+        if rule.Source != "" {
+            args = append(args, "from", rule.Source)
+        }
+        args = append(args, "to", "any", "port", strconv.Itoa(rule.Port))
+    }
+    _, err = sudo.Run("ufw", append(args, "comment", Tag)...)
+    return err
+}
+
+// Save 不需要做任何事：ufw 新增規則時已寫入 /etc/ufw/user.rules
+func (ufw) Save() error { return nil }
+
+func (u ufw) Remove(rule Rule) error {
+    return u.delete(func(e ufwEntry) bool { return e.matches(rule) })
+}
+
+func (u ufw) RemoveAll() error {
+    return u.delete(func(e ufwEntry) bool { return true })
+}
+
+// delete 刪除符合條件且帶有 Tag 的規則；由大到小刪除，避免編號位移
+func (u ufw) delete(match func(ufwEntry) bool) error {
+    entries, err := u.list()
+    if err != nil {
+        return err
+    }
+    var nums []int
+    for _, e := range entries {
+        if e.Comment == Tag && match(e) {
+            nums = append(nums, e.Num)
+        }
+    }
+    sort.Sort(sort.Reverse(sort.IntSlice(nums)))
+    for _, n := range nums {
+        if _, err := sudo.Run("ufw", "--force", "delete", strconv.Itoa(n)); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+func (ufw) list() ([]ufwEntry, error) {
+    out, err := sudo.Run("ufw", "status", "numbered")
+    if err != nil {
+        return nil, err
+    }
+    return parseUFWStatus(string(out)), nil
+}
+
+// parseUFWStatus 解析 ufw status numbered 的輸出
+func parseUFWStatus(out string) []ufwEntry {
+    var entries []ufwEntry
+    for _, line := range strings.Split(out, "\n") {
+        m := ufwLine.FindStringSubmatch(strings.TrimSpace(line))
+        if m == nil {
+            continue
+        }
+        num, _ := strconv.Atoi(m[1])
+        entries = append(entries, ufwEntry{Num: num, To: m[2], Interface: m[3], From: m[4], Comment: strings.TrimSpace(m[5])})
+    }
+    return entries
+}
+
+func (e ufwEntry) matches(rule Rule) bool {
+    if e.To != strconv.Itoa(rule.Port)+"/"+rule.Proto || e.Interface != rule.Interface {
+        return false
+    }
+    if rule.Source == "" {
+        return e.From == "Anywhere"
+    }
+    return e.From == rule.Source
+}
@@ -0,0 +1,84 @@
+package installer
+
+import (
+    "context"
+    "fmt"
+    "go-auto-proxy/internal/logging"
+    "go-auto-proxy/internal/system"
+    "log"
+    "time"
+)
+
+// DpkgLockHolder 找出持有 apt/dpkg 鎖的程序，測試時可替換
+var DpkgLockHolder = system.DpkgLockHolder
+
+var (
+    // lockPollInterval 為檢查鎖是否釋放的間隔
+    lockPollInterval = 2 * time.Second
+    // lockLogInterval 為非終端環境中記錄剩餘時間的間隔
+    lockLogInterval = 30 * time.Second
+)
+
+// LockError 表示等待 dpkg 鎖逾時
+type LockError struct {
+    Holder system.LockHolder
+    Waited time.Duration
+}
+
+func (e *LockError) Error() string {
+    return fmt.Sprintf("%s is still locked by %s after waiting %v (let it finish, or raise install.<step>.lock_wait in config.json)", e.Holder.Path, e.Holder, e.Waited)
+}
+
+// waitForDpkgLock 在 dpkg 鎖被佔用時等待釋放，終端上顯示倒數；
+// 無法讀取 /proc/locks 時不等待，交由 apt 自行報錯
+func waitForDpkgLock(ctx context.Context, limit time.Duration) error {
+    holder, err := DpkgLockHolder()
+    if err != nil || holder == nil {
+        return nil
+    }
+    log.Printf("Warning: %s is locked by %s, waiting up to %v for it to finish...", holder.Path, holder, limit)
+    start := time.Now()
+    progress := logging.StartProgress(lockLabel(holder, limit))
+    defer progress.Stop()
+
+    ticker := time.NewTicker(lockPollInterval)
+    defer ticker.Stop()
+    lastLog := start
+    for {
+        select {
+        case <-ctx.Done():
+            return ctx.Err()
+        case <-ticker.C:
+        }
+        current, err := DpkgLockHolder()
+        if err != nil || current == nil {
+            log.Printf("dpkg lock released after %v.", time.Since(start).Round(time.Second))
+            return nil
+        }
+        holder = current
+        remaining := limit - time.Since(start)
+        if remaining <= 0 {
+            return &LockError{Holder: *holder, Waited: limit}
+        }
+        progress.SetLabel(lockLabel(holder, remaining))
+        if time.Since(lastLog) >= lockLogInterval {
+            log.Printf("Still waiting for %s, %v left...", holder, remaining.Round(time.Second))
+            lastLog = time.Now()
+        }
+    }
+}
+
+func lockLabel(holder *system.LockHolder, remaining time.Duration) string {
+    return fmt.Sprintf("waiting for the dpkg lock held by %s, %v left", holder, remaining.Round(time.Second))
+}
+
+// runApt 以 sudo 執行 apt；因 dpkg 鎖失敗時在錯誤中指出持有鎖的程序
+func runApt(ctx context.Context, args ...string) error {
+    err := runCommand(ctx, "sudo", append([]string{"apt"}, args...)...)
+    if err != nil && dpkgLocked(err) {
+        if holder, _ := DpkgLockHolder(); holder != nil {
+            return fmt.Errorf("apt could not get the dpkg lock held by %s: %w", holder, err)
+        }
+    }
+    return err
+}
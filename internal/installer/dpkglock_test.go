@@ -0,0 +1,81 @@
+package installer
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "go-auto-proxy/internal/system"
+    "os/exec"
+    "testing"
+    "time"
+
+    "github.com/stretchr/testify/assert"
+)
+
+var unattendedUpgrades = &system.LockHolder{Path: "/var/lib/dpkg/lock-frontend", PID: 1234, Name: "unattended-upgr", Command: "/usr/bin/python3 /usr/bin/unattended-upgrade"}
+
+// fakeLockHolder 讓鎖在前 held 次檢查中被佔用
+func fakeLockHolder(t *testing.T, held int) *int {
+    checks := 0
+    originalHolder, originalPoll := DpkgLockHolder, lockPollInterval
+    DpkgLockHolder = func() (*system.LockHolder, error) {
+        checks++
+        if checks <= held {
+            return unattendedUpgrades, nil
+        }
+        return nil, nil
+    }
+    lockPollInterval = time.Millisecond
+    t.Cleanup(func() { DpkgLockHolder, lockPollInterval = originalHolder, originalPoll })
+    return &checks
+}
+
+func TestWaitForDpkgLockReleased(t *testing.T) {
+    checks := fakeLockHolder(t, 3)
+    assert.NoError(t, waitForDpkgLock(context.Background(), time.Minute))
+    assert.Equal(t, 4, *checks)
+}
+
+func TestWaitForDpkgLockTimesOut(t *testing.T) {
+    fakeLockHolder(t, 1<<30)
+    err := waitForDpkgLock(context.Background(), 20*time.Millisecond)
+
+    var lockErr *LockError
+    assert.True(t, errors.As(err, &lockErr))
+    assert.Equal(t, 1234, lockErr.Holder.PID)
+    assert.Contains(t, err.Error(), "/var/lib/dpkg/lock-frontend is still locked by unattended-upgr (pid 1234: /usr/bin/python3 /usr/bin/unattended-upgrade)")
+}
+
+func TestWaitForDpkgLockCanceled(t *testing.T) {
+    fakeLockHolder(t, 1<<30)
+    ctx, cancel := context.WithCancel(context.Background())
+    time.AfterFunc(10*time.Millisecond, cancel)
+    assert.ErrorIs(t, waitForDpkgLock(ctx, time.Minute), context.Canceled)
+}
+
+func TestRunWithPolicyDoesNotRetryAfterLockTimeout(t *testing.T) {
+    fakeLockHolder(t, 1<<30)
+    calls := 0
+    policy := Policy{Timeout: time.Second, Attempts: 3, Retry: dpkgLocked, LockWait: 10 * time.Millisecond}
+    attempts, err := runWithPolicy(context.Background(), "nginx", policy, func(ctx context.Context) error {
+        calls++
+        return nil
+    })
+    var lockErr *LockError
+    assert.ErrorAs(t, err, &lockErr)
+    assert.Equal(t, 0, attempts)
+    assert.Equal(t, 0, calls)
+}
+
+func TestRunAptNamesLockHolder(t *testing.T) {
+    // 執行 apt 時才被搶走鎖：檢查時已被佔用
+    fakeLockHolder(t, 1<<30)
+    DefaultCommand = func(command string, args ...string) *exec.Cmd {
+        return exec.Command("sh", "-c", fmt.Sprintf("echo '%s'; exit 100", "E: Could not get lock /var/lib/dpkg/lock-frontend. It is held by process 1234 (unattended-upgr)"))
+    }
+    defer func() { DefaultCommand = exec.Command }()
+
+    err := runApt(context.Background(), "install", "-y", "nginx")
+    assert.ErrorContains(t, err, "apt could not get the dpkg lock held by unattended-upgr (pid 1234")
+    assert.True(t, dpkgLocked(err))
+}
@@ -4,6 +4,8 @@ import (
     "bytes"
     "context"
     "fmt"
+    "go-auto-proxy/internal/logging"
+    "go-auto-proxy/internal/system"
     "log"
     "os"
     "os/exec"
@@ -12,10 +14,7 @@ import (
     "time"
 )
 
-const (
-    bashrcPath = ".bashrc"
-    commandTimeout = 5 * time.Minute // 設置 5 分鐘超時
-)
+const bashrcPath = ".bashrc"
 
 // CommandFunc 定義執行命令的函數類型
 type CommandFunc func(string, ...string) *exec.Cmd
@@ -35,17 +34,129 @@ var DefaultMkdirAll MkdirAllFunc = os.MkdirAll
 // DefaultRemove 使用標準的 os.Remove
 var DefaultRemove RemoveFunc = os.Remove
 
-// InstallDependencies 使用指定的 CommandFunc 安裝依賴
-func InstallDependencies() error {
+// Options 控制要安裝的元件
+type Options struct {
+    // SkipAcmeSH 使用內建 ACME 客戶端時不安裝 acme.sh
+    SkipAcmeSH bool
+    // Completed 為先前已完成的步驟（init --resume），不再重複執行
+    Completed func(step string) bool
+    // OnStepDone 在每個步驟完成後呼叫，用於記錄進度
+    OnStepDone func(step string)
+    // Overrides 覆寫各步驟的超時與重試策略，來自 config.json 的 install
+    Overrides map[string]system.StepOverride
+}
+
+// 步驟結果的狀態
+const (
+    StatusInstalled = "installed"
+    StatusSkipped   = "skipped"
+    StatusFailed    = "failed"
+)
+
+// StepResult 是一個安裝步驟的結果
+type StepResult struct {
+    Step     string
+    Status   string
+    Attempts int
+    Duration time.Duration
+    Err      error
+}
+
+// step 是一個可單獨追蹤的安裝步驟，日誌會標記為 install/<name>
+type step struct {
+    name string
+    run  func(ctx context.Context) error
+}
+
+// StepError 表示某個安裝步驟失敗或被中斷
+type StepError struct {
+    Step string
+    Err  error
+}
+
+func (e *StepError) Error() string {
+    return fmt.Sprintf("%s: %v", e.Step, e.Err)
+}
+
+func (e *StepError) Unwrap() error {
+    return e.Err
+}
+
+// InstallDependencies 使用指定的 CommandFunc 安裝依賴；每個步驟依 Policy 超時與重試，
+// ctx 取消時終止執行中的命令。返回到停止為止各步驟的結果，錯誤為 *StepError
+func InstallDependencies(ctx context.Context, opts Options) ([]StepResult, error) {
+    steps := []step{
+        {"apt", installBasePackages},
+        {"trojan-go", installTrojanGo},
+        {"acme.sh", installAcmeSH},
+        {"nginx", aptInstall("nginx")},
+        {"fail2ban", aptInstall("fail2ban")},
+        {"zerotier", installZeroTier},
+    }
+    policies := make(map[string]Policy, len(steps))
+    for _, s := range steps {
+        policy, err := policyFor(s.name, opts.Overrides)
+        if err != nil {
+            return nil, err
+        }
+        policies[s.name] = policy
+    }
+
+    var results []StepResult
+    for _, s := range steps {
+        if s.name == "acme.sh" && opts.SkipAcmeSH {
+            log.Println("Using the native ACME client, skipping acme.sh installation.")
+            results = append(results, StepResult{Step: s.name, Status: StatusSkipped})
+            continue
+        }
+        if opts.Completed != nil && opts.Completed(s.name) {
+            log.Printf("Step %s already completed, skipping.", s.name)
+            results = append(results, StepResult{Step: s.name, Status: StatusSkipped})
+            continue
+        }
+        if err := ctx.Err(); err != nil {
+            return results, &StepError{Step: s.name, Err: err}
+        }
+        done := logging.Step("install/" + s.name)
+        start := time.Now()
+        attempts, err := runWithPolicy(ctx, s.name, policies[s.name], s.run)
+        done()
+        result := StepResult{Step: s.name, Status: StatusInstalled, Attempts: attempts, Duration: time.Since(start)}
+        if err != nil {
+            result.Status = StatusFailed
+            result.Err = err
+            return append(results, result), &StepError{Step: s.name, Err: err}
+        }
+        results = append(results, result)
+        if opts.OnStepDone != nil {
+            opts.OnStepDone(s.name)
+        }
+    }
+
+    log.Println("Dependencies installed successfully.")
+    return results, nil
+}
+
+// installBasePackages 更新套件索引並安裝 unzip
+func installBasePackages(ctx context.Context) error {
     log.Println("Updating package index...")
-    if err := runCommand("sudo", "apt", "update"); err != nil {
+    if err := runApt(ctx, "update"); err != nil {
         return err
     }
     log.Println("Installing unzip...")
-    if err := runCommand("sudo", "apt", "install", "-y", "unzip"); err != nil {
-        return err
+    return runApt(ctx, "install", "-y", "unzip")
+}
+
+// aptInstall 返回以 apt 安裝單一套件的步驟
+func aptInstall(pkg string) func(ctx context.Context) error {
+    return func(ctx context.Context) error {
+        log.Printf("Installing %s...", pkg)
+        return runApt(ctx, "install", "-y", pkg)
     }
+}
 
+// installTrojanGo 下載並解壓 trojan-go
+func installTrojanGo(ctx context.Context) error {
     trojanDir := "trojan-go"
     log.Println("Creating trojan-go directory...")
     if err := DefaultMkdirAll(trojanDir, 0755); err != nil {
@@ -53,20 +164,21 @@ func InstallDependencies() error {
     }
     zipPath := filepath.Join(trojanDir, "trojan-go-linux-amd64.zip")
     log.Println("Downloading trojan-go...")
-    if err := runCommand("wget", "-O", zipPath, "https://github.com/p4gefau1t/trojan-go/releases/download/v0.10.6/trojan-go-linux-amd64.zip"); err != nil {
+    if err := runCommand(ctx, "wget", "-O", zipPath, "https://github.com/p4gefau1t/trojan-go/releases/download/v0.10.6/trojan-go-linux-amd64.zip"); err != nil {
         return err
     }
     log.Println("Unzipping trojan-go...")
-    if err := runCommand("unzip", zipPath, "-d", trojanDir); err != nil {
+    if err := runCommand(ctx, "unzip", "-o", zipPath, "-d", trojanDir); err != nil {
         return err
     }
     log.Println("Removing trojan-go zip file...")
-    if err := DefaultRemove(zipPath); err != nil {
-        return err
-    }
+    return DefaultRemove(zipPath)
+}
 
+// installAcmeSH 安裝 acme.sh 並設定 alias
+func installAcmeSH(ctx context.Context) error {
     log.Println("Installing acme.sh...")
-    if err := runCommand("sh", "-c", "curl https://get.acme.sh | sh"); err != nil {
+    if err := runCommand(ctx, "sh", "-c", "curl https://get.acme.sh | sh"); err != nil {
         return err
     }
     log.Println("Setting alias for acme.sh...")
@@ -82,73 +194,18 @@ func InstallDependencies() error {
     }
     log.Printf("Alias added to %s: %s", bashrc, aliasCmd)
     log.Println("Please run 'source ~/.bashrc' or restart your shell to apply the alias.")
-
-    log.Println("Installing nginx...")
-    if err := runCommand("sudo", "apt", "install", "-y", "nginx"); err != nil {
-        return err
-    }
-
-    log.Println("Installing fail2ban...")
-    if err := runCommand("sudo", "apt", "install", "-y", "fail2ban"); err != nil {
-        return err
-    }
-
-    log.Println("Installing ZeroTier...")
-    if err := installZeroTier(); err != nil {
-        return err
-    }
-
-    log.Println("Dependencies installed successfully.")
     return nil
 }
 
-// runCommand 執行命令並記錄輸出，帶超時
-func runCommand(name string, args ...string) error {
-    ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
-    defer cancel()
-
-    cmd := DefaultCommand(name, args...)
-    cmd.Env = os.Environ() // 繼承環境變數
-    var out bytes.Buffer
-    cmd.Stdout = &out
-    cmd.Stderr = &out
-
-    if err := cmd.Start(); err != nil {
-        return fmt.Errorf("failed to start command: %v", err)
-    }
-
-    errChan := make(chan error, 1)
-    go func() {
-        errChan <- cmd.Wait()
-    }()
-
-    select {
-    case err := <-errChan:
-        if err != nil {
-            log.Printf("Command failed: %s %v\nOutput: %s", name, args, out.String())
-            return fmt.Errorf("command failed: %v", err)
-        }
-        log.Printf("Command output: %s", out.String())
-        return nil
-    case <-ctx.Done():
-        cmd.Process.Kill() // 超時後殺死進程
-        log.Printf("Command timed out after %v: %s %v\nPartial output: %s", commandTimeout, name, args, out.String())
-        return fmt.Errorf("command timed out after %v", commandTimeout)
-    }
-}
-
-// installZeroTier 使用官方推薦方式安裝 ZeroTier
-func installZeroTier() error {
+// installZeroTier 使用官方推薦方式安裝 ZeroTier；"zerotier" 策略在任何錯誤時重試，
+// 每一步都必須可以重複執行
+func installZeroTier(ctx context.Context) error {
     log.Println("Adding ZeroTier GPG key...")
-    cmd := exec.Command("sh", "-c", "curl -s https://raw.githubusercontent.com/zerotier/ZeroTierOne/master/doc/contact@zerotier.com.gpg | sudo gpg --dearmor -o /usr/share/keyrings/zerotier.gpg")
-    var out bytes.Buffer
-    cmd.Stdout = &out
-    cmd.Stderr = &out
-    if err := cmd.Run(); err != nil {
-        log.Printf("Command output: %s", out.String())
+    // --batch --yes 讓 gpg 覆寫上一次嘗試留下的金鑰檔，否則重試時會因檔案已存在而失敗
+    if err := runCommand(ctx, "sh", "-c", "curl -s https://raw.githubusercontent.com/zerotier/ZeroTierOne/master/doc/contact@zerotier.com.gpg | sudo gpg --batch --yes --dearmor -o /usr/share/keyrings/zerotier.gpg"); err != nil {
         return fmt.Errorf("failed to add ZeroTier GPG key: %v", err)
     }
-    log.Printf("GPG key added: %s", out.String())
+    log.Println("GPG key added.")
 
     sourceLine := "deb [signed-by=/usr/share/keyrings/zerotier.gpg] http://download.zerotier.com/debian/jammy jammy main"
     log.Println("Adding ZeroTier repository...")
@@ -157,11 +214,11 @@ func installZeroTier() error {
     }
 
     log.Println("Updating package index for ZeroTier...")
-    if err := runCommand("sudo", "apt", "update"); err != nil {
+    if err := runApt(ctx, "update"); err != nil {
         return fmt.Errorf("failed to update package index for ZeroTier: %v", err)
     }
     log.Println("Installing zerotier-one...")
-    if err := runCommand("sudo", "apt", "install", "-y", "zerotier-one"); err != nil {
+    if err := runApt(ctx, "install", "-y", "zerotier-one"); err != nil {
         return fmt.Errorf("failed to install zerotier-one: %v", err)
     }
 
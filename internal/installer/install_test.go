@@ -1,9 +1,12 @@
 package installer
 
 import (
+    "context"
     "os"
     "os/exec"
+    "strings"
     "testing"
+    "time"
 
     "github.com/stretchr/testify/assert"
 )
@@ -22,20 +25,23 @@ func TestInstallDependenciesSuccess(t *testing.T) {
     originalCommand := DefaultCommand
     originalMkdirAll := DefaultMkdirAll
     originalRemove := DefaultRemove
+    originalSleep := backoffSleep
 
     // 替換為 mock
     DefaultCommand = mockExecCommand
     DefaultMkdirAll = func(path string, perm os.FileMode) error { return nil }
     DefaultRemove = func(path string) error { return nil }
+    backoffSleep = func(ctx context.Context, d time.Duration) error { return nil }
 
     defer func() {
         DefaultCommand = originalCommand
         DefaultMkdirAll = originalMkdirAll
         DefaultRemove = originalRemove
+        backoffSleep = originalSleep
     }()
 
     // 執行並驗證
-    err := InstallDependencies()
+    _, err := InstallDependencies(context.Background(), Options{})
     assert.NoError(t, err, "InstallDependencies should succeed with mock commands")
 }
 
@@ -46,11 +52,63 @@ func TestInstallDependenciesFailure(t *testing.T) {
     }
     defer func() { DefaultCommand = exec.Command }()
 
-    err := InstallDependencies()
+    _, err := InstallDependencies(context.Background(), Options{})
     assert.Error(t, err, "InstallDependencies should fail with mock failure")
     assert.Contains(t, err.Error(), "failed", "Error message should indicate failure")
 }
 
+func TestInstallDependenciesSkipsCompletedSteps(t *testing.T) {
+    var commands []string
+    DefaultCommand = func(command string, args ...string) *exec.Cmd {
+        commands = append(commands, command+" "+strings.Join(args, " "))
+        return exec.Command("false")
+    }
+    defer func() { DefaultCommand = exec.Command }()
+    originalMkdirAll := DefaultMkdirAll
+    originalRemove := DefaultRemove
+    DefaultMkdirAll = func(path string, perm os.FileMode) error { return nil }
+    DefaultRemove = func(path string) error { return nil }
+    defer func() {
+        DefaultMkdirAll = originalMkdirAll
+        DefaultRemove = originalRemove
+    }()
+
+    completed := map[string]bool{"apt": true, "trojan-go": true}
+    var done []string
+    results, err := InstallDependencies(context.Background(), Options{
+        SkipAcmeSH: true,
+        Completed:  func(step string) bool { return completed[step] },
+        OnStepDone: func(step string) { done = append(done, step) },
+    })
+
+    var stepErr *StepError
+    assert.ErrorAs(t, err, &stepErr)
+    assert.Equal(t, "nginx", stepErr.Step)
+    assert.Equal(t, []string{"sudo apt install -y nginx"}, commands)
+    assert.Empty(t, done)
+    assert.Equal(t, []string{StatusSkipped, StatusSkipped, StatusSkipped, StatusFailed}, statuses(results))
+    assert.Equal(t, 1, results[3].Attempts)
+}
+
+func TestInstallDependenciesCanceled(t *testing.T) {
+    ctx, cancel := context.WithCancel(context.Background())
+    cancel()
+
+    _, err := InstallDependencies(ctx, Options{})
+    var stepErr *StepError
+    assert.ErrorAs(t, err, &stepErr)
+    assert.Equal(t, "apt", stepErr.Step)
+    assert.ErrorIs(t, err, context.Canceled)
+}
+
+func statuses(results []StepResult) []string {
+    var out []string
+    for _, r := range results {
+        out = append(out, r.Status)
+    }
+    return out
+}
+
 // TestHelperProcess 模擬命令執行
 func TestHelperProcess(t *testing.T) {
     if os.Getenv("GO_WANT_HELPER_PROCESS") != "1" {
@@ -0,0 +1,142 @@
+package installer
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "go-auto-proxy/internal/system"
+    "log"
+    "strings"
+    "time"
+)
+
+// maxBackoff 是重試間隔的上限
+const maxBackoff = 2 * time.Minute
+
+// Policy 控制安裝步驟每次嘗試的超時與失敗後的重試
+type Policy struct {
+    // Timeout 為單次嘗試的時間上限
+    Timeout time.Duration
+    // Attempts 為總嘗試次數，至少 1
+    Attempts int
+    // Backoff 為第一次重試前的等待，之後每次加倍，最多 maxBackoff
+    Backoff time.Duration
+    // Retry 判斷錯誤是否值得重試；nil 表示所有錯誤都重試
+    Retry func(error) bool
+    // LockWait 為每次嘗試前等待 dpkg 鎖釋放的上限，不計入 Timeout；0 表示不等待
+    LockWait time.Duration
+}
+
+// DefaultPolicies 是各步驟的預設策略：下載類步驟任何錯誤都重試，
+// apt 類步驟只在 dpkg 鎖被佔用時重試
+var DefaultPolicies = map[string]Policy{
+    "apt":       {Timeout: 10 * time.Minute, Attempts: 3, Backoff: 15 * time.Second, Retry: dpkgLocked, LockWait: 10 * time.Minute},
+    "trojan-go": {Timeout: 5 * time.Minute, Attempts: 3, Backoff: 5 * time.Second},
+    "acme.sh":   {Timeout: 5 * time.Minute, Attempts: 3, Backoff: 5 * time.Second},
+    "nginx":     {Timeout: 10 * time.Minute, Attempts: 3, Backoff: 15 * time.Second, Retry: dpkgLocked, LockWait: 10 * time.Minute},
+    "fail2ban":  {Timeout: 10 * time.Minute, Attempts: 3, Backoff: 15 * time.Second, Retry: dpkgLocked, LockWait: 10 * time.Minute},
+    "zerotier":  {Timeout: 10 * time.Minute, Attempts: 3, Backoff: 10 * time.Second, LockWait: 10 * time.Minute},
+}
+
+// defaultPolicy 用於沒有列在 DefaultPolicies 的步驟
+var defaultPolicy = Policy{Timeout: 5 * time.Minute, Attempts: 1}
+
+// backoffSleep 等待重試間隔，ctx 取消時提前返回；測試時可替換
+var backoffSleep = func(ctx context.Context, d time.Duration) error {
+    timer := time.NewTimer(d)
+    defer timer.Stop()
+    select {
+    case <-ctx.Done():
+        return ctx.Err()
+    case <-timer.C:
+        return nil
+    }
+}
+
+// policyFor 返回步驟的策略並套用覆寫
+func policyFor(step string, overrides map[string]system.StepOverride) (Policy, error) {
+    policy, ok := DefaultPolicies[step]
+    if !ok {
+        policy = defaultPolicy
+    }
+    override, ok := overrides[step]
+    if !ok {
+        return policy, nil
+    }
+    if override.Timeout != "" {
+        d, err := time.ParseDuration(override.Timeout)
+        if err != nil || d <= 0 {
+            return policy, fmt.Errorf("invalid install.%s.timeout %q", step, override.Timeout)
+        }
+        policy.Timeout = d
+    }
+    if override.Backoff != "" {
+        d, err := time.ParseDuration(override.Backoff)
+        if err != nil || d < 0 {
+            return policy, fmt.Errorf("invalid install.%s.backoff %q", step, override.Backoff)
+        }
+        policy.Backoff = d
+    }
+    if override.LockWait != "" {
+        d, err := time.ParseDuration(override.LockWait)
+        if err != nil || d < 0 {
+            return policy, fmt.Errorf("invalid install.%s.lock_wait %q", step, override.LockWait)
+        }
+        policy.LockWait = d
+    }
+    if override.Attempts < 0 {
+        return policy, fmt.Errorf("invalid install.%s.attempts %d", step, override.Attempts)
+    }
+    if override.Attempts > 0 {
+        policy.Attempts = override.Attempts
+    }
+    return policy, nil
+}
+
+// runWithPolicy 依策略執行步驟，返回實際嘗試次數；ctx 取消或等待 dpkg 鎖逾時時不再重試
+func runWithPolicy(ctx context.Context, name string, policy Policy, run func(ctx context.Context) error) (int, error) {
+    attempts := policy.Attempts
+    if attempts < 1 {
+        attempts = 1
+    }
+    backoff := policy.Backoff
+    for attempt := 1; ; attempt++ {
+        if policy.LockWait > 0 {
+            if err := waitForDpkgLock(ctx, policy.LockWait); err != nil {
+                return attempt - 1, err
+            }
+        }
+        attemptCtx, cancel := context.WithTimeout(ctx, policy.Timeout)
+        err := run(attemptCtx)
+        timedOut := errors.Is(attemptCtx.Err(), context.DeadlineExceeded) && ctx.Err() == nil
+        cancel()
+        if err == nil {
+            return attempt, nil
+        }
+        if timedOut {
+            err = fmt.Errorf("timed out after %v: %w", policy.Timeout, err)
+        }
+        if ctx.Err() != nil || attempt >= attempts || (policy.Retry != nil && !policy.Retry(err)) {
+            return attempt, err
+        }
+        log.Printf("Warning: %s attempt %d/%d failed, retrying in %v: %v", name, attempt, attempts, backoff, firstLine(err))
+        if err := backoffSleep(ctx, backoff); err != nil {
+            return attempt, err
+        }
+        backoff *= 2
+        if backoff > maxBackoff {
+            backoff = maxBackoff
+        }
+    }
+}
+
+// dpkgLocked 判斷 apt 是否因 dpkg 鎖被其他程序（如 unattended-upgrades）佔用而失敗
+func dpkgLocked(err error) bool {
+    msg := err.Error()
+    return strings.Contains(msg, "Could not get lock") || strings.Contains(msg, "Unable to acquire the dpkg frontend lock")
+}
+
+func firstLine(err error) string {
+    line, _, _ := strings.Cut(err.Error(), "\n")
+    return line
+}
@@ -0,0 +1,117 @@
+package installer
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "go-auto-proxy/internal/system"
+    "os/exec"
+    "testing"
+    "time"
+
+    "github.com/stretchr/testify/assert"
+    "github.com/stretchr/testify/require"
+)
+
+// recordSleeps 以記錄取代實際等待
+func recordSleeps(t *testing.T) *[]time.Duration {
+    var sleeps []time.Duration
+    original := backoffSleep
+    backoffSleep = func(ctx context.Context, d time.Duration) error {
+        sleeps = append(sleeps, d)
+        return ctx.Err()
+    }
+    t.Cleanup(func() { backoffSleep = original })
+    return &sleeps
+}
+
+func TestRunWithPolicyRetriesWithBackoff(t *testing.T) {
+    sleeps := recordSleeps(t)
+    calls := 0
+    policy := Policy{Timeout: time.Second, Attempts: 4, Backoff: 40 * time.Second}
+
+    attempts, err := runWithPolicy(context.Background(), "trojan-go", policy, func(ctx context.Context) error {
+        calls++
+        if calls < 4 {
+            return fmt.Errorf("download failed")
+        }
+        return nil
+    })
+
+    assert.NoError(t, err)
+    assert.Equal(t, 4, attempts)
+    assert.Equal(t, []time.Duration{40 * time.Second, 80 * time.Second, maxBackoff}, *sleeps)
+}
+
+func TestRunWithPolicyRetryFilter(t *testing.T) {
+    sleeps := recordSleeps(t)
+    policy := Policy{Timeout: time.Second, Attempts: 3, Backoff: time.Second, Retry: dpkgLocked}
+
+    attempts, err := runWithPolicy(context.Background(), "nginx", policy, func(ctx context.Context) error {
+        return fmt.Errorf("command failed: sudo apt install -y nginx: exit status 100")
+    })
+    assert.Error(t, err)
+    assert.Equal(t, 1, attempts)
+    assert.Empty(t, *sleeps)
+
+    attempts, err = runWithPolicy(context.Background(), "nginx", policy, func(ctx context.Context) error {
+        return fmt.Errorf("E: Could not get lock /var/lib/dpkg/lock-frontend. It is held by process 1234 (unattended-upgr)")
+    })
+    assert.Error(t, err)
+    assert.Equal(t, 3, attempts)
+    assert.Len(t, *sleeps, 2)
+}
+
+func TestRunWithPolicyTimeout(t *testing.T) {
+    recordSleeps(t)
+    DefaultCommand = func(command string, args ...string) *exec.Cmd {
+        return exec.Command("sleep", "30")
+    }
+    defer func() { DefaultCommand = exec.Command }()
+
+    policy := Policy{Timeout: 200 * time.Millisecond, Attempts: 2}
+    start := time.Now()
+    attempts, err := runWithPolicy(context.Background(), "unzip", policy, func(ctx context.Context) error {
+        return runCommand(ctx, "unzip", "a.zip")
+    })
+    assert.Equal(t, 2, attempts)
+    assert.ErrorContains(t, err, "timed out after 200ms: command timed out: unzip a.zip")
+    assert.Less(t, time.Since(start), 2*outputGrace)
+}
+
+func TestRunWithPolicyStopsWhenCanceled(t *testing.T) {
+    recordSleeps(t)
+    ctx, cancel := context.WithCancel(context.Background())
+    policy := Policy{Timeout: time.Second, Attempts: 5, Backoff: time.Second}
+
+    attempts, err := runWithPolicy(ctx, "zerotier", policy, func(ctx context.Context) error {
+        cancel()
+        return ctx.Err()
+    })
+    assert.Equal(t, 1, attempts)
+    assert.True(t, errors.Is(err, context.Canceled))
+}
+
+func TestPolicyFor(t *testing.T) {
+    policy, err := policyFor("apt", map[string]system.StepOverride{"apt": {Timeout: "30m", Attempts: 6, Backoff: "1m"}})
+    require.NoError(t, err)
+    assert.Equal(t, 30*time.Minute, policy.Timeout)
+    assert.Equal(t, 6, policy.Attempts)
+    assert.Equal(t, time.Minute, policy.Backoff)
+    assert.NotNil(t, policy.Retry, "overrides keep the retry filter")
+
+    policy, err = policyFor("nginx", nil)
+    require.NoError(t, err)
+    assert.Equal(t, DefaultPolicies["nginx"].Timeout, policy.Timeout)
+
+    _, err = policyFor("apt", map[string]system.StepOverride{"apt": {Timeout: "soon"}})
+    assert.ErrorContains(t, err, "install.apt.timeout")
+    _, err = policyFor("apt", map[string]system.StepOverride{"apt": {Attempts: -1}})
+    assert.Error(t, err)
+
+    policy, err = policyFor("nginx", map[string]system.StepOverride{"nginx": {LockWait: "0s"}})
+    require.NoError(t, err)
+    assert.Zero(t, policy.LockWait)
+    _, err = policyFor("nginx", map[string]system.StepOverride{"nginx": {LockWait: "-1m"}})
+    assert.Error(t, err)
+}
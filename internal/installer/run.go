@@ -0,0 +1,139 @@
+package installer
+
+import (
+    "bufio"
+    "context"
+    "errors"
+    "fmt"
+    "go-auto-proxy/internal/logging"
+    "log"
+    "os"
+    "os/exec"
+    "strings"
+    "syscall"
+    "time"
+)
+
+const (
+    // tailLines 為命令失敗時附在錯誤中的最後輸出行數
+    tailLines = 20
+    // outputGrace 為命令結束後等待剩餘輸出讀完的時間（背景子程序可能仍持有管道）
+    outputGrace = 2 * time.Second
+    // killGrace 為送出 SIGTERM 後等待程序組結束的時間，逾時改送 SIGKILL
+    killGrace = 5 * time.Second
+)
+
+// tail 保留最後 n 行輸出
+type tail struct {
+    n     int
+    lines []string
+}
+
+func (t *tail) add(line string) {
+    t.lines = append(t.lines, line)
+    if len(t.lines) > t.n {
+        t.lines = t.lines[len(t.lines)-t.n:]
+    }
+}
+
+func (t *tail) String() string {
+    return strings.Join(t.lines, "\n")
+}
+
+// runCommand 執行命令並逐行記錄輸出；終端上以 spinner 顯示進度，
+// 失敗時錯誤中附上最後幾行輸出。命令在獨立的程序組中執行，
+// ctx 超時（見 Policy.Timeout）或取消時整個程序組（包括 sh -c 的子程序）都會被終止
+func runCommand(ctx context.Context, name string, args ...string) error {
+    cmd := DefaultCommand(name, args...)
+    cmd.Env = os.Environ() // 繼承環境變數
+    // 獨立的程序組也讓終端的 Ctrl-C 只送到本程式，由我們決定如何終止子程序
+    cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+    // stdout 與 stderr 共用同一個管道，保持輸出的先後順序
+    r, w, err := os.Pipe()
+    if err != nil {
+        return fmt.Errorf("failed to create output pipe: %v", err)
+    }
+    defer r.Close()
+    cmd.Stdout = w
+    cmd.Stderr = w
+
+    progress := logging.StartProgress(strings.Join(append([]string{name}, args...), " "))
+    defer progress.Stop()
+
+    if err := cmd.Start(); err != nil {
+        w.Close()
+        return fmt.Errorf("failed to start command: %v", err)
+    }
+    w.Close()
+
+    last := &tail{n: tailLines}
+    outputDone := make(chan struct{})
+    go func() {
+        defer close(outputDone)
+        scanner := bufio.NewScanner(r)
+        scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+        for scanner.Scan() {
+            line := strings.TrimRight(scanner.Text(), "\r")
+            if line == "" {
+                continue
+            }
+            last.add(line)
+            logging.Output(line)
+        }
+    }()
+
+    errChan := make(chan error, 1)
+    go func() {
+        errChan <- cmd.Wait()
+    }()
+
+    var waitErr error
+    select {
+    case waitErr = <-errChan:
+    case <-ctx.Done():
+        waitErr = killProcessGroup(cmd, errChan)
+    }
+    select {
+    case <-outputDone:
+    case <-time.After(outputGrace):
+        r.Close()
+        <-outputDone
+    }
+
+    command := strings.TrimSpace(name + " " + strings.Join(args, " "))
+    if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+        log.Printf("Command timed out: %s", command)
+        return fmt.Errorf("command timed out: %s%s", command, formatTail(last))
+    }
+    if err := ctx.Err(); err != nil {
+        log.Printf("Command interrupted: %s", command)
+        return fmt.Errorf("command interrupted: %s: %w", command, err)
+    }
+    if waitErr != nil {
+        log.Printf("Command failed: %s: %v", command, waitErr)
+        return fmt.Errorf("command failed: %s: %v%s", command, waitErr, formatTail(last))
+    }
+    return nil
+}
+
+// killProcessGroup 先以 SIGTERM 終止命令的程序組，killGrace 後仍未結束則送 SIGKILL；
+// sudo 會將收到的 SIGTERM 轉送給以 root 執行的命令
+func killProcessGroup(cmd *exec.Cmd, errChan <-chan error) error {
+    pgid := cmd.Process.Pid
+    syscall.Kill(-pgid, syscall.SIGTERM)
+    select {
+    case err := <-errChan:
+        return err
+    case <-time.After(killGrace):
+    }
+    syscall.Kill(-pgid, syscall.SIGKILL)
+    return <-errChan
+}
+
+// formatTail 將最後幾行輸出格式化為錯誤訊息的附加段落
+func formatTail(t *tail) string {
+    if len(t.lines) == 0 {
+        return ""
+    }
+    return fmt.Sprintf("\nlast %d lines of output:\n%s", len(t.lines), t.String())
+}
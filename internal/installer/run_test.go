@@ -0,0 +1,57 @@
+package installer
+
+import (
+    "context"
+    "os/exec"
+    "testing"
+    "time"
+
+    "github.com/stretchr/testify/assert"
+)
+
+func TestRunCommandIncludesLastLinesOnFailure(t *testing.T) {
+    DefaultCommand = func(command string, args ...string) *exec.Cmd {
+        return exec.Command("sh", "-c", "for i in $(seq 1 30); do echo line $i; done; echo oops >&2; exit 3")
+    }
+    defer func() { DefaultCommand = exec.Command }()
+
+    err := runCommand(context.Background(), "apt", "update")
+    assert.Error(t, err)
+    assert.Contains(t, err.Error(), "command failed: apt update: exit status 3")
+    assert.Contains(t, err.Error(), "last 20 lines of output:")
+    assert.Contains(t, err.Error(), "line 30\noops")
+    assert.NotContains(t, err.Error(), "line 10\n")
+}
+
+func TestRunCommandSuccess(t *testing.T) {
+    DefaultCommand = func(command string, args ...string) *exec.Cmd {
+        return exec.Command("sh", "-c", "echo done")
+    }
+    defer func() { DefaultCommand = exec.Command }()
+
+    assert.NoError(t, runCommand(context.Background(), "unzip", "a.zip"))
+}
+
+func TestTail(t *testing.T) {
+    last := &tail{n: 2}
+    for _, line := range []string{"a", "b", "c"} {
+        last.add(line)
+    }
+    assert.Equal(t, "b\nc", last.String())
+}
+
+func TestRunCommandCancelKillsProcessGroup(t *testing.T) {
+    // 背景的 sleep 與 sh 在同一程序組，取消時也必須被終止，否則會一直持有輸出管道
+    DefaultCommand = func(command string, args ...string) *exec.Cmd {
+        return exec.Command("sh", "-c", "sleep 30 & echo started; sleep 30")
+    }
+    defer func() { DefaultCommand = exec.Command }()
+
+    ctx, cancel := context.WithCancel(context.Background())
+    time.AfterFunc(200*time.Millisecond, cancel)
+    start := time.Now()
+    err := runCommand(ctx, "sh", "-c", "...")
+    assert.ErrorIs(t, err, context.Canceled)
+    assert.Contains(t, err.Error(), "command interrupted")
+    assert.Less(t, time.Since(start), outputGrace)
+}
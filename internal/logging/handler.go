@@ -0,0 +1,152 @@
+package logging
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "io"
+    "log/slog"
+    "strings"
+)
+
+// stepHandler 為每筆記錄加上目前的 step 屬性
+type stepHandler struct {
+    handler slog.Handler
+}
+
+func (h *stepHandler) Enabled(ctx context.Context, level slog.Level) bool {
+    return h.handler.Enabled(ctx, level)
+}
+
+func (h *stepHandler) Handle(ctx context.Context, r slog.Record) error {
+    if step := CurrentStep(); step != "" {
+        r = r.Clone()
+        r.AddAttrs(slog.String("step", step))
+    }
+    return h.handler.Handle(ctx, r)
+}
+
+func (h *stepHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+    return &stepHandler{handler: h.handler.WithAttrs(attrs)}
+}
+
+func (h *stepHandler) WithGroup(name string) slog.Handler {
+    return &stepHandler{handler: h.handler.WithGroup(name)}
+}
+
+// fanout 將記錄交給所有啟用該等級的 handler
+type fanout []slog.Handler
+
+func (f fanout) Enabled(ctx context.Context, level slog.Level) bool {
+    for _, h := range f {
+        if h.Enabled(ctx, level) {
+            return true
+        }
+    }
+    return false
+}
+
+func (f fanout) Handle(ctx context.Context, r slog.Record) error {
+    var errs []error
+    for _, h := range f {
+        if h.Enabled(ctx, r.Level) {
+            if err := h.Handle(ctx, r.Clone()); err != nil {
+                errs = append(errs, err)
+            }
+        }
+    }
+    return errors.Join(errs...)
+}
+
+func (f fanout) WithAttrs(attrs []slog.Attr) slog.Handler {
+    out := make(fanout, len(f))
+    for i, h := range f {
+        out[i] = h.WithAttrs(attrs)
+    }
+    return out
+}
+
+func (f fanout) WithGroup(name string) slog.Handler {
+    out := make(fanout, len(f))
+    for i, h := range f {
+        out[i] = h.WithGroup(name)
+    }
+    return out
+}
+
+// consoleHandler 以接近原本 log 套件的格式輸出到終端：
+//
+//    2006/01/02 15:04:05 WARN [install/nginx] message key=value
+//
+// info 不顯示等級，step 以方括號放在訊息前；顯示進度列時命令輸出只更新進度列
+type consoleHandler struct {
+    w     io.Writer
+    level slog.Level
+    attrs []slog.Attr
+}
+
+func newConsoleHandler(w io.Writer, level slog.Level) *consoleHandler {
+    return &consoleHandler{w: w, level: level}
+}
+
+func (h *consoleHandler) Enabled(_ context.Context, level slog.Level) bool {
+    return level >= h.level
+}
+
+func (h *consoleHandler) Handle(_ context.Context, r slog.Record) error {
+    var b strings.Builder
+    b.WriteString(r.Time.Format("2006/01/02 15:04:05"))
+    if r.Level != slog.LevelInfo {
+        b.WriteString(" " + r.Level.String())
+    }
+    var step string
+    var output bool
+    var extra []string
+    add := func(a slog.Attr) bool {
+        switch a.Key {
+        case "step":
+            step = a.Value.String()
+        case OutputKey:
+            output = true
+        default:
+            extra = append(extra, fmt.Sprintf("%s=%v", a.Key, a.Value))
+        }
+        return true
+    }
+    for _, a := range h.attrs {
+        add(a)
+    }
+    r.Attrs(add)
+
+    consoleMu.Lock()
+    defer consoleMu.Unlock()
+    if active != nil {
+        if output {
+            active.last = r.Message
+            return nil
+        }
+        // 先清除進度列，下一次重繪時會出現在這行之後
+        io.WriteString(h.w, "\r\033[K")
+    }
+    if step != "" {
+        b.WriteString(" [" + step + "]")
+    }
+    b.WriteString(" " + r.Message)
+    for _, e := range extra {
+        b.WriteString(" " + e)
+    }
+    b.WriteString("\n")
+    _, err := io.WriteString(h.w, b.String())
+    return err
+}
+
+func (h *consoleHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+    next := *h
+    next.attrs = append(append([]slog.Attr{}, h.attrs...), attrs...)
+    return &next
+}
+
+// WithGroup 終端輸出不區分群組
+func (h *consoleHandler) WithGroup(string) slog.Handler {
+    return h
+}
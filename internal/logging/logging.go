@@ -0,0 +1,228 @@
+package logging
+
+import (
+    "context"
+    "fmt"
+    "io"
+    "log"
+    "log/slog"
+    "os"
+    "path/filepath"
+    "regexp"
+    "sort"
+    "strings"
+    "sync"
+    "time"
+)
+
+// DefaultDir 是預設的日誌目錄，需要 root 權限才能建立
+const DefaultDir = "/var/log/go-auto-proxy"
+
+// Console 是終端輸出，測試時可替換
+var Console io.Writer = os.Stdout
+
+// Options 控制日誌等級、檔案格式與輪替
+type Options struct {
+    // Level 為 debug、info、warn 或 error
+    Level string
+    // Format 為日誌檔的格式：text 或 json；終端一律輸出易讀的文字
+    Format string
+    // Dir 為日誌目錄，留空時只輸出到終端
+    Dir string
+    // FallbackDir 在 Dir 無法建立或寫入時使用（非 root 執行）
+    FallbackDir string
+    // Name 為日誌檔名前綴，通常是命令名稱
+    Name string
+    // MaxSize 為單一檔案的位元組上限，超過時換到下一個檔案；0 表示不限制
+    MaxSize int64
+    // MaxAge 之前的舊日誌在啟動時刪除；0 表示不依時間刪除
+    MaxAge time.Duration
+    // MaxFiles 為目錄中保留的日誌檔數量上限；0 表示不限制
+    MaxFiles int
+}
+
+// DefaultOptions 返回預設的等級、格式與輪替設定
+func DefaultOptions() Options {
+    return Options{
+        Level:    "info",
+        Format:   "text",
+        Dir:      DefaultDir,
+        MaxSize:  10 << 20,
+        MaxAge:   30 * 24 * time.Hour,
+        MaxFiles: 50,
+    }
+}
+
+var (
+    stepMu  sync.Mutex
+    current string
+)
+
+// Step 將之後的日誌標記為 name 步驟，返回的函數恢復為先前的步驟
+//
+//    defer logging.Step("install/nginx")()
+func Step(name string) func() {
+    previous := CurrentStep()
+    SetStep(name)
+    return func() { SetStep(previous) }
+}
+
+// SetStep 設定目前的步驟，用於依序執行、不需要恢復的階段
+func SetStep(name string) {
+    stepMu.Lock()
+    defer stepMu.Unlock()
+    current = name
+}
+
+// CurrentStep 返回目前的步驟名稱，沒有時為空
+func CurrentStep() string {
+    stepMu.Lock()
+    defer stepMu.Unlock()
+    return current
+}
+
+// ParseLevel 解析 --log-level
+func ParseLevel(name string) (slog.Level, error) {
+    switch strings.ToLower(name) {
+    case "debug":
+        return slog.LevelDebug, nil
+    case "", "info":
+        return slog.LevelInfo, nil
+    case "warn", "warning":
+        return slog.LevelWarn, nil
+    case "error":
+        return slog.LevelError, nil
+    }
+    return 0, fmt.Errorf("unknown log level %q (use debug, info, warn or error)", name)
+}
+
+// Setup 設定 slog 預設 logger，並將標準 log 套件的輸出轉交給它；
+// 有設定 Dir 時另外寫入每次執行各自的日誌檔。返回日誌檔路徑（未寫檔時為空）
+// 與結束時呼叫的 close
+func Setup(opts Options) (string, func() error, error) {
+    level, err := ParseLevel(opts.Level)
+    if err != nil {
+        return "", nil, err
+    }
+    if opts.Format != "" && opts.Format != "text" && opts.Format != "json" {
+        return "", nil, fmt.Errorf("unknown log format %q (use text or json)", opts.Format)
+    }
+
+    handlers := []slog.Handler{newConsoleHandler(Console, level)}
+    path := ""
+    closeFn := func() error { return nil }
+    if opts.Dir != "" {
+        w, err := openLogFile(opts)
+        if err != nil {
+            return "", nil, err
+        }
+        path = w.Path()
+        closeFn = w.Close
+        handlerOpts := &slog.HandlerOptions{Level: level}
+        if opts.Format == "json" {
+            handlers = append(handlers, slog.NewJSONHandler(w, handlerOpts))
+        } else {
+            handlers = append(handlers, slog.NewTextHandler(w, handlerOpts))
+        }
+    }
+
+    logger := slog.New(&stepHandler{handler: fanout(handlers)})
+    // 必須先 SetDefault 再轉接標準 log，否則 slog 的預設 handler 會寫回 log 形成迴圈
+    slog.SetDefault(logger)
+    log.SetFlags(0)
+    log.SetPrefix("")
+    log.SetOutput(bridge{logger: logger})
+    return path, closeFn, nil
+}
+
+// openLogFile 先嘗試 Dir，無法建立時改用 FallbackDir，並清理過舊的日誌
+func openLogFile(opts Options) (*rotatingWriter, error) {
+    dirs := []string{opts.Dir}
+    if opts.FallbackDir != "" && opts.FallbackDir != opts.Dir {
+        dirs = append(dirs, opts.FallbackDir)
+    }
+    name := opts.Name
+    if name == "" {
+        name = "go-auto-proxy"
+    }
+    base := fmt.Sprintf("%s-%s", name, time.Now().Format("20060102-150405"))
+
+    var lastErr error
+    for _, dir := range dirs {
+        if err := os.MkdirAll(dir, 0700); err != nil {
+            lastErr = err
+            continue
+        }
+        prune(dir, opts.MaxAge, opts.MaxFiles)
+        w, err := newRotatingWriter(dir, base, opts.MaxSize)
+        if err != nil {
+            lastErr = err
+            continue
+        }
+        return w, nil
+    }
+    return nil, fmt.Errorf("failed to open log file in %s: %v", strings.Join(dirs, " or "), lastErr)
+}
+
+// bridge 將標準 log 的每一行轉為 slog 記錄，等級由訊息開頭推斷
+type bridge struct {
+    logger *slog.Logger
+}
+
+func (b bridge) Write(p []byte) (int, error) {
+    msg := strings.TrimRight(string(p), "\n")
+    b.logger.Log(context.Background(), levelOf(msg), msg)
+    return len(p), nil
+}
+
+// levelOf 依既有訊息的慣例推斷等級：Warning: 開頭為 warn，Error/Failed 開頭為 error
+func levelOf(msg string) slog.Level {
+    lower := strings.ToLower(msg)
+    switch {
+    case strings.HasPrefix(lower, "warning:"):
+        return slog.LevelWarn
+    case strings.HasPrefix(lower, "error"), strings.HasPrefix(lower, "failed"), strings.HasPrefix(lower, "command failed"), strings.HasPrefix(lower, "command timed out"):
+        return slog.LevelError
+    }
+    return slog.LevelInfo
+}
+
+// logName 比對本工具建立的日誌檔：<name>-YYYYMMDD-HHMMSS.log 與輪替的 <name>-YYYYMMDD-HHMMSS.N.log
+var logName = regexp.MustCompile(`^.+-\d{8}-\d{6}(\.\d+)?\.log$`)
+
+// prune 刪除超過 maxAge 的日誌，並只保留最新的 maxFiles 個；
+// 目錄中其他程式的 .log 檔（如 FallbackDir 為共用目錄時）不受影響
+func prune(dir string, maxAge time.Duration, maxFiles int) {
+    entries, err := os.ReadDir(dir)
+    if err != nil {
+        return
+    }
+    type logFile struct {
+        path    string
+        modTime time.Time
+    }
+    var files []logFile
+    for _, entry := range entries {
+        if entry.IsDir() || !logName.MatchString(entry.Name()) {
+            continue
+        }
+        info, err := entry.Info()
+        if err != nil {
+            continue
+        }
+        path := filepath.Join(dir, entry.Name())
+        if maxAge > 0 && time.Since(info.ModTime()) > maxAge {
+            os.Remove(path)
+            continue
+        }
+        files = append(files, logFile{path: path, modTime: info.ModTime()})
+    }
+    // 新執行還會建立一個檔案，因此只保留 maxFiles-1 個
+    if maxFiles <= 0 || len(files) < maxFiles {
+        return
+    }
+    sort.Slice(files, func(i, j int) bool { return files[i].modTime.Before(files[j].modTime) })
+    for _, f := range files[:len(files)-maxFiles+1] {
+        os.Remove(f.path)
+    }
+}
@@ -0,0 +1,176 @@
+package logging
+
+import (
+    "bytes"
+    "encoding/json"
+    "fmt"
+    "log"
+    "log/slog"
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+    "time"
+
+    "github.com/stretchr/testify/assert"
+    "github.com/stretchr/testify/require"
+)
+
+// withConsole 替換終端輸出，並在測試結束時恢復標準 log 與 slog 的設定
+func withConsole(t *testing.T) *bytes.Buffer {
+    var buf bytes.Buffer
+    originalConsole := Console
+    originalDefault := slog.Default()
+    Console = &buf
+    t.Cleanup(func() {
+        Console = originalConsole
+        slog.SetDefault(originalDefault)
+        log.SetOutput(os.Stderr)
+        log.SetFlags(log.LstdFlags)
+        SetStep("")
+    })
+    return &buf
+}
+
+func TestSetupJSONFileWithStep(t *testing.T) {
+    console := withConsole(t)
+    dir := t.TempDir()
+    opts := DefaultOptions()
+    opts.Dir = dir
+    opts.Format = "json"
+    opts.Name = "init"
+
+    path, closeFn, err := Setup(opts)
+    require.NoError(t, err)
+    assert.Equal(t, dir, filepath.Dir(path))
+    assert.True(t, strings.HasPrefix(filepath.Base(path), "init-"))
+
+    done := Step("install/nginx")
+    log.Println("Installing nginx...")
+    log.Println("Warning: port 80 is busy")
+    slog.Debug("hidden at info level")
+    done()
+    log.Println("Dependencies installed successfully.")
+    require.NoError(t, closeFn())
+
+    info, err := os.Stat(path)
+    require.NoError(t, err)
+    assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+    data, err := os.ReadFile(path)
+    require.NoError(t, err)
+    lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+    require.Len(t, lines, 3)
+    var records []map[string]interface{}
+    for _, line := range lines {
+        var record map[string]interface{}
+        require.NoError(t, json.Unmarshal([]byte(line), &record))
+        records = append(records, record)
+    }
+    assert.Equal(t, "install/nginx", records[0]["step"])
+    assert.Equal(t, "INFO", records[0]["level"])
+    assert.Equal(t, "WARN", records[1]["level"])
+    assert.Nil(t, records[2]["step"])
+
+    out := console.String()
+    assert.Contains(t, out, "[install/nginx] Installing nginx...")
+    assert.Contains(t, out, "WARN [install/nginx] Warning: port 80 is busy")
+    assert.NotContains(t, out, "hidden")
+}
+
+func TestSetupWithoutDirOnlyWritesConsole(t *testing.T) {
+    console := withConsole(t)
+    opts := DefaultOptions()
+    opts.Dir = ""
+    opts.Level = "debug"
+
+    path, closeFn, err := Setup(opts)
+    require.NoError(t, err)
+    defer closeFn()
+    assert.Empty(t, path)
+
+    slog.Debug("visible", "attempt", 2)
+    assert.Contains(t, console.String(), "DEBUG visible attempt=2")
+}
+
+func TestSetupFallsBackWhenDirIsNotWritable(t *testing.T) {
+    withConsole(t)
+    blocker := filepath.Join(t.TempDir(), "file")
+    require.NoError(t, os.WriteFile(blocker, nil, 0600))
+    fallback := t.TempDir()
+    opts := DefaultOptions()
+    opts.Dir = filepath.Join(blocker, "logs")
+    opts.FallbackDir = fallback
+
+    path, closeFn, err := Setup(opts)
+    require.NoError(t, err)
+    defer closeFn()
+    assert.Equal(t, fallback, filepath.Dir(path))
+}
+
+func TestSetupRejectsUnknownOptions(t *testing.T) {
+    withConsole(t)
+    _, _, err := Setup(Options{Level: "verbose"})
+    assert.Error(t, err)
+    _, _, err = Setup(Options{Level: "info", Format: "xml"})
+    assert.Error(t, err)
+}
+
+func TestRotatingWriter(t *testing.T) {
+    dir := t.TempDir()
+    w, err := newRotatingWriter(dir, "run", 10)
+    require.NoError(t, err)
+    for i := 0; i < 3; i++ {
+        _, err := fmt.Fprintf(w, "line %d\n", i)
+        require.NoError(t, err)
+    }
+    require.NoError(t, w.Close())
+
+    for i, name := range []string{"run.log", "run.1.log", "run.2.log"} {
+        data, err := os.ReadFile(filepath.Join(dir, name))
+        require.NoError(t, err)
+        assert.Equal(t, fmt.Sprintf("line %d\n", i), string(data))
+    }
+}
+
+func TestPrune(t *testing.T) {
+    dir := t.TempDir()
+    now := time.Now()
+    files := []string{
+        "install-20260101-100000.log",
+        "install-20260101-100000.1.log",
+        "ddns-20260102-100000.log",
+        "ddns-20250101-100000.log",
+        "keep.txt",
+        "other-app.log",
+        "ancient.log",
+    }
+    for i, name := range files {
+        path := filepath.Join(dir, name)
+        require.NoError(t, os.WriteFile(path, nil, 0600))
+        modTime := now.Add(time.Duration(i) * time.Minute)
+        if name == "ddns-20250101-100000.log" || name == "ancient.log" {
+            modTime = now.Add(-48 * time.Hour)
+        }
+        require.NoError(t, os.Chtimes(path, modTime, modTime))
+    }
+
+    prune(dir, 24*time.Hour, 3)
+
+    entries, err := os.ReadDir(dir)
+    require.NoError(t, err)
+    var names []string
+    for _, entry := range entries {
+        names = append(names, entry.Name())
+    }
+    // 保留最新的兩個，為新執行的檔案留一個位置；不是本工具建立的檔案一律保留
+    assert.ElementsMatch(t, []string{"install-20260101-100000.1.log", "ddns-20260102-100000.log", "keep.txt", "other-app.log", "ancient.log"}, names)
+}
+
+func TestLevelOf(t *testing.T) {
+    assert.Equal(t, slog.LevelWarn, levelOf("Warning: sudo requires a password"))
+    assert.Equal(t, slog.LevelError, levelOf("Error writing config: denied"))
+    assert.Equal(t, slog.LevelError, levelOf("Failed to join ZeroTier network: timeout"))
+    assert.Equal(t, slog.LevelError, levelOf("Command failed: apt [update]"))
+    assert.Equal(t, slog.LevelInfo, levelOf("Installing nginx..."))
+}
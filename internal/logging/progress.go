@@ -0,0 +1,131 @@
+package logging
+
+import (
+    "fmt"
+    "log/slog"
+    "os"
+    "sync"
+    "time"
+)
+
+// OutputKey 標記命令輸出的日誌行；終端顯示進度時改以進度列呈現，不逐行印出
+const OutputKey = "output"
+
+var (
+    // IsTerminal 判斷 Console 是否為終端，測試時可替換
+    IsTerminal = func() bool {
+        f, ok := Console.(*os.File)
+        if !ok {
+            return false
+        }
+        info, err := f.Stat()
+        return err == nil && info.Mode()&os.ModeCharDevice != 0
+    }
+
+    // consoleMu 讓日誌行與進度列不會交錯寫入終端
+    consoleMu sync.Mutex
+    active    *Progress
+)
+
+var spinnerFrames = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+
+const (
+    spinnerInterval = 100 * time.Millisecond
+    // progressWidth 為進度列中最後一行輸出的顯示長度上限
+    progressWidth = 60
+)
+
+// Progress 在終端上以 spinner 顯示執行中的命令、經過時間與最後一行輸出
+type Progress struct {
+    label string
+    start time.Time
+    last  string
+    frame int
+    stop  chan struct{}
+    done  chan struct{}
+}
+
+// Output 以 info 等級記錄一行命令輸出，日誌檔中會帶有 step 與 output 屬性
+func Output(line string) {
+    slog.Info(line, OutputKey, true)
+}
+
+// StartProgress 在終端上開始顯示進度；不是終端時返回不做任何事的 Progress，
+// 命令輸出照常逐行印出
+func StartProgress(label string) *Progress {
+    p := &Progress{label: label, start: time.Now()}
+    if !IsTerminal() {
+        return p
+    }
+    consoleMu.Lock()
+    if active != nil {
+        // 已有進度列時不巢狀顯示
+        consoleMu.Unlock()
+        return p
+    }
+    active = p
+    p.stop = make(chan struct{})
+    p.done = make(chan struct{})
+    p.drawLocked()
+    consoleMu.Unlock()
+
+    go func() {
+        defer close(p.done)
+        ticker := time.NewTicker(spinnerInterval)
+        defer ticker.Stop()
+        for {
+            select {
+            case <-p.stop:
+                return
+            case <-ticker.C:
+                consoleMu.Lock()
+                p.frame++
+                p.drawLocked()
+                consoleMu.Unlock()
+            }
+        }
+    }()
+    return p
+}
+
+// SetLabel 更新進度列的說明，如倒數的剩餘時間
+func (p *Progress) SetLabel(label string) {
+    consoleMu.Lock()
+    defer consoleMu.Unlock()
+    p.label = label
+}
+
+// Stop 停止並清除進度列
+func (p *Progress) Stop() {
+    if p.stop == nil {
+        return
+    }
+    close(p.stop)
+    <-p.done
+    consoleMu.Lock()
+    defer consoleMu.Unlock()
+    fmt.Fprint(Console, "\r\033[K")
+    active = nil
+    p.stop = nil
+}
+
+// drawLocked 重繪進度列，呼叫者須持有 consoleMu
+func (p *Progress) drawLocked() {
+    label := p.label
+    if step := CurrentStep(); step != "" {
+        label = "[" + step + "] " + label
+    }
+    line := fmt.Sprintf("%s %s (%s)", spinnerFrames[p.frame%len(spinnerFrames)], label, time.Since(p.start).Round(time.Second))
+    if p.last != "" {
+        line += " " + truncate(p.last, progressWidth)
+    }
+    fmt.Fprint(Console, "\r\033[K"+line)
+}
+
+func truncate(s string, n int) string {
+    runes := []rune(s)
+    if len(runes) <= n {
+        return s
+    }
+    return string(runes[:n-1]) + "…"
+}
@@ -0,0 +1,57 @@
+package logging
+
+import (
+    "strings"
+    "testing"
+    "time"
+
+    "github.com/stretchr/testify/assert"
+    "github.com/stretchr/testify/require"
+)
+
+func TestProgressOnTerminal(t *testing.T) {
+    console := withConsole(t)
+    originalIsTerminal := IsTerminal
+    IsTerminal = func() bool { return true }
+    defer func() { IsTerminal = originalIsTerminal }()
+    _, closeFn, err := Setup(Options{Level: "info"})
+    require.NoError(t, err)
+    defer closeFn()
+
+    defer Step("install/apt")()
+    p := StartProgress("apt update")
+    Output("Get:1 http://archive.ubuntu.com jammy InRelease")
+    time.Sleep(2 * spinnerInterval)
+    p.Stop()
+    Output("after stop")
+
+    out := console.String()
+    assert.Contains(t, out, "[install/apt] apt update")
+    assert.Contains(t, out, "Get:1 http://archive.ubuntu.com")
+    // 進度列顯示期間命令輸出不逐行印出
+    assert.NotContains(t, out, "[install/apt] Get:1")
+    assert.True(t, strings.HasSuffix(out, "[install/apt] after stop\n"))
+}
+
+func TestProgressWithoutTerminalPrintsLines(t *testing.T) {
+    console := withConsole(t)
+    originalIsTerminal := IsTerminal
+    IsTerminal = func() bool { return false }
+    defer func() { IsTerminal = originalIsTerminal }()
+    _, closeFn, err := Setup(Options{Level: "info"})
+    require.NoError(t, err)
+    defer closeFn()
+
+    defer Step("install/nginx")()
+    p := StartProgress("apt install -y nginx")
+    Output("Setting up nginx")
+    p.Stop()
+
+    assert.Contains(t, console.String(), "[install/nginx] Setting up nginx\n")
+    assert.NotContains(t, console.String(), "\r")
+}
+
+func TestTruncate(t *testing.T) {
+    assert.Equal(t, "abc", truncate("abc", 5))
+    assert.Equal(t, "abcd…", truncate("abcdefgh", 5))
+}
@@ -0,0 +1,82 @@
+package logging
+
+import (
+    "fmt"
+    "os"
+    "path/filepath"
+    "sync"
+)
+
+// rotatingWriter 寫入 <base>.log，超過 maxSize 時依序換到 <base>.1.log、<base>.2.log…
+type rotatingWriter struct {
+    mu      sync.Mutex
+    dir     string
+    base    string
+    maxSize int64
+    index   int
+    size    int64
+    file    *os.File
+}
+
+func newRotatingWriter(dir, base string, maxSize int64) (*rotatingWriter, error) {
+    w := &rotatingWriter{dir: dir, base: base, maxSize: maxSize}
+    if err := w.open(); err != nil {
+        return nil, err
+    }
+    return w, nil
+}
+
+// Path 返回目前寫入的檔案
+func (w *rotatingWriter) Path() string {
+    w.mu.Lock()
+    defer w.mu.Unlock()
+    return w.file.Name()
+}
+
+func (w *rotatingWriter) name() string {
+    if w.index == 0 {
+        return filepath.Join(w.dir, w.base+".log")
+    }
+    return filepath.Join(w.dir, fmt.Sprintf("%s.%d.log", w.base, w.index))
+}
+
+// open 以 0600 開啟檔案：日誌可能含有主機資訊，僅允許擁有者讀寫
+func (w *rotatingWriter) open() error {
+    f, err := os.OpenFile(w.name(), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+    if err != nil {
+        return err
+    }
+    if err := f.Chmod(0600); err != nil {
+        f.Close()
+        return err
+    }
+    info, err := f.Stat()
+    if err != nil {
+        f.Close()
+        return err
+    }
+    w.file = f
+    w.size = info.Size()
+    return nil
+}
+
+func (w *rotatingWriter) Write(p []byte) (int, error) {
+    w.mu.Lock()
+    defer w.mu.Unlock()
+    if w.maxSize > 0 && w.size > 0 && w.size+int64(len(p)) > w.maxSize {
+        w.file.Close()
+        w.index++
+        if err := w.open(); err != nil {
+            return 0, err
+        }
+    }
+    n, err := w.file.Write(p)
+    w.size += int64(n)
+    return n, err
+}
+
+func (w *rotatingWriter) Close() error {
+    w.mu.Lock()
+    defer w.mu.Unlock()
+    return w.file.Close()
+}
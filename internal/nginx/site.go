@@ -0,0 +1,105 @@
+package nginx
+
+import (
+    "bytes"
+    "fmt"
+    "go-auto-proxy/internal/sudo"
+    "log"
+    "os"
+    "path/filepath"
+    "text/template"
+)
+
+var (
+    // SiteDir 與 EnabledDir 為 Debian/Ubuntu 的 nginx 站點目錄
+    SiteDir    = "/etc/nginx/sites-available"
+    EnabledDir = "/etc/nginx/sites-enabled"
+)
+
+const siteFile = "go-auto-proxy.conf"
+
+// Site 描述本工具管理的 nginx 站點：公網 80 端口提供 ACME 驗證並轉向 trojan-go，
+// 來自 trojan-go 的回落流量（127.0.0.1）則直接提供網頁內容
+type Site struct {
+    Domain     string
+    Webroot    string // 留空時使用 /var/www/html
+    TrojanPort int
+}
+
+var siteTemplate = template.Must(template.New("site").Parse(`# Managed by go-auto-proxy
+server {
+    listen 80;
+    listen [::]:80;
+    server_name {{if .Domain}}{{.Domain}}{{else}}_{{end}};
+    root {{.Webroot}};
+    index index.html;
+
+    location /.well-known/acme-challenge/ {
+        try_files $uri =404;
+    }
+
+    location / {
+        # trojan-go 將未通過驗證的流量轉發到本機，直接提供網頁以免形成轉向迴圈
+        if ($remote_addr != 127.0.0.1) {
+            return 301 https://$host{{.PortSuffix}}$request_uri;
+        }
+        try_files $uri $uri/ =404;
+    }
+}
+`))
+
+// Render 返回站點配置內容
+func (s Site) Render() ([]byte, error) {
+    data := struct {
+        Site
+        PortSuffix string
+    }{Site: s}
+    if data.Webroot == "" {
+        data.Webroot = "/var/www/html"
+    }
+    if s.TrojanPort != 443 {
+        data.PortSuffix = fmt.Sprintf(":%d", s.TrojanPort)
+    }
+    var buf bytes.Buffer
+    if err := siteTemplate.Execute(&buf, data); err != nil {
+        return nil, err
+    }
+    return buf.Bytes(), nil
+}
+
+// SitePath 返回站點配置檔的路徑
+func SitePath() string {
+    return filepath.Join(SiteDir, siteFile)
+}
+
+// Apply 寫入並啟用站點，以 nginx -t 驗證後重新載入；驗證失敗時還原原有配置
+func Apply(site Site) error {
+    data, err := site.Render()
+    if err != nil {
+        return err
+    }
+    path := SitePath()
+    previous, readErr := os.ReadFile(path)
+
+    if err := sudo.WriteFile(path, data, 0644); err != nil {
+        return err
+    }
+    if _, err := sudo.Run("ln", "-sf", path, filepath.Join(EnabledDir, siteFile)); err != nil {
+        return err
+    }
+    if _, err := sudo.Run("nginx", "-t"); err != nil {
+        log.Println("nginx configuration test failed, restoring the previous site...")
+        if readErr == nil {
+            sudo.WriteFile(path, previous, 0644)
+        } else {
+            sudo.RemoveFile(filepath.Join(EnabledDir, siteFile))
+            sudo.RemoveFile(path)
+        }
+        return fmt.Errorf("nginx configuration is invalid: %v", err)
+    }
+    if _, err := sudo.Run("systemctl", "reload", "nginx"); err != nil {
+        return fmt.Errorf("failed to reload nginx: %v", err)
+    }
+    log.Printf("nginx site %s updated (redirects to port %d).", path, site.TrojanPort)
+    return nil
+}
@@ -0,0 +1,79 @@
+package nginx
+
+import (
+    "go-auto-proxy/internal/sudo"
+    "os"
+    "os/exec"
+    "path/filepath"
+    "testing"
+
+    "github.com/stretchr/testify/assert"
+    "github.com/stretchr/testify/require"
+)
+
+func TestRender(t *testing.T) {
+    data, err := Site{Domain: "proxy.example.com", TrojanPort: 443}.Render()
+    require.NoError(t, err)
+    assert.Contains(t, string(data), "server_name proxy.example.com;")
+    assert.Contains(t, string(data), "root /var/www/html;")
+    assert.Contains(t, string(data), "return 301 https://$host$request_uri;")
+
+    data, err = Site{TrojanPort: 8443, Webroot: "/srv/www"}.Render()
+    require.NoError(t, err)
+    assert.Contains(t, string(data), "server_name _;")
+    assert.Contains(t, string(data), "return 301 https://$host:8443$request_uri;")
+}
+
+// withTempDirs 將站點目錄指向暫存目錄，並以 testFails 控制 nginx -t 的結果
+func withTempDirs(t *testing.T, testFails bool) *[]string {
+    SiteDir, EnabledDir = t.TempDir(), t.TempDir()
+    var calls []string
+    original := sudo.DefaultCommand
+    sudo.DefaultCommand = func(name string, args ...string) *exec.Cmd {
+        if name == "sudo" {
+            name, args = args[0], args[1:]
+        }
+        calls = append(calls, name+" "+args[0])
+        switch {
+        case name == "nginx" && testFails:
+            return exec.Command("false")
+        case name == "nginx" || name == "systemctl":
+            return exec.Command("true")
+        }
+        return exec.Command(name, args...)
+    }
+    t.Cleanup(func() {
+        sudo.DefaultCommand = original
+        SiteDir, EnabledDir = "/etc/nginx/sites-available", "/etc/nginx/sites-enabled"
+    })
+    return &calls
+}
+
+func TestApply(t *testing.T) {
+    calls := withTempDirs(t, false)
+    require.NoError(t, Apply(Site{Domain: "proxy.example.com", TrojanPort: 443}))
+
+    target, err := os.Readlink(filepath.Join(EnabledDir, siteFile))
+    require.NoError(t, err)
+    assert.Equal(t, filepath.Join(SiteDir, siteFile), target)
+    assert.Contains(t, *calls, "nginx -t")
+    assert.Contains(t, *calls, "systemctl reload")
+}
+
+func TestApplyRestoresOnFailure(t *testing.T) {
+    withTempDirs(t, false)
+    require.NoError(t, Apply(Site{TrojanPort: 443}))
+    previous, err := os.ReadFile(filepath.Join(SiteDir, siteFile))
+    require.NoError(t, err)
+
+    withTempDirs(t, true)
+    require.NoError(t, os.WriteFile(filepath.Join(SiteDir, siteFile), previous, 0644))
+    assert.Error(t, Apply(Site{TrojanPort: 8443}))
+    restored, err := os.ReadFile(filepath.Join(SiteDir, siteFile))
+    require.NoError(t, err)
+    assert.Equal(t, previous, restored)
+
+    withTempDirs(t, true)
+    assert.Error(t, Apply(Site{TrojanPort: 8443}))
+    assert.NoFileExists(t, filepath.Join(SiteDir, siteFile), "a new site is removed when the test fails")
+}
@@ -0,0 +1,164 @@
+package report
+
+import (
+    "encoding/json"
+    "fmt"
+    "go-auto-proxy/internal/secret"
+    "io"
+    "math"
+    "path/filepath"
+    "strings"
+    "time"
+)
+
+// 元件的狀態
+const (
+    StatusInstalled = "installed"
+    StatusSkipped   = "skipped"
+    StatusFailed    = "failed"
+)
+
+// init 的整體結果
+const (
+    ResultCompleted   = "completed"
+    ResultFailed      = "failed"
+    ResultInterrupted = "interrupted"
+)
+
+// Component 是一個元件（安裝步驟或設定）的結果
+type Component struct {
+    Name     string        `json:"name"`
+    Status   string        `json:"status"`
+    Version  string        `json:"version,omitempty"`
+    Detail   string        `json:"detail,omitempty"`
+    Attempts int           `json:"attempts,omitempty"`
+    Duration time.Duration `json:"-"`
+    Errors   []string      `json:"errors,omitempty"`
+    Files    []string      `json:"files,omitempty"` // 生成或修改的檔案
+}
+
+// MarshalJSON 以秒數輸出耗時，方便其他工具解析
+func (c Component) MarshalJSON() ([]byte, error) {
+    type plain Component
+    return json.Marshal(struct {
+        plain
+        DurationSeconds float64 `json:"duration_seconds"`
+    }{plain(c), math.Round(c.Duration.Seconds()*1000) / 1000})
+}
+
+// Fail 將元件標記為失敗並記錄錯誤
+func (c *Component) Fail(err error) {
+    c.Status = StatusFailed
+    c.Errors = append(c.Errors, err.Error())
+}
+
+// AddFiles 記錄生成的檔案，相對路徑轉為絕對路徑
+func (c *Component) AddFiles(paths ...string) {
+    for _, path := range paths {
+        if abs, err := filepath.Abs(path); err == nil {
+            path = abs
+        }
+        c.Files = append(c.Files, path)
+    }
+}
+
+// Report 是 init 的結果報告
+type Report struct {
+    Result     string       `json:"result"`
+    StartedAt  time.Time    `json:"started_at"`
+    FinishedAt time.Time    `json:"finished_at"`
+    LogFile    string       `json:"log_file,omitempty"`
+    Components []*Component `json:"components"`
+}
+
+// New 建立報告並記錄開始時間
+func New() *Report {
+    return &Report{StartedAt: time.Now()}
+}
+
+// Component 返回名為 name 的元件，不存在時依呼叫順序新增
+func (r *Report) Component(name string) *Component {
+    for _, c := range r.Components {
+        if c.Name == name {
+            return c
+        }
+    }
+    c := &Component{Name: name}
+    r.Components = append(r.Components, c)
+    return c
+}
+
+// Finish 記錄結束時間與整體結果；沒有設定狀態的元件視為略過
+func (r *Report) Finish(result string) {
+    r.Result = result
+    r.FinishedAt = time.Now()
+    for _, c := range r.Components {
+        if c.Status == "" {
+            c.Status = StatusSkipped
+        }
+    }
+}
+
+// WriteTable 以表格輸出，說明、錯誤與檔案列在各元件下方且不影響欄寬
+func (r *Report) WriteTable(w io.Writer) error {
+    rows := [][]string{{"COMPONENT", "STATUS", "VERSION", "DURATION", "ATTEMPTS"}}
+    for _, c := range r.Components {
+        row := []string{c.Name, c.Status, "-", "-", "-"}
+        if c.Version != "" {
+            row[2] = c.Version
+        }
+        if c.Duration > 0 {
+            row[3] = c.Duration.Round(100 * time.Millisecond).String()
+        }
+        if c.Attempts > 0 {
+            row[4] = fmt.Sprint(c.Attempts)
+        }
+        rows = append(rows, row)
+    }
+    widths := make([]int, len(rows[0]))
+    for _, row := range rows {
+        for i, cell := range row {
+            if len(cell) > widths[i] {
+                widths[i] = len(cell)
+            }
+        }
+    }
+
+    var b strings.Builder
+    for i, row := range rows {
+        for j, cell := range row {
+            if j == len(row)-1 {
+                b.WriteString(cell)
+            } else {
+                fmt.Fprintf(&b, "%-*s  ", widths[j], cell)
+            }
+        }
+        b.WriteString("\n")
+        if i == 0 {
+            continue
+        }
+        c := r.Components[i-1]
+        if c.Detail != "" {
+            fmt.Fprintf(&b, "  %s\n", c.Detail)
+        }
+        for _, e := range c.Errors {
+            line, _, _ := strings.Cut(e, "\n")
+            fmt.Fprintf(&b, "  error: %s\n", line)
+        }
+        for _, f := range c.Files {
+            fmt.Fprintf(&b, "  file: %s\n", f)
+        }
+    }
+    fmt.Fprintf(&b, "Result: %s in %s\n", r.Result, r.FinishedAt.Sub(r.StartedAt).Round(time.Second))
+    _, err := io.WriteString(w, b.String())
+    return err
+}
+
+// WriteJSON 寫入 JSON 報告；與 config.json 相同，僅允許擁有者讀寫
+func (r *Report) WriteJSON(path string) error {
+    data, err := json.MarshalIndent(r, "", "  ")
+    if err != nil {
+        return err
+    }
+    return secret.WriteFile(path, append(data, '\n'))
+}
@@ -0,0 +1,97 @@
+package report
+
+import (
+    "bytes"
+    "encoding/json"
+    "fmt"
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+    "time"
+
+    "github.com/stretchr/testify/assert"
+    "github.com/stretchr/testify/require"
+)
+
+func sampleReport() *Report {
+    r := New()
+    r.LogFile = "/var/log/go-auto-proxy/init-20261018-150405.log"
+    c := r.Component("config")
+    c.Status = StatusInstalled
+    c.Files = []string{"/root/config.json"}
+
+    apt := r.Component("apt")
+    apt.Status = StatusInstalled
+    apt.Attempts = 2
+    apt.Duration = 12345 * time.Millisecond
+
+    nginx := r.Component("nginx")
+    nginx.Status = StatusInstalled
+    nginx.Version = "1.18.0"
+    nginx.Fail(fmt.Errorf("nginx -t failed\nnginx: [emerg] unexpected end of file"))
+
+    r.Component("tune")
+    r.Finish(ResultFailed)
+    return r
+}
+
+func TestComponentKeepsOrder(t *testing.T) {
+    r := New()
+    first := r.Component("apt")
+    r.Component("nginx")
+    assert.Same(t, first, r.Component("apt"))
+    assert.Len(t, r.Components, 2)
+}
+
+func TestAddFilesMakesPathsAbsolute(t *testing.T) {
+    c := &Component{}
+    c.AddFiles("config.json", "/etc/nginx/sites-available/go-auto-proxy.conf")
+    assert.True(t, filepath.IsAbs(c.Files[0]))
+    assert.Equal(t, "/etc/nginx/sites-available/go-auto-proxy.conf", c.Files[1])
+}
+
+func TestWriteJSON(t *testing.T) {
+    r := sampleReport()
+    path := filepath.Join(t.TempDir(), "init-report.json")
+    require.NoError(t, r.WriteJSON(path))
+
+    info, err := os.Stat(path)
+    require.NoError(t, err)
+    assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+    data, err := os.ReadFile(path)
+    require.NoError(t, err)
+    var decoded struct {
+        Result     string `json:"result"`
+        LogFile    string `json:"log_file"`
+        Components []map[string]interface{}
+    }
+    require.NoError(t, json.Unmarshal(data, &decoded))
+    assert.Equal(t, ResultFailed, decoded.Result)
+    assert.Equal(t, r.LogFile, decoded.LogFile)
+    require.Len(t, decoded.Components, 4)
+    assert.Equal(t, "apt", decoded.Components[1]["name"])
+    assert.Equal(t, 12.345, decoded.Components[1]["duration_seconds"])
+    assert.Equal(t, float64(2), decoded.Components[1]["attempts"])
+    assert.Equal(t, StatusFailed, decoded.Components[2]["status"])
+    assert.Equal(t, "1.18.0", decoded.Components[2]["version"])
+    assert.Len(t, decoded.Components[2]["errors"], 1)
+    assert.Equal(t, StatusSkipped, decoded.Components[3]["status"], "components without a status are skipped")
+}
+
+func TestWriteTable(t *testing.T) {
+    var buf bytes.Buffer
+    require.NoError(t, sampleReport().WriteTable(&buf))
+    lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+
+    assert.Equal(t, "COMPONENT  STATUS     VERSION  DURATION  ATTEMPTS", lines[0])
+    assert.Equal(t, "config     installed  -        -         -", lines[1])
+    assert.Equal(t, "  file: /root/config.json", lines[2])
+    assert.Equal(t, "apt        installed  -        12.3s     2", lines[3])
+    assert.Equal(t, "nginx      failed     1.18.0   -         -", lines[4])
+    // 多行錯誤只顯示第一行
+    assert.Equal(t, "  error: nginx -t failed", lines[5])
+    assert.Equal(t, "tune       skipped    -        -         -", lines[6])
+    assert.True(t, strings.HasPrefix(lines[7], "Result: failed in "))
+}
@@ -0,0 +1,159 @@
+package secret
+
+import (
+    "crypto/aes"
+    "crypto/cipher"
+    "crypto/rand"
+    "encoding/base64"
+    "encoding/json"
+    "fmt"
+    "log"
+    "os"
+    "path/filepath"
+    "strings"
+    "sync"
+)
+
+const (
+    // encryptedPrefix 標記以 AES-256-GCM 加密的值：enc:v1:base64(nonce || ciphertext)
+    encryptedPrefix = "enc:v1:"
+    // KeyEnv 直接提供 base64 編碼的金鑰
+    KeyEnv = "GO_AUTO_PROXY_SECRET_KEY"
+    // KeyFileEnv 指定金鑰檔路徑
+    KeyFileEnv = "GO_AUTO_PROXY_SECRET_KEY_FILE"
+    // CredentialName 是 systemd LoadCredential= 的名稱，讀取自 $CREDENTIALS_DIRECTORY
+    CredentialName = "go-auto-proxy.key"
+)
+
+// KeyFile 是預設金鑰檔，與 config.StateDir 相同目錄；備份 config.json 時不要一併備份
+var KeyFile = ".go-auto-proxy/secret.key"
+
+// warnDefaultKey 確保每次執行只提醒一次金鑰放在預設位置
+var warnDefaultKey sync.Once
+
+// LoadKey 依序從環境變數、金鑰檔環境變數、systemd credential 目錄與預設金鑰檔讀取金鑰；
+// 都沒有時返回 nil，表示不加密
+func LoadKey() ([]byte, error) {
+    if value := os.Getenv(KeyEnv); value != "" {
+        return ParseKey([]byte(value))
+    }
+    path := os.Getenv(KeyFileEnv)
+    if path == "" {
+        if dir := os.Getenv("CREDENTIALS_DIRECTORY"); dir != "" {
+            if _, err := os.Stat(filepath.Join(dir, CredentialName)); err == nil {
+                path = filepath.Join(dir, CredentialName)
+            }
+        }
+    }
+    if path == "" {
+        if _, err := os.Stat(KeyFile); err != nil {
+            return nil, nil
+        }
+        path = KeyFile
+        warnDefaultKey.Do(func() {
+            log.Printf("Warning: the secret key is read from %s, next to config.json; a backup of this directory exposes the encrypted secrets. Move it elsewhere and set %s or the %s systemd credential.", KeyFile, KeyFileEnv, CredentialName)
+        })
+    }
+    data, err := os.ReadFile(path)
+    if err != nil {
+        return nil, fmt.Errorf("failed to read secret key: %v", err)
+    }
+    key, err := ParseKey(data)
+    if err != nil {
+        return nil, fmt.Errorf("invalid secret key in %s: %v", path, err)
+    }
+    return key, nil
+}
+
+// ParseKey 解析 base64 編碼的 32 位元組金鑰
+func ParseKey(data []byte) ([]byte, error) {
+    key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+    if err != nil || len(key) != 32 {
+        return nil, fmt.Errorf("expected 32 random bytes in base64 (generate one with 'go-auto-proxy config keygen')")
+    }
+    return key, nil
+}
+
+// GenerateKey 返回 base64 編碼的新金鑰
+func GenerateKey() (string, error) {
+    key := make([]byte, 32)
+    if _, err := rand.Read(key); err != nil {
+        return "", err
+    }
+    return base64.StdEncoding.EncodeToString(key), nil
+}
+
+// IsEncrypted 判斷值是否為 Encrypt 的輸出
+func IsEncrypted(value string) bool {
+    return strings.HasPrefix(value, encryptedPrefix)
+}
+
+// Encrypt 以 AES-256-GCM 加密，每次使用隨機 nonce
+func Encrypt(key []byte, plaintext string) (string, error) {
+    gcm, err := newGCM(key)
+    if err != nil {
+        return "", err
+    }
+    nonce := make([]byte, gcm.NonceSize())
+    if _, err := rand.Read(nonce); err != nil {
+        return "", err
+    }
+    sealed := gcm.Seal(nonce, nonce, []byte(plaintext), nil)
+    return encryptedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt 解密 Encrypt 的輸出；金鑰錯誤或內容被竄改時返回錯誤
+func Decrypt(key []byte, value string) (string, error) {
+    if !IsEncrypted(value) {
+        return "", fmt.Errorf("value is not encrypted")
+    }
+    data, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, encryptedPrefix))
+    if err != nil {
+        return "", fmt.Errorf("malformed encrypted value: %v", err)
+    }
+    gcm, err := newGCM(key)
+    if err != nil {
+        return "", err
+    }
+    if len(data) < gcm.NonceSize() {
+        return "", fmt.Errorf("malformed encrypted value")
+    }
+    plaintext, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+    if err != nil {
+        return "", fmt.Errorf("failed to decrypt secret (wrong key?)")
+    }
+    return string(plaintext), nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+    block, err := aes.NewCipher(key)
+    if err != nil {
+        return nil, err
+    }
+    return cipher.NewGCM(block)
+}
+
+// UnmarshalJSON 透明地解密加密過的值，金鑰來源見 LoadKey
+func (s *Secret) UnmarshalJSON(data []byte) error {
+    var value string
+    if err := json.Unmarshal(data, &value); err != nil {
+        return err
+    }
+    if !IsEncrypted(value) {
+        *s = Secret(value)
+        return nil
+    }
+    key, err := LoadKey()
+    if err != nil {
+        return err
+    }
+    if key == nil {
+        return fmt.Errorf("config contains encrypted secrets but no key is available (set %s, %s or provide the %s systemd credential)", KeyEnv, KeyFileEnv, CredentialName)
+    }
+    plaintext, err := Decrypt(key, value)
+    if err != nil {
+        return err
+    }
+    *s = Secret(plaintext)
+    return nil
+}
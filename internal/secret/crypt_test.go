@@ -0,0 +1,115 @@
+package secret
+
+import (
+    "encoding/json"
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+
+    "github.com/stretchr/testify/assert"
+    "github.com/stretchr/testify/require"
+)
+
+// withKeySources 清除所有金鑰來源，並把預設金鑰檔指向暫存目錄
+func withKeySources(t *testing.T) string {
+    dir := t.TempDir()
+    original := KeyFile
+    KeyFile = filepath.Join(dir, "secret.key")
+    t.Cleanup(func() { KeyFile = original })
+    t.Setenv(KeyEnv, "")
+    t.Setenv(KeyFileEnv, "")
+    t.Setenv("CREDENTIALS_DIRECTORY", "")
+    return dir
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+    encoded, err := GenerateKey()
+    require.NoError(t, err)
+    key, err := ParseKey([]byte(encoded + "\n"))
+    require.NoError(t, err)
+
+    sealed, err := Encrypt(key, "hunter2")
+    require.NoError(t, err)
+    assert.True(t, IsEncrypted(sealed))
+    again, _ := Encrypt(key, "hunter2")
+    assert.NotEqual(t, sealed, again, "every encryption uses a fresh nonce")
+
+    plain, err := Decrypt(key, sealed)
+    require.NoError(t, err)
+    assert.Equal(t, "hunter2", plain)
+
+    other, _ := GenerateKey()
+    otherKey, _ := ParseKey([]byte(other))
+    _, err = Decrypt(otherKey, sealed)
+    assert.ErrorContains(t, err, "wrong key")
+    _, err = Decrypt(key, sealed[:len(sealed)-4]+"AAAA")
+    assert.Error(t, err, "tampered ciphertext is rejected")
+
+    _, err = ParseKey([]byte("too-short"))
+    assert.Error(t, err)
+}
+
+func TestLoadKey(t *testing.T) {
+    dir := withKeySources(t)
+    key, err := LoadKey()
+    require.NoError(t, err)
+    assert.Nil(t, key, "no key means no encryption")
+
+    write := func(name string) (string, string) {
+        encoded, err := GenerateKey()
+        require.NoError(t, err)
+        path := filepath.Join(dir, name)
+        require.NoError(t, os.WriteFile(path, []byte(encoded+"\n"), 0600))
+        return path, encoded
+    }
+    expect := func(encoded string) {
+        key, err := LoadKey()
+        require.NoError(t, err)
+        want, _ := ParseKey([]byte(encoded))
+        assert.Equal(t, want, key)
+    }
+
+    _, defaultKey := write("secret.key")
+    expect(defaultKey)
+
+    credDir := filepath.Join(dir, "credentials")
+    require.NoError(t, os.MkdirAll(credDir, 0700))
+    _, credKey := write(filepath.Join("credentials", CredentialName))
+    t.Setenv("CREDENTIALS_DIRECTORY", credDir)
+    expect(credKey)
+
+    filePath, fileKey := write("custom.key")
+    t.Setenv(KeyFileEnv, filePath)
+    expect(fileKey)
+
+    envKey, _ := GenerateKey()
+    t.Setenv(KeyEnv, envKey)
+    expect(envKey)
+
+    t.Setenv(KeyEnv, "")
+    t.Setenv(KeyFileEnv, filepath.Join(dir, "missing.key"))
+    _, err = LoadKey()
+    assert.Error(t, err, "an explicitly configured key file must exist")
+}
+
+func TestSealedRoundTrip(t *testing.T) {
+    withKeySources(t)
+    encoded, _ := GenerateKey()
+    key, _ := ParseKey([]byte(encoded))
+
+    s := newSample()
+    data, err := MarshalSealed(s, key)
+    require.NoError(t, err)
+    assert.NotContains(t, string(data), "hunter2")
+    assert.Equal(t, 5, strings.Count(string(data), encryptedPrefix), "every non-empty secret is encrypted")
+
+    var back sample
+    assert.ErrorContains(t, json.Unmarshal(data, &back), "no key is available")
+
+    t.Setenv(KeyEnv, encoded)
+    require.NoError(t, json.Unmarshal(data, &back))
+    assert.Equal(t, "hunter2", back.Password.Reveal())
+    assert.Equal(t, "k1", back.Nested.Key.Reveal())
+    assert.Equal(t, "t3", back.ByName["b"].Token.Reveal())
+}
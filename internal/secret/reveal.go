@@ -0,0 +1,249 @@
+package secret
+
+import (
+    "bytes"
+    "encoding/json"
+    "fmt"
+    "reflect"
+    "sort"
+    "strings"
+)
+
+var (
+    secretType    = reflect.TypeOf(Secret(""))
+    marshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+)
+
+// encoder 決定 Secret 欄位在輸出中的值
+type encoder func(plaintext string) (string, error)
+
+// MarshalRevealed 與 json.Marshal 相同（遵循 json 標籤、保留欄位順序），
+// 但 Secret 欄位輸出原值；用於寫入配置檔與 config show --reveal
+func MarshalRevealed(v interface{}) ([]byte, error) {
+    return marshal(v, func(plaintext string) (string, error) { return plaintext, nil })
+}
+
+// MarshalSealed 與 MarshalRevealed 相同，但 Secret 欄位以 key 加密；空值不加密
+func MarshalSealed(v interface{}, key []byte) ([]byte, error) {
+    return marshal(v, func(plaintext string) (string, error) {
+        if plaintext == "" {
+            return "", nil
+        }
+        return Encrypt(key, plaintext)
+    })
+}
+
+// MarshalIndentRevealed 是帶縮排的 MarshalRevealed
+func MarshalIndentRevealed(v interface{}, prefix, indent string) ([]byte, error) {
+    data, err := MarshalRevealed(v)
+    if err != nil {
+        return nil, err
+    }
+    return indentJSON(data, prefix, indent)
+}
+
+// MarshalIndentSealed 是帶縮排的 MarshalSealed
+func MarshalIndentSealed(v interface{}, key []byte, prefix, indent string) ([]byte, error) {
+    data, err := MarshalSealed(v, key)
+    if err != nil {
+        return nil, err
+    }
+    return indentJSON(data, prefix, indent)
+}
+
+func marshal(v interface{}, enc encoder) ([]byte, error) {
+    var buf bytes.Buffer
+    if err := enc.value(&buf, reflect.ValueOf(v)); err != nil {
+        return nil, err
+    }
+    return buf.Bytes(), nil
+}
+
+func indentJSON(data []byte, prefix, indent string) ([]byte, error) {
+    var out bytes.Buffer
+    if err := json.Indent(&out, data, prefix, indent); err != nil {
+        return nil, err
+    }
+    return out.Bytes(), nil
+}
+
+// value 只展開可能含有 Secret 的值，其餘交給 encoding/json
+func (enc encoder) value(buf *bytes.Buffer, v reflect.Value) error {
+    if !v.IsValid() {
+        buf.WriteString("null")
+        return nil
+    }
+    if v.Type() == secretType {
+        out, err := enc(v.String())
+        if err != nil {
+            return err
+        }
+        return writeJSON(buf, out)
+    }
+    if !containsSecret(v.Type(), map[reflect.Type]bool{}) || v.Type().Implements(marshalerType) {
+        return writeJSON(buf, v.Interface())
+    }
+
+    switch v.Kind() {
+    case reflect.Interface, reflect.Ptr:
+        if v.IsNil() {
+            buf.WriteString("null")
+            return nil
+        }
+        return enc.value(buf, v.Elem())
+    case reflect.Struct:
+        buf.WriteByte('{')
+        first := true
+        if err := enc.fields(buf, v, &first); err != nil {
+            return err
+        }
+        buf.WriteByte('}')
+        return nil
+    case reflect.Slice, reflect.Array:
+        if v.Kind() == reflect.Slice && v.IsNil() {
+            buf.WriteString("null")
+            return nil
+        }
+        buf.WriteByte('[')
+        for i := 0; i < v.Len(); i++ {
+            if i > 0 {
+                buf.WriteByte(',')
+            }
+            if err := enc.value(buf, v.Index(i)); err != nil {
+                return err
+            }
+        }
+        buf.WriteByte(']')
+        return nil
+    case reflect.Map:
+        if v.IsNil() {
+            buf.WriteString("null")
+            return nil
+        }
+        // 與 encoding/json 相同，依鍵排序
+        keys := v.MapKeys()
+        names := make(map[string]reflect.Value, len(keys))
+        var sorted []string
+        for _, k := range keys {
+            name := fmt.Sprint(k.Interface())
+            names[name] = k
+            sorted = append(sorted, name)
+        }
+        sort.Strings(sorted)
+        buf.WriteByte('{')
+        for i, name := range sorted {
+            if i > 0 {
+                buf.WriteByte(',')
+            }
+            if err := writeJSON(buf, name); err != nil {
+                return err
+            }
+            buf.WriteByte(':')
+            if err := enc.value(buf, v.MapIndex(names[name])); err != nil {
+                return err
+            }
+        }
+        buf.WriteByte('}')
+        return nil
+    }
+    return writeJSON(buf, v.Interface())
+}
+
+// fields 依 json 標籤輸出結構欄位，匿名嵌入的結構欄位會提升到同一層
+func (enc encoder) fields(buf *bytes.Buffer, v reflect.Value, first *bool) error {
+    t := v.Type()
+    for i := 0; i < t.NumField(); i++ {
+        field := t.Field(i)
+        tag := field.Tag.Get("json")
+        if tag == "-" {
+            continue
+        }
+        name, opts, _ := strings.Cut(tag, ",")
+        fv := v.Field(i)
+        if field.Anonymous && name == "" {
+            if fv.Kind() == reflect.Ptr {
+                if fv.IsNil() {
+                    continue
+                }
+                fv = fv.Elem()
+            }
+            if fv.Kind() == reflect.Struct {
+                if err := enc.fields(buf, fv, first); err != nil {
+                    return err
+                }
+                continue
+            }
+        }
+        if field.PkgPath != "" {
+            continue
+        }
+        if name == "" {
+            name = field.Name
+        }
+        if strings.Contains(","+opts+",", ",omitempty,") && isEmpty(fv) {
+            continue
+        }
+        if !*first {
+            buf.WriteByte(',')
+        }
+        *first = false
+        if err := writeJSON(buf, name); err != nil {
+            return err
+        }
+        buf.WriteByte(':')
+        if err := enc.value(buf, fv); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// containsSecret 判斷型別中是否可能出現 Secret；interface 無法靜態判斷，視為可能
+func containsSecret(t reflect.Type, seen map[reflect.Type]bool) bool {
+    if t == secretType || t.Kind() == reflect.Interface {
+        return true
+    }
+    if seen[t] {
+        return false
+    }
+    seen[t] = true
+    switch t.Kind() {
+    case reflect.Ptr, reflect.Slice, reflect.Array, reflect.Map:
+        return containsSecret(t.Elem(), seen)
+    case reflect.Struct:
+        for i := 0; i < t.NumField(); i++ {
+            if containsSecret(t.Field(i).Type, seen) {
+                return true
+            }
+        }
+    }
+    return false
+}
+
+// isEmpty 與 encoding/json 的 omitempty 規則相同
+func isEmpty(v reflect.Value) bool {
+    switch v.Kind() {
+    case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+        return v.Len() == 0
+    case reflect.Bool:
+        return !v.Bool()
+    case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+        return v.Int() == 0
+    case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+        return v.Uint() == 0
+    case reflect.Float32, reflect.Float64:
+        return v.Float() == 0
+    case reflect.Interface, reflect.Ptr:
+        return v.IsNil()
+    }
+    return false
+}
+
+func writeJSON(buf *bytes.Buffer, v interface{}) error {
+    data, err := json.Marshal(v)
+    if err != nil {
+        return err
+    }
+    buf.Write(data)
+    return nil
+}
@@ -0,0 +1,65 @@
+package secret
+
+import (
+    "encoding/json"
+    "fmt"
+    "os"
+)
+
+// Redacted 取代日誌與 JSON 輸出中的秘密值
+const Redacted = "[REDACTED]"
+
+// Secret 是密碼或 API token；以 fmt 或 encoding/json 輸出時一律遮蔽，
+// 只有 Reveal 與 MarshalRevealed 會返回原值
+type Secret string
+
+// Reveal 返回原值，僅在實際使用（寫入配置、呼叫 API）時呼叫
+func (s Secret) Reveal() string {
+    return string(s)
+}
+
+// String 返回遮蔽後的值；空值保持為空，以便看出尚未設定
+func (s Secret) String() string {
+    if s == "" {
+        return ""
+    }
+    return Redacted
+}
+
+// GoString 讓 %#v 也遮蔽
+func (s Secret) GoString() string {
+    return fmt.Sprintf("%q", s.String())
+}
+
+// Format 讓 %v、%+v、%s、%q 等所有動詞都只看到遮蔽後的值
+func (s Secret) Format(f fmt.State, verb rune) {
+    if verb == 'v' && f.Flag('#') {
+        fmt.Fprint(f, s.GoString())
+        return
+    }
+    fmt.Fprintf(f, fmt.FormatString(f, verb), s.String())
+}
+
+// MarshalJSON 遮蔽 JSON 輸出，如 --json 的命令結果
+func (s Secret) MarshalJSON() ([]byte, error) {
+    return json.Marshal(s.String())
+}
+
+// WriteFile 以 0600 寫入含有秘密的檔案；先寫入暫存檔再改名，
+// 中斷時不會留下寫到一半的檔案
+func WriteFile(path string, data []byte) error {
+    tmp := path + ".tmp"
+    if err := os.WriteFile(tmp, data, 0600); err != nil {
+        return err
+    }
+    // 暫存檔已存在時 WriteFile 不會改變權限
+    if err := os.Chmod(tmp, 0600); err != nil {
+        os.Remove(tmp)
+        return err
+    }
+    if err := os.Rename(tmp, path); err != nil {
+        os.Remove(tmp)
+        return err
+    }
+    return nil
+}
@@ -0,0 +1,94 @@
+package secret
+
+import (
+    "encoding/json"
+    "fmt"
+    "os"
+    "path/filepath"
+    "testing"
+
+    "github.com/stretchr/testify/assert"
+    "github.com/stretchr/testify/require"
+)
+
+type inner struct {
+    Token Secret `json:"token"`
+    Note  string `json:"note,omitempty"`
+}
+
+type Embedded struct {
+    Level int `json:"level"`
+}
+
+type sample struct {
+    Embedded
+    Name     string            `json:"name"`
+    Password Secret            `json:"password"`
+    Nested   struct{ Key Secret } `json:"nested"`
+    Items    []inner           `json:"items"`
+    ByName   map[string]inner  `json:"by_name"`
+    Ptr      *inner            `json:"ptr,omitempty"`
+    Skipped  string            `json:"-"`
+    hidden   string
+}
+
+func newSample() sample {
+    s := sample{Name: "proxy", Password: "hunter2", Items: []inner{{Token: "t1", Note: "first"}}, ByName: map[string]inner{"b": {Token: "t3"}, "a": {Token: "t2"}}}
+    s.Level = 3
+    s.Nested.Key = "k1"
+    s.hidden = "x"
+    return s
+}
+
+func TestRedaction(t *testing.T) {
+    s := newSample()
+    for _, format := range []string{"%v", "%+v", "%#v", "%s", "%q"} {
+        out := fmt.Sprintf(format, s)
+        assert.NotContains(t, out, "hunter2", format)
+        assert.NotContains(t, out, "k1", format)
+        assert.NotContains(t, out, "t1", format)
+    }
+    assert.Equal(t, `"[REDACTED]"`, fmt.Sprintf("%q", s.Password))
+    assert.Equal(t, "", Secret("").String(), "unset secrets stay visible as empty")
+
+    data, err := json.Marshal(s)
+    require.NoError(t, err)
+    assert.NotContains(t, string(data), "hunter2")
+    assert.Contains(t, string(data), `"password":"[REDACTED]"`)
+}
+
+func TestMarshalRevealed(t *testing.T) {
+    s := newSample()
+    data, err := MarshalRevealed(s)
+    require.NoError(t, err)
+    assert.JSONEq(t, `{
+        "level": 3,
+        "name": "proxy",
+        "password": "hunter2",
+        "nested": {"Key": "k1"},
+        "items": [{"token": "t1", "note": "first"}],
+        "by_name": {"a": {"token": "t2"}, "b": {"token": "t3"}}
+    }`, string(data))
+    assert.Regexp(t, `^\{"level":3,"name":"proxy","password":"hunter2"`, string(data), "field order follows the struct")
+
+    var back sample
+    require.NoError(t, json.Unmarshal(data, &back))
+    assert.Equal(t, Secret("hunter2"), back.Password)
+    assert.Equal(t, "t2", back.ByName["a"].Token.Reveal())
+
+    // 不含 Secret 的值與 encoding/json 輸出完全相同
+    plain := map[string]interface{}{"z": []int{1, 2}, "a": "<b>"}
+    expected, _ := json.Marshal(plain)
+    data, err = MarshalRevealed(plain)
+    require.NoError(t, err)
+    assert.Equal(t, string(expected), string(data))
+}
+
+func TestWriteFile(t *testing.T) {
+    path := filepath.Join(t.TempDir(), "config.json")
+    require.NoError(t, os.WriteFile(path, []byte("old"), 0644))
+    require.NoError(t, WriteFile(path, []byte("new")))
+    st, err := os.Stat(path)
+    require.NoError(t, err)
+    assert.Equal(t, os.FileMode(0600), st.Mode().Perm())
+}
@@ -0,0 +1,53 @@
+package sudo
+
+import (
+    "bytes"
+    "fmt"
+    "os"
+    "os/exec"
+    "strings"
+)
+
+// CommandFunc 定義執行命令的函數類型
+type CommandFunc func(string, ...string) *exec.Cmd
+
+// DefaultCommand 使用標準的 exec.Command
+var DefaultCommand CommandFunc = exec.Command
+
+// Command 以 root 權限建立命令，當前已是 root 時不加 sudo
+func Command(name string, args ...string) *exec.Cmd {
+    if os.Geteuid() == 0 {
+        return DefaultCommand(name, args...)
+    }
+    return DefaultCommand("sudo", append([]string{name}, args...)...)
+}
+
+// Run 以 root 權限執行命令並返回合併輸出，失敗時將輸出附在錯誤中
+func Run(name string, args ...string) ([]byte, error) {
+    out, err := Command(name, args...).CombinedOutput()
+    if err != nil {
+        return out, fmt.Errorf("%s %s failed: %v: %s", name, strings.Join(args, " "), err, strings.TrimSpace(string(out)))
+    }
+    return out, nil
+}
+
+// WriteFile 以 root 權限覆寫檔案並設定權限
+func WriteFile(path string, data []byte, perm os.FileMode) error {
+    cmd := Command("tee", path)
+    cmd.Stdin = bytes.NewReader(data)
+    var stderr bytes.Buffer
+    cmd.Stderr = &stderr
+    if err := cmd.Run(); err != nil {
+        return fmt.Errorf("failed to write %s: %v: %s", path, err, strings.TrimSpace(stderr.String()))
+    }
+    if _, err := Run("chmod", fmt.Sprintf("%o", perm), path); err != nil {
+        return err
+    }
+    return nil
+}
+
+// RemoveFile 以 root 權限刪除檔案，檔案不存在時不報錯
+func RemoveFile(path string) error {
+    _, err := Run("rm", "-f", path)
+    return err
+}
@@ -0,0 +1,48 @@
+package sudo
+
+import (
+    "os"
+    "os/exec"
+    "path/filepath"
+    "testing"
+
+    "github.com/stretchr/testify/assert"
+)
+
+// withoutSudo 直接執行命令，讓測試不依賴 sudo
+func withoutSudo(t *testing.T) {
+    original := DefaultCommand
+    DefaultCommand = func(name string, args ...string) *exec.Cmd {
+        if name == "sudo" {
+            return exec.Command(args[0], args[1:]...)
+        }
+        return exec.Command(name, args...)
+    }
+    t.Cleanup(func() { DefaultCommand = original })
+}
+
+func TestWriteFile(t *testing.T) {
+    withoutSudo(t)
+    path := filepath.Join(t.TempDir(), "managed.conf")
+
+    err := WriteFile(path, []byte("key = value\n"), 0600)
+    assert.NoError(t, err)
+
+    data, err := os.ReadFile(path)
+    assert.NoError(t, err)
+    assert.Equal(t, "key = value\n", string(data))
+
+    st, err := os.Stat(path)
+    assert.NoError(t, err)
+    assert.Equal(t, os.FileMode(0600), st.Mode().Perm(), "permissions should be applied")
+
+    assert.NoError(t, RemoveFile(path))
+    assert.NoError(t, RemoveFile(path), "removing a missing file should not fail")
+}
+
+func TestRunIncludesOutputInError(t *testing.T) {
+    withoutSudo(t)
+    _, err := Run("sh", "-c", "echo boom >&2; exit 3")
+    assert.Error(t, err)
+    assert.Contains(t, err.Error(), "boom")
+}
@@ -0,0 +1,209 @@
+package system
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "io"
+    "net/http"
+    "strings"
+    "time"
+)
+
+// 雲端服務商
+const (
+    CloudGCE    = "gce"
+    CloudAWS    = "aws"
+    CloudAzure  = "azure"
+    CloudOracle = "oracle"
+    CloudNone   = "none" // 實體機或未知環境
+)
+
+var (
+    // MetadataURL 是各雲端共用的 link-local metadata 位址，測試時替換為 httptest 伺服器
+    MetadataURL = "http://169.254.169.254"
+    // MetadataTimeout 是整體探測時間，實體機上不會有回應，因此必須很短
+    MetadataTimeout = 2 * time.Second
+)
+
+// Cloud 是從雲端 metadata 取得的主機資訊
+type Cloud struct {
+    Provider   string `json:"provider"`
+    Region     string `json:"region"`
+    Zone       string `json:"zone"`
+    InstanceID string `json:"instance_id"`
+    PublicIP   string `json:"public_ip"` // 未分配或 metadata 不提供時為空
+}
+
+// DetectCloud 並行探測各雲端的 metadata 端點，返回第一個成功的結果；
+// 皆無回應時 Provider 為 none
+func DetectCloud(ctx context.Context) Cloud {
+    ctx, cancel := context.WithTimeout(ctx, MetadataTimeout)
+    defer cancel()
+
+    probes := []func(context.Context) (Cloud, error){probeGCE, probeAWS, probeAzure, probeOracle}
+    results := make(chan Cloud, len(probes))
+    for _, probe := range probes {
+        go func(probe func(context.Context) (Cloud, error)) {
+            cloud, err := probe(ctx)
+            if err != nil {
+                cloud = Cloud{}
+            }
+            results <- cloud
+        }(probe)
+    }
+    for range probes {
+        if cloud := <-results; cloud.Provider != "" {
+            return cloud
+        }
+    }
+    return Cloud{Provider: CloudNone}
+}
+
+// metadataGet 以指定 header 請求 metadata，非 200 時返回錯誤
+func metadataGet(ctx context.Context, method, path string, header map[string]string) ([]byte, http.Header, error) {
+    req, err := http.NewRequestWithContext(ctx, method, MetadataURL+path, nil)
+    if err != nil {
+        return nil, nil, err
+    }
+    for k, v := range header {
+        req.Header.Set(k, v)
+    }
+    // 不使用代理，metadata 只能直接存取
+    client := &http.Client{Transport: &http.Transport{Proxy: nil}}
+    resp, err := client.Do(req)
+    if err != nil {
+        return nil, nil, err
+    }
+    defer resp.Body.Close()
+    body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+    if err != nil {
+        return nil, nil, err
+    }
+    if resp.StatusCode != http.StatusOK {
+        return nil, resp.Header, fmt.Errorf("%s %s: %s", method, path, resp.Status)
+    }
+    return body, resp.Header, nil
+}
+
+// probeGCE 讀取 GCE metadata，回應須帶有 Metadata-Flavor: Google
+func probeGCE(ctx context.Context) (Cloud, error) {
+    body, header, err := metadataGet(ctx, http.MethodGet, "/computeMetadata/v1/instance/?recursive=true", map[string]string{"Metadata-Flavor": "Google"})
+    if err != nil {
+        return Cloud{}, err
+    }
+    if header.Get("Metadata-Flavor") != "Google" {
+        return Cloud{}, fmt.Errorf("not a GCE metadata server")
+    }
+    var doc struct {
+        ID                json.Number `json:"id"`
+        Zone              string      `json:"zone"` // projects/<n>/zones/us-west1-b
+        NetworkInterfaces []struct {
+            AccessConfigs []struct {
+                ExternalIP string `json:"externalIp"`
+            } `json:"accessConfigs"`
+        } `json:"networkInterfaces"`
+    }
+    if err := json.Unmarshal(body, &doc); err != nil {
+        return Cloud{}, err
+    }
+    cloud := Cloud{Provider: CloudGCE, InstanceID: doc.ID.String()}
+    cloud.Zone = doc.Zone[strings.LastIndex(doc.Zone, "/")+1:]
+    if i := strings.LastIndex(cloud.Zone, "-"); i > 0 {
+        cloud.Region = cloud.Zone[:i]
+    }
+    for _, nic := range doc.NetworkInterfaces {
+        for _, ac := range nic.AccessConfigs {
+            if ac.ExternalIP != "" && cloud.PublicIP == "" {
+                cloud.PublicIP = ac.ExternalIP
+            }
+        }
+    }
+    return cloud, nil
+}
+
+// probeAWS 使用 IMDSv2：先以 PUT 取得 session token，再讀取 identity document
+func probeAWS(ctx context.Context) (Cloud, error) {
+    token, _, err := metadataGet(ctx, http.MethodPut, "/latest/api/token", map[string]string{"X-aws-ec2-metadata-token-ttl-seconds": "60"})
+    if err != nil {
+        return Cloud{}, err
+    }
+    auth := map[string]string{"X-aws-ec2-metadata-token": string(token)}
+    body, _, err := metadataGet(ctx, http.MethodGet, "/latest/dynamic/instance-identity/document", auth)
+    if err != nil {
+        return Cloud{}, err
+    }
+    var doc struct {
+        InstanceID       string `json:"instanceId"`
+        Region           string `json:"region"`
+        AvailabilityZone string `json:"availabilityZone"`
+    }
+    if err := json.Unmarshal(body, &doc); err != nil {
+        return Cloud{}, err
+    }
+    cloud := Cloud{Provider: CloudAWS, Region: doc.Region, Zone: doc.AvailabilityZone, InstanceID: doc.InstanceID}
+    // 沒有公網 IP 時此端點返回 404
+    if ip, _, err := metadataGet(ctx, http.MethodGet, "/latest/meta-data/public-ipv4", auth); err == nil {
+        cloud.PublicIP, _ = parseIP(string(ip), "4")
+    }
+    return cloud, nil
+}
+
+// probeAzure 讀取 Azure Instance Metadata Service
+func probeAzure(ctx context.Context) (Cloud, error) {
+    body, _, err := metadataGet(ctx, http.MethodGet, "/metadata/instance?api-version=2021-02-01", map[string]string{"Metadata": "true"})
+    if err != nil {
+        return Cloud{}, err
+    }
+    var doc struct {
+        Compute struct {
+            Location string `json:"location"`
+            Zone     string `json:"zone"`
+            VMID     string `json:"vmId"`
+        } `json:"compute"`
+        Network struct {
+            Interface []struct {
+                IPv4 struct {
+                    IPAddress []struct {
+                        PublicIPAddress string `json:"publicIpAddress"`
+                    } `json:"ipAddress"`
+                } `json:"ipv4"`
+            } `json:"interface"`
+        } `json:"network"`
+    }
+    if err := json.Unmarshal(body, &doc); err != nil {
+        return Cloud{}, err
+    }
+    if doc.Compute.VMID == "" {
+        return Cloud{}, fmt.Errorf("not an Azure metadata response")
+    }
+    cloud := Cloud{Provider: CloudAzure, Region: doc.Compute.Location, Zone: doc.Compute.Zone, InstanceID: doc.Compute.VMID}
+    for _, nic := range doc.Network.Interface {
+        for _, addr := range nic.IPv4.IPAddress {
+            if addr.PublicIPAddress != "" && cloud.PublicIP == "" {
+                cloud.PublicIP = addr.PublicIPAddress
+            }
+        }
+    }
+    return cloud, nil
+}
+
+// probeOracle 讀取 OCI metadata v2；OCI metadata 不包含公網 IP
+func probeOracle(ctx context.Context) (Cloud, error) {
+    body, _, err := metadataGet(ctx, http.MethodGet, "/opc/v2/instance/", map[string]string{"Authorization": "Bearer Oracle"})
+    if err != nil {
+        return Cloud{}, err
+    }
+    var doc struct {
+        ID                  string `json:"id"`
+        CanonicalRegionName string `json:"canonicalRegionName"`
+        AvailabilityDomain  string `json:"availabilityDomain"`
+    }
+    if err := json.Unmarshal(body, &doc); err != nil {
+        return Cloud{}, err
+    }
+    if doc.ID == "" {
+        return Cloud{}, fmt.Errorf("not an OCI metadata response")
+    }
+    return Cloud{Provider: CloudOracle, Region: doc.CanonicalRegionName, Zone: doc.AvailabilityDomain, InstanceID: doc.ID}, nil
+}
@@ -0,0 +1,66 @@
+package system
+
+import (
+    "context"
+    "net/http"
+    "net/http/httptest"
+    "testing"
+
+    "github.com/stretchr/testify/assert"
+)
+
+// metadataServer 模擬指定雲端的 metadata 端點，其餘路徑返回 404
+func metadataServer(t *testing.T, provider string) {
+    srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        switch {
+        case provider == CloudGCE && r.URL.Path == "/computeMetadata/v1/instance/":
+            if r.Header.Get("Metadata-Flavor") != "Google" {
+                w.WriteHeader(http.StatusForbidden)
+                return
+            }
+            w.Header().Set("Metadata-Flavor", "Google")
+            w.Write([]byte(`{"id":4520031799277581759,"zone":"projects/123456/zones/asia-east1-b",
+                "networkInterfaces":[{"ip":"10.140.0.2","accessConfigs":[{"externalIp":"35.185.174.224","type":"ONE_TO_ONE_NAT"}]}]}`))
+        case provider == CloudAWS && r.URL.Path == "/latest/api/token" && r.Method == http.MethodPut:
+            w.Write([]byte("aws-token"))
+        case provider == CloudAWS && r.Header.Get("X-aws-ec2-metadata-token") != "aws-token" && r.URL.Path != "/latest/api/token":
+            // IMDSv2 要求 token
+            w.WriteHeader(http.StatusUnauthorized)
+        case provider == CloudAWS && r.URL.Path == "/latest/dynamic/instance-identity/document":
+            w.Write([]byte(`{"instanceId":"i-0abc123","region":"us-east-1","availabilityZone":"us-east-1a"}`))
+        case provider == CloudAWS && r.URL.Path == "/latest/meta-data/public-ipv4":
+            w.Write([]byte("54.1.2.3"))
+        case provider == CloudAzure && r.URL.Path == "/metadata/instance" && r.Header.Get("Metadata") == "true":
+            w.Write([]byte(`{"compute":{"location":"westeurope","zone":"1","vmId":"02aab8a4-74ef-476e-8182-f6d2ba4166a6"},
+                "network":{"interface":[{"ipv4":{"ipAddress":[{"privateIpAddress":"10.0.0.4","publicIpAddress":"20.1.2.3"}]}}]}}`))
+        case provider == CloudOracle && r.URL.Path == "/opc/v2/instance/" && r.Header.Get("Authorization") == "Bearer Oracle":
+            w.Write([]byte(`{"id":"ocid1.instance.oc1.ap-tokyo-1.abc","canonicalRegionName":"ap-tokyo-1","availabilityDomain":"Uocm:AP-TOKYO-1-AD-1"}`))
+        default:
+            http.NotFound(w, r)
+        }
+    }))
+    t.Cleanup(srv.Close)
+
+    original := MetadataURL
+    MetadataURL = srv.URL
+    t.Cleanup(func() { MetadataURL = original })
+}
+
+func TestDetectCloud(t *testing.T) {
+    tests := []struct {
+        provider string
+        want     Cloud
+    }{
+        {CloudGCE, Cloud{Provider: CloudGCE, Region: "asia-east1", Zone: "asia-east1-b", InstanceID: "4520031799277581759", PublicIP: "35.185.174.224"}},
+        {CloudAWS, Cloud{Provider: CloudAWS, Region: "us-east-1", Zone: "us-east-1a", InstanceID: "i-0abc123", PublicIP: "54.1.2.3"}},
+        {CloudAzure, Cloud{Provider: CloudAzure, Region: "westeurope", Zone: "1", InstanceID: "02aab8a4-74ef-476e-8182-f6d2ba4166a6", PublicIP: "20.1.2.3"}},
+        {CloudOracle, Cloud{Provider: CloudOracle, Region: "ap-tokyo-1", Zone: "Uocm:AP-TOKYO-1-AD-1", InstanceID: "ocid1.instance.oc1.ap-tokyo-1.abc"}},
+        {CloudNone, Cloud{Provider: CloudNone}},
+    }
+    for _, tt := range tests {
+        t.Run(tt.provider, func(t *testing.T) {
+            metadataServer(t, tt.provider)
+            assert.Equal(t, tt.want, DetectCloud(context.Background()))
+        })
+    }
+}
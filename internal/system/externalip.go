@@ -0,0 +1,301 @@
+package system
+
+import (
+    "context"
+    "crypto/rand"
+    "encoding/binary"
+    "errors"
+    "fmt"
+    "io"
+    "net"
+    "net/http"
+    "strings"
+    "time"
+)
+
+// IPDetection 配置對外 IP 的偵測來源，記錄在 config.json 中供使用者調整
+type IPDetection struct {
+    IPv4Providers []string `json:"ipv4_providers"` // 返回純文字 IP 的 HTTP 服務
+    IPv6Providers []string `json:"ipv6_providers"`
+    STUNServers   []string `json:"stun_servers"` // 與 HTTP 來源同時查詢，如 stun.l.google.com:19302
+    Quorum        int      `json:"quorum"`       // 至少幾個來源結果一致才採用
+    Timeout       string   `json:"timeout"`      // 整體逾時，如 5s
+}
+
+// DefaultIPDetection 是預設的偵測來源
+var DefaultIPDetection = IPDetection{
+    IPv4Providers: []string{"https://api.ipify.org", "https://ipv4.icanhazip.com", "https://v4.ident.me", "https://ifconfig.me/ip"},
+    IPv6Providers: []string{"https://api6.ipify.org", "https://ipv6.icanhazip.com", "https://v6.ident.me"},
+    STUNServers:   []string{"stun.l.google.com:19302", "stun.cloudflare.com:3478"},
+    Quorum:        2,
+    Timeout:       "5s",
+}
+
+// ExternalIPs 是分別偵測到的 IPv4 與 IPv6 對外位址，偵測不到時為空
+type ExternalIPs struct {
+    IPv4 string `json:"ipv4"`
+    IPv6 string `json:"ipv6"`
+}
+
+// DetectExternalIP 並行查詢所有來源，IPv4 與 IPv6 分開偵測；
+// 結果須通過 net.ParseIP 驗證且至少 Quorum 個來源一致
+func DetectExternalIP(ctx context.Context, cfg IPDetection) (ExternalIPs, error) {
+    timeout, err := detectionTimeout(cfg)
+    if err != nil {
+        return ExternalIPs{}, err
+    }
+    ctx, cancel := context.WithTimeout(ctx, timeout)
+    defer cancel()
+
+    type result struct {
+        ip  string
+        err error
+    }
+    v4, v6 := make(chan result, 1), make(chan result, 1)
+    go func() {
+        ip, err := detectFamily(ctx, "4", cfg.IPv4Providers, cfg.STUNServers, cfg.Quorum)
+        v4 <- result{ip, err}
+    }()
+    go func() {
+        ip, err := detectFamily(ctx, "6", cfg.IPv6Providers, cfg.STUNServers, cfg.Quorum)
+        v6 <- result{ip, err}
+    }()
+    r4, r6 := <-v4, <-v6
+
+    ips := ExternalIPs{IPv4: r4.ip, IPv6: r6.ip}
+    if ips.IPv4 == "" && ips.IPv6 == "" {
+        return ips, fmt.Errorf("failed to detect external IP: IPv4: %v; IPv6: %v", r4.err, r6.err)
+    }
+    return ips, nil
+}
+
+// DetectExternalIPv6 只偵測 IPv6，用於 IPv4 已由雲端 metadata 提供的情況
+func DetectExternalIPv6(ctx context.Context, cfg IPDetection) (string, error) {
+    timeout, err := detectionTimeout(cfg)
+    if err != nil {
+        return "", err
+    }
+    ctx, cancel := context.WithTimeout(ctx, timeout)
+    defer cancel()
+    return detectFamily(ctx, "6", cfg.IPv6Providers, cfg.STUNServers, cfg.Quorum)
+}
+
+// detectionTimeout 解析整體逾時，未設定時為 5 秒
+func detectionTimeout(cfg IPDetection) (time.Duration, error) {
+    if cfg.Timeout == "" {
+        return 5 * time.Second, nil
+    }
+    d, err := time.ParseDuration(cfg.Timeout)
+    if err != nil {
+        return 0, fmt.Errorf("invalid IP detection timeout %q: %v", cfg.Timeout, err)
+    }
+    return d, nil
+}
+
+// vote 是單個來源的結果
+type vote struct {
+    source string
+    ip     string
+    err    error
+}
+
+// detectFamily 並行查詢 HTTP 與 STUN 來源並一起計票；family 為 "4" 或 "6"。
+// STUN 不能等 HTTP 結束才開始：出站 HTTPS 被靜默丟棄時 HTTP 查詢會耗盡整個逾時
+func detectFamily(ctx context.Context, family string, providers, stunServers []string, quorum int) (string, error) {
+    if quorum <= 0 {
+        quorum = 2
+    }
+    total := len(providers) + len(stunServers)
+    if total == 0 {
+        return "", fmt.Errorf("no IPv%s sources configured", family)
+    }
+    // 來源不足時不能降低門檻，否則單一來源的結果會未經比對就被採用
+    if total < quorum {
+        return "", fmt.Errorf("only %d IPv%s source(s) configured but a quorum of %d is required; add providers or STUN servers, or lower ip_detection.quorum", total, family, quorum)
+    }
+
+    ctx, cancel := context.WithCancel(ctx)
+    defer cancel()
+    votes := make(chan vote, total)
+    for _, url := range providers {
+        go func(url string) {
+            ip, err := queryHTTP(ctx, family, url)
+            votes <- vote{url, ip, err}
+        }(url)
+    }
+    for _, server := range stunServers {
+        go func(server string) {
+            ip, err := querySTUN(ctx, family, server)
+            votes <- vote{"stun:" + server, ip, err}
+        }(server)
+    }
+
+    counts := make(map[string]int)
+    var errs []string
+    for i := 0; i < total; i++ {
+        v := <-votes
+        if v.err != nil {
+            errs = append(errs, fmt.Sprintf("%s: %v", v.source, v.err))
+            continue
+        }
+        counts[v.ip]++
+        if counts[v.ip] >= quorum {
+            return v.ip, nil
+        }
+    }
+
+    if len(counts) > 0 {
+        return "", fmt.Errorf("IPv%s sources disagree or lack quorum of %d: %v", family, quorum, counts)
+    }
+    return "", fmt.Errorf("all IPv%s sources failed: %s", family, strings.Join(errs, "; "))
+}
+
+// parseIP 驗證回應內容是否為指定協議族的 IP
+func parseIP(raw, family string) (string, error) {
+    ip := net.ParseIP(strings.TrimSpace(raw))
+    if ip == nil {
+        return "", fmt.Errorf("response is not an IP address")
+    }
+    if (ip.To4() != nil) != (family == "4") {
+        return "", fmt.Errorf("got %s, expected an IPv%s address", ip, family)
+    }
+    return ip.String(), nil
+}
+
+// queryHTTP 強制以指定協議族連線，避免雙棧主機上 IPv4 來源返回 IPv6
+func queryHTTP(ctx context.Context, family, url string) (string, error) {
+    dialer := &net.Dialer{}
+    client := &http.Client{Transport: &http.Transport{
+        DialContext: func(ctx context.Context, _, addr string) (net.Conn, error) {
+            return dialer.DialContext(ctx, "tcp"+family, addr)
+        },
+    }}
+    req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+    if err != nil {
+        return "", err
+    }
+    req.Header.Set("User-Agent", "curl/8") // 部分服務依 User-Agent 決定是否返回純文字
+    resp, err := client.Do(req)
+    if err != nil {
+        return "", err
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusOK {
+        return "", fmt.Errorf("unexpected status %s", resp.Status)
+    }
+    body, err := io.ReadAll(io.LimitReader(resp.Body, 256))
+    if err != nil {
+        return "", err
+    }
+    return parseIP(string(body), family)
+}
+
+// STUN（RFC 5389）常數
+const (
+    stunBindingRequest  = 0x0001
+    stunBindingResponse = 0x0101
+    stunMagicCookie     = 0x2112A442
+    stunMappedAddress   = 0x0001
+    stunXORMappedAddr   = 0x0020
+)
+
+// querySTUN 送出 Binding Request 並從 XOR-MAPPED-ADDRESS 取得對外位址
+func querySTUN(ctx context.Context, family, server string) (string, error) {
+    conn, err := (&net.Dialer{}).DialContext(ctx, "udp"+family, server)
+    if err != nil {
+        return "", err
+    }
+    defer conn.Close()
+    if deadline, ok := ctx.Deadline(); ok {
+        conn.SetDeadline(deadline)
+    }
+
+    req := make([]byte, 20)
+    binary.BigEndian.PutUint16(req[0:], stunBindingRequest)
+    binary.BigEndian.PutUint32(req[4:], stunMagicCookie)
+    if _, err := rand.Read(req[8:20]); err != nil {
+        return "", err
+    }
+    if _, err := conn.Write(req); err != nil {
+        return "", err
+    }
+
+    buf := make([]byte, 1024)
+    n, err := conn.Read(buf)
+    if err != nil {
+        return "", err
+    }
+    ip, err := parseSTUNResponse(buf[:n], req[8:20])
+    if err != nil {
+        return "", err
+    }
+    return parseIP(ip.String(), family)
+}
+
+// parseSTUNResponse 解析 Binding Response，優先使用 XOR-MAPPED-ADDRESS
+func parseSTUNResponse(msg, txID []byte) (net.IP, error) {
+    if len(msg) < 20 || binary.BigEndian.Uint16(msg[0:]) != stunBindingResponse {
+        return nil, errors.New("not a STUN binding response")
+    }
+    if binary.BigEndian.Uint32(msg[4:]) != stunMagicCookie || string(msg[8:20]) != string(txID) {
+        return nil, errors.New("STUN transaction mismatch")
+    }
+    length := int(binary.BigEndian.Uint16(msg[2:]))
+    if 20+length > len(msg) {
+        return nil, errors.New("truncated STUN response")
+    }
+
+    var mapped net.IP
+    attrs := msg[20 : 20+length]
+    for len(attrs) >= 4 {
+        typ := binary.BigEndian.Uint16(attrs[0:])
+        size := int(binary.BigEndian.Uint16(attrs[2:]))
+        if 4+size > len(attrs) {
+            break
+        }
+        value := attrs[4 : 4+size]
+        switch typ {
+        case stunXORMappedAddr:
+            if ip := stunAddress(value, msg[4:20]); ip != nil {
+                return ip, nil
+            }
+        case stunMappedAddress:
+            mapped = stunAddress(value, nil)
+        }
+        // 屬性以 4 位元組對齊；最後一個屬性可能缺少填充，不能越界
+        next := 4 + (size+3)&^3
+        if next > len(attrs) {
+            next = len(attrs)
+        }
+        attrs = attrs[next:]
+    }
+    if mapped == nil {
+        return nil, errors.New("STUN response has no mapped address")
+    }
+    return mapped, nil
+}
+
+// stunAddress 解析位址屬性；xor 非空時以 magic cookie 與 transaction ID 還原
+func stunAddress(value, xor []byte) net.IP {
+    if len(value) < 8 {
+        return nil
+    }
+    var ip net.IP
+    switch value[1] {
+    case 0x01:
+        ip = net.IP(append([]byte(nil), value[4:8]...))
+    case 0x02:
+        if len(value) < 20 {
+            return nil
+        }
+        ip = net.IP(append([]byte(nil), value[4:20]...))
+    default:
+        return nil
+    }
+    for i := range ip {
+        if xor != nil {
+            ip[i] ^= xor[i]
+        }
+    }
+    return ip
+}
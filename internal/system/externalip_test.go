@@ -0,0 +1,173 @@
+package system
+
+import (
+    "context"
+    "encoding/binary"
+    "net"
+    "net/http"
+    "net/http/httptest"
+    "testing"
+    "time"
+
+    "github.com/stretchr/testify/assert"
+    "github.com/stretchr/testify/require"
+)
+
+// ipServer 返回固定內容的 HTTP 服務
+func ipServer(t *testing.T, body string) string {
+    srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        w.Write([]byte(body))
+    }))
+    t.Cleanup(srv.Close)
+    return srv.URL
+}
+
+// stunServer 模擬 STUN 伺服器，以 XOR-MAPPED-ADDRESS 回覆 mapped
+func stunServer(t *testing.T, mapped net.IP) string {
+    conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+    require.NoError(t, err)
+    t.Cleanup(func() { conn.Close() })
+    go func() {
+        buf := make([]byte, 1024)
+        for {
+            n, addr, err := conn.ReadFrom(buf)
+            if err != nil {
+                return
+            }
+            if n < 20 {
+                continue
+            }
+            resp := make([]byte, 32)
+            binary.BigEndian.PutUint16(resp[0:], stunBindingResponse)
+            binary.BigEndian.PutUint16(resp[2:], 12)
+            copy(resp[4:20], buf[4:20])
+            binary.BigEndian.PutUint16(resp[20:], stunXORMappedAddr)
+            binary.BigEndian.PutUint16(resp[22:], 8)
+            resp[25] = 0x01
+            binary.BigEndian.PutUint16(resp[26:], 3478^uint16(stunMagicCookie>>16))
+            ip := mapped.To4()
+            for i := 0; i < 4; i++ {
+                resp[28+i] = ip[i] ^ buf[4+i]
+            }
+            conn.WriteTo(resp, addr)
+        }
+    }()
+    return conn.LocalAddr().String()
+}
+
+func TestDetectExternalIPQuorum(t *testing.T) {
+    good := ipServer(t, "35.185.174.224\n")
+    html := ipServer(t, "<html>502 Bad Gateway</html>")
+    other := ipServer(t, "203.0.113.9")
+
+    ips, err := DetectExternalIP(context.Background(), IPDetection{
+        IPv4Providers: []string{html, good, other, good + "/again"},
+        Quorum:        2,
+        Timeout:       "2s",
+    })
+    require.NoError(t, err)
+    assert.Equal(t, "35.185.174.224", ips.IPv4)
+    assert.Empty(t, ips.IPv6)
+}
+
+func TestDetectExternalIPRejectsDisagreement(t *testing.T) {
+    _, err := DetectExternalIP(context.Background(), IPDetection{
+        IPv4Providers: []string{ipServer(t, "35.185.174.224"), ipServer(t, "203.0.113.9")},
+        Quorum:        2,
+        Timeout:       "2s",
+    })
+    assert.ErrorContains(t, err, "quorum")
+
+    _, err = DetectExternalIP(context.Background(), IPDetection{
+        IPv4Providers: []string{ipServer(t, "<html>error</html>")},
+        Quorum:        1,
+        Timeout:       "2s",
+    })
+    assert.ErrorContains(t, err, "not an IP address", "invalid bodies must never become the external IP")
+
+    _, err = DetectExternalIP(context.Background(), IPDetection{
+        IPv4Providers: []string{ipServer(t, "35.185.174.224")},
+        Timeout:       "2s",
+    })
+    assert.ErrorContains(t, err, "quorum of 2 is required", "a single source must not be trusted without agreement")
+}
+
+func TestDetectExternalIPv6(t *testing.T) {
+    ln, err := net.Listen("tcp6", "[::1]:0")
+    if err != nil {
+        t.Skip("no IPv6 loopback:", err)
+    }
+    srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        w.Write([]byte("2001:db8::7\n"))
+    }))
+    srv.Listener.Close()
+    srv.Listener = ln
+    srv.Start()
+    defer srv.Close()
+
+    ip, err := DetectExternalIPv6(context.Background(), IPDetection{
+        IPv4Providers: []string{"http://127.0.0.1:1/never-queried"},
+        IPv6Providers: []string{srv.URL, srv.URL + "/again"},
+        Quorum:        2,
+        Timeout:       "2s",
+    })
+    require.NoError(t, err)
+    assert.Equal(t, "2001:db8::7", ip)
+}
+
+func TestDetectExternalIPSTUNFallback(t *testing.T) {
+    ips, err := DetectExternalIP(context.Background(), IPDetection{
+        IPv4Providers: []string{"http://127.0.0.1:1/unreachable"},
+        STUNServers:   []string{stunServer(t, net.ParseIP("35.185.174.224")), stunServer(t, net.ParseIP("35.185.174.224"))},
+        Quorum:        2,
+        Timeout:       "2s",
+    })
+    require.NoError(t, err)
+    assert.Equal(t, "35.185.174.224", ips.IPv4)
+}
+
+func TestDetectExternalIPSTUNWhileHTTPHangs(t *testing.T) {
+    // 模擬出站 HTTPS 被靜默丟棄：HTTP 來源一直不回應，STUN 仍須在逾時前取得結果
+    hang := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        <-r.Context().Done()
+    }))
+    defer hang.Close()
+
+    start := time.Now()
+    ips, err := DetectExternalIP(context.Background(), IPDetection{
+        IPv4Providers: []string{hang.URL, hang.URL},
+        STUNServers:   []string{stunServer(t, net.ParseIP("35.185.174.224")), stunServer(t, net.ParseIP("35.185.174.224"))},
+        Quorum:        2,
+        Timeout:       "3s",
+    })
+    require.NoError(t, err)
+    assert.Equal(t, "35.185.174.224", ips.IPv4)
+    assert.Less(t, time.Since(start), 2*time.Second, "STUN must not wait for the HTTP queries to time out")
+}
+
+func TestParseSTUNResponse(t *testing.T) {
+    txID := []byte("0123456789ab")
+    msg := make([]byte, 32)
+    binary.BigEndian.PutUint16(msg[0:], stunBindingResponse)
+    binary.BigEndian.PutUint16(msg[2:], 12)
+    binary.BigEndian.PutUint32(msg[4:], stunMagicCookie)
+    copy(msg[8:], txID)
+    binary.BigEndian.PutUint16(msg[20:], stunMappedAddress)
+    binary.BigEndian.PutUint16(msg[22:], 8)
+    msg[25] = 0x01
+    copy(msg[28:], net.ParseIP("198.51.100.7").To4())
+
+    ip, err := parseSTUNResponse(msg, txID)
+    require.NoError(t, err)
+    assert.Equal(t, "198.51.100.7", ip.String())
+
+    _, err = parseSTUNResponse(msg, []byte("other-txn-id"))
+    assert.Error(t, err)
+
+    // 最後一個屬性長度不是 4 的倍數且沒有填充，不能因越界而 panic
+    unpadded := append(append([]byte(nil), msg...), 0x80, 0x22, 0x00, 0x05, 'x', 'y', 'z', 'z', 'y')
+    binary.BigEndian.PutUint16(unpadded[2:], uint16(len(unpadded)-20))
+    ip, err = parseSTUNResponse(unpadded, txID)
+    require.NoError(t, err)
+    assert.Equal(t, "198.51.100.7", ip.String())
+}
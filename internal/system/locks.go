@@ -0,0 +1,140 @@
+package system
+
+import (
+    "bufio"
+    "bytes"
+    "fmt"
+    "os"
+    "path/filepath"
+    "strconv"
+    "strings"
+    "syscall"
+)
+
+// DpkgLockFiles 是 apt 與 dpkg 使用的鎖檔，依檢查順序排列
+var DpkgLockFiles = []string{
+    "/var/lib/dpkg/lock-frontend",
+    "/var/lib/dpkg/lock",
+    "/var/lib/apt/lists/lock",
+    "/var/cache/apt/archives/lock",
+}
+
+// LockHolder 是持有鎖檔的程序
+type LockHolder struct {
+    Path    string // 被鎖住的檔案
+    PID     int    // OFD 鎖等無法得知程序時為 0
+    Name    string // /proc/<pid>/comm，最多 15 個字元
+    Command string // /proc/<pid>/cmdline
+}
+
+// String 返回易讀的描述，如 unattended-upgr (pid 1234: /usr/bin/python3 /usr/bin/unattended-upgrade)
+func (h LockHolder) String() string {
+    if h.PID <= 0 {
+        return "an unknown process"
+    }
+    name := h.Name
+    if name == "" {
+        name = "process"
+    }
+    if h.Command != "" {
+        return fmt.Sprintf("%s (pid %d: %s)", name, h.PID, h.Command)
+    }
+    return fmt.Sprintf("%s (pid %d)", name, h.PID)
+}
+
+// procLock 是 /proc/locks 中的一筆鎖
+type procLock struct {
+    pid   int
+    major uint64
+    minor uint64
+    inode uint64
+}
+
+// DpkgLockHolder 返回持有 apt/dpkg 鎖的程序，沒有被鎖住時返回 nil
+func DpkgLockHolder() (*LockHolder, error) {
+    return LockHolderOf(DpkgLockFiles)
+}
+
+// LockHolderOf 依 /proc/locks 找出持有其中任一檔案鎖的程序；檔案不存在時略過
+func LockHolderOf(paths []string) (*LockHolder, error) {
+    data, err := os.ReadFile(filepath.Join(ProcDir, "locks"))
+    if err != nil {
+        return nil, err
+    }
+    locks := parseProcLocks(data)
+    for _, path := range paths {
+        info, err := os.Stat(path)
+        if err != nil {
+            continue
+        }
+        st, ok := info.Sys().(*syscall.Stat_t)
+        if !ok {
+            continue
+        }
+        dev := uint64(st.Dev)
+        major, minor := devMajor(dev), devMinor(dev)
+        for _, l := range locks {
+            if l.inode != uint64(st.Ino) || l.major != major || l.minor != minor {
+                continue
+            }
+            holder := &LockHolder{Path: path}
+            if l.pid > 0 {
+                holder.PID = l.pid
+                holder.Name = processName(l.pid)
+                holder.Command = processCommand(l.pid)
+            }
+            return holder, nil
+        }
+    }
+    return nil, nil
+}
+
+// parseProcLocks 解析 /proc/locks，例如：
+//
+//    1: POSIX  ADVISORY  WRITE 1234 08:01:131090 0 EOF
+//
+// 等待中的鎖（以 -> 標記）不代表持有，略過
+func parseProcLocks(data []byte) []procLock {
+    var locks []procLock
+    scanner := bufio.NewScanner(bytes.NewReader(data))
+    for scanner.Scan() {
+        fields := strings.Fields(scanner.Text())
+        if len(fields) < 6 || fields[1] == "->" {
+            continue
+        }
+        pid, err := strconv.Atoi(fields[4])
+        if err != nil {
+            continue
+        }
+        parts := strings.Split(fields[5], ":")
+        if len(parts) != 3 {
+            continue
+        }
+        major, err1 := strconv.ParseUint(parts[0], 16, 64)
+        minor, err2 := strconv.ParseUint(parts[1], 16, 64)
+        inode, err3 := strconv.ParseUint(parts[2], 10, 64)
+        if err1 != nil || err2 != nil || err3 != nil {
+            continue
+        }
+        locks = append(locks, procLock{pid: pid, major: major, minor: minor, inode: inode})
+    }
+    return locks
+}
+
+// devMajor 與 devMinor 解碼 stat 的裝置號碼（與 glibc 的 major()/minor() 相同）
+func devMajor(dev uint64) uint64 {
+    return (dev>>8)&0xfff | (dev>>32)&0xfffff000
+}
+
+func devMinor(dev uint64) uint64 {
+    return dev&0xff | (dev>>12)&0xffffff00
+}
+
+// processCommand 返回程序的完整命令列
+func processCommand(pid int) string {
+    data, err := os.ReadFile(filepath.Join(ProcDir, strconv.Itoa(pid), "cmdline"))
+    if err != nil {
+        return ""
+    }
+    return strings.TrimSpace(strings.ReplaceAll(string(data), "\x00", " "))
+}
@@ -0,0 +1,68 @@
+package system
+
+import (
+    "fmt"
+    "os"
+    "path/filepath"
+    "syscall"
+    "testing"
+
+    "github.com/stretchr/testify/assert"
+    "github.com/stretchr/testify/require"
+)
+
+func TestParseProcLocks(t *testing.T) {
+    data := []byte(`1: POSIX  ADVISORY  WRITE 1234 08:01:131090 0 EOF
+1: -> POSIX  ADVISORY  WRITE 5678 08:01:131090 0 EOF
+2: OFDLCK ADVISORY  WRITE -1 fd:00:42 0 EOF
+3: FLOCK  ADVISORY  WRITE 99 00:1a:7 0 EOF
+`)
+    locks := parseProcLocks(data)
+    assert.Equal(t, []procLock{
+        {pid: 1234, major: 8, minor: 1, inode: 131090},
+        {pid: -1, major: 0xfd, minor: 0, inode: 42},
+        {pid: 99, major: 0, minor: 0x1a, inode: 7},
+    }, locks)
+}
+
+func TestLockHolderOf(t *testing.T) {
+    dir := t.TempDir()
+    lockFile := filepath.Join(dir, "lock-frontend")
+    require.NoError(t, os.WriteFile(lockFile, nil, 0640))
+    info, err := os.Stat(lockFile)
+    require.NoError(t, err)
+    st := info.Sys().(*syscall.Stat_t)
+    dev := uint64(st.Dev)
+
+    procDir := t.TempDir()
+    require.NoError(t, os.MkdirAll(filepath.Join(procDir, "4321"), 0755))
+    require.NoError(t, os.WriteFile(filepath.Join(procDir, "4321", "comm"), []byte("unattended-upgr\n"), 0644))
+    require.NoError(t, os.WriteFile(filepath.Join(procDir, "4321", "cmdline"), []byte("/usr/bin/python3\x00/usr/bin/unattended-upgrade\x00"), 0644))
+    locks := fmt.Sprintf("1: POSIX  ADVISORY  WRITE 4321 %02x:%02x:%d 0 EOF\n", devMajor(dev), devMinor(dev), st.Ino)
+    require.NoError(t, os.WriteFile(filepath.Join(procDir, "locks"), []byte(locks), 0644))
+
+    originalProc := ProcDir
+    ProcDir = procDir
+    defer func() { ProcDir = originalProc }()
+
+    holder, err := LockHolderOf([]string{filepath.Join(dir, "missing"), lockFile})
+    require.NoError(t, err)
+    require.NotNil(t, holder)
+    assert.Equal(t, lockFile, holder.Path)
+    assert.Equal(t, 4321, holder.PID)
+    assert.Equal(t, "unattended-upgr (pid 4321: /usr/bin/python3 /usr/bin/unattended-upgrade)", holder.String())
+
+    // 沒有對應的鎖時返回 nil
+    require.NoError(t, os.WriteFile(filepath.Join(procDir, "locks"), nil, 0644))
+    holder, err = LockHolderOf([]string{lockFile})
+    assert.NoError(t, err)
+    assert.Nil(t, holder)
+}
+
+func TestDevNumbers(t *testing.T) {
+    // 與 glibc 的 makedev 相同的編碼
+    major, minor := uint64(0x1103), uint64(0x10001)
+    dev := minor&0xff | (major&0xfff)<<8 | (minor&^uint64(0xff))<<12 | (major&^uint64(0xfff))<<32
+    assert.Equal(t, major, devMajor(dev))
+    assert.Equal(t, minor, devMinor(dev))
+}
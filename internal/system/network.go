@@ -0,0 +1,244 @@
+package system
+
+import (
+    "bufio"
+    "bytes"
+    "fmt"
+    "net"
+    "os"
+    "path/filepath"
+    "strconv"
+    "strings"
+)
+
+var (
+    // ProcNetDir 與 SysClassNetDir 可在測試中替換
+    ProcNetDir     = "/proc/net"
+    SysClassNetDir = "/sys/class/net"
+)
+
+// 介面類型
+const (
+    KindPhysical = "physical"
+    KindBridge   = "bridge"
+    KindTunnel   = "tunnel"
+    KindZeroTier = "zerotier"
+    KindLoopback = "loopback"
+    KindVirtual  = "virtual" // veth、dummy 等其他虛擬介面
+)
+
+// Interface 是單個網路介面及其位址
+type Interface struct {
+    Name      string    `json:"name"`
+    Kind      string    `json:"kind"`
+    Flags     []string  `json:"flags"`
+    MTU       int       `json:"mtu"`
+    MAC       string    `json:"mac,omitempty"`
+    Addresses []Address `json:"addresses"`
+    // DefaultIPv4 與 DefaultIPv6 表示該介面承載預設路由
+    DefaultIPv4 bool `json:"default_ipv4,omitempty"`
+    DefaultIPv6 bool `json:"default_ipv6,omitempty"`
+}
+
+// Address 是介面上的一個位址
+type Address struct {
+    IP        string `json:"ip"`
+    PrefixLen int    `json:"prefix_len"`
+    Scope     string `json:"scope"` // global、private、link-local 或 loopback
+}
+
+// Global 判斷是否為可公開路由的位址
+func (a Address) Global() bool {
+    return a.Scope == "global"
+}
+
+// addressScope 判斷位址範圍，ULA 與 RFC 1918 視為 private
+func addressScope(ip net.IP) string {
+    switch {
+    case ip.IsLoopback():
+        return "loopback"
+    case ip.IsLinkLocalUnicast(), ip.IsLinkLocalMulticast():
+        return "link-local"
+    case ip.IsPrivate():
+        return "private"
+    }
+    return "global"
+}
+
+// ListInterfaces 列出所有介面、位址、類型，並標記預設路由所在的介面
+func ListInterfaces() ([]Interface, error) {
+    ifaces, err := net.Interfaces()
+    if err != nil {
+        return nil, err
+    }
+    v4, v6 := DefaultRouteInterfaces()
+
+    result := make([]Interface, 0, len(ifaces))
+    for _, iface := range ifaces {
+        item := Interface{
+            Name:        iface.Name,
+            Kind:        interfaceKind(iface),
+            Flags:       strings.Split(iface.Flags.String(), "|"),
+            MTU:         iface.MTU,
+            MAC:         iface.HardwareAddr.String(),
+            Addresses:   []Address{},
+            DefaultIPv4: iface.Name == v4,
+            DefaultIPv6: iface.Name == v6,
+        }
+        addrs, err := iface.Addrs()
+        if err != nil {
+            return nil, fmt.Errorf("failed to list addresses of %s: %v", iface.Name, err)
+        }
+        for _, addr := range addrs {
+            ipnet, ok := addr.(*net.IPNet)
+            if !ok {
+                continue
+            }
+            ones, _ := ipnet.Mask.Size()
+            item.Addresses = append(item.Addresses, Address{IP: ipnet.IP.String(), PrefixLen: ones, Scope: addressScope(ipnet.IP)})
+        }
+        result = append(result, item)
+    }
+    return result, nil
+}
+
+// interfaceKind 依名稱、旗標與 sysfs 判斷介面類型
+func interfaceKind(iface net.Interface) string {
+    sys := filepath.Join(SysClassNetDir, iface.Name)
+    switch {
+    case iface.Flags&net.FlagLoopback != 0:
+        return KindLoopback
+    case strings.HasPrefix(iface.Name, "zt"):
+        return KindZeroTier
+    case exists(filepath.Join(sys, "bridge")):
+        return KindBridge
+    case iface.Flags&net.FlagPointToPoint != 0, exists(filepath.Join(sys, "tun_flags")), isTunnelType(sys),
+        strings.HasPrefix(iface.Name, "tun"), strings.HasPrefix(iface.Name, "wg"):
+        return KindTunnel
+    case exists(filepath.Join(sys, "device")):
+        return KindPhysical
+    }
+    return KindVirtual
+}
+
+// isTunnelType 檢查 ARPHRD 類型：ipip、sit、gre、ip6tnl 與無鏈路層的 tun/WireGuard
+func isTunnelType(sys string) bool {
+    data, err := os.ReadFile(filepath.Join(sys, "type"))
+    if err != nil {
+        return false
+    }
+    switch strings.TrimSpace(string(data)) {
+    case "768", "769", "776", "778", "823", "65534":
+        return true
+    }
+    return false
+}
+
+func exists(path string) bool {
+    _, err := os.Stat(path)
+    return err == nil
+}
+
+// DefaultRouteInterfaces 從 /proc/net/route 與 /proc/net/ipv6_route 找出預設路由的介面
+func DefaultRouteInterfaces() (v4, v6 string) {
+    if data, err := os.ReadFile(filepath.Join(ProcNetDir, "route")); err == nil {
+        v4 = parseRoute(data)
+    }
+    if data, err := os.ReadFile(filepath.Join(ProcNetDir, "ipv6_route")); err == nil {
+        v6 = parseIPv6Route(data)
+    }
+    return v4, v6
+}
+
+const (
+    rtfUp     = 0x1
+    rtfReject = 0x200
+)
+
+// parseRoute 解析 /proc/net/route，返回 metric 最小的預設路由介面：
+//
+//	Iface	Destination	Gateway 	Flags	RefCnt	Use	Metric	Mask	...
+//	eth0	00000000	0100000A	0003	0	0	100	00000000	...
+func parseRoute(data []byte) string {
+    best, bestMetric := "", uint64(1<<63)
+    scanner := bufio.NewScanner(bytes.NewReader(data))
+    for scanner.Scan() {
+        fields := strings.Fields(scanner.Text())
+        if len(fields) < 8 || fields[0] == "Iface" {
+            continue
+        }
+        flags, _ := strconv.ParseUint(fields[3], 16, 32)
+        metric, _ := strconv.ParseUint(fields[6], 10, 64)
+        if fields[1] != "00000000" || fields[7] != "00000000" || flags&rtfUp == 0 || flags&rtfReject != 0 {
+            continue
+        }
+        if metric < bestMetric {
+            best, bestMetric = fields[0], metric
+        }
+    }
+    return best
+}
+
+// parseIPv6Route 解析 /proc/net/ipv6_route，欄位依序為目的位址、前綴長度、來源、來源前綴、
+// 下一跳、metric、refcnt、use、flags 與介面，數值皆為十六進位
+func parseIPv6Route(data []byte) string {
+    best, bestMetric := "", uint64(1<<63)
+    scanner := bufio.NewScanner(bytes.NewReader(data))
+    for scanner.Scan() {
+        fields := strings.Fields(scanner.Text())
+        if len(fields) < 10 {
+            continue
+        }
+        if strings.Trim(fields[0], "0") != "" || fields[1] != "00" {
+            continue
+        }
+        metric, _ := strconv.ParseUint(fields[5], 16, 64)
+        flags, _ := strconv.ParseUint(fields[8], 16, 32)
+        if flags&rtfUp == 0 || flags&rtfReject != 0 || fields[9] == "lo" {
+            continue
+        }
+        if metric < bestMetric {
+            best, bestMetric = fields[9], metric
+        }
+    }
+    return best
+}
+
+// primaryIPv4 優先選擇預設路由介面上的 IPv4，其次是實體介面上的非 link-local 位址
+func primaryIPv4(ifaces []Interface) string {
+    for _, preferDefault := range []bool{true, false} {
+        for _, iface := range ifaces {
+            if preferDefault && !iface.DefaultIPv4 || !preferDefault && iface.Kind != KindPhysical {
+                continue
+            }
+            for _, addr := range iface.Addresses {
+                ip := net.ParseIP(addr.IP)
+                if ip.To4() != nil && (addr.Scope == "global" || addr.Scope == "private") {
+                    return addr.IP
+                }
+            }
+        }
+    }
+    return ""
+}
+
+// globalIPv6 返回可公開路由的 IPv6 位址，預設路由介面上的位址排在最前
+func globalIPv6(ifaces []Interface) []string {
+    var first, rest []string
+    for _, iface := range ifaces {
+        if iface.Kind == KindZeroTier || iface.Kind == KindLoopback {
+            continue
+        }
+        for _, addr := range iface.Addresses {
+            if net.ParseIP(addr.IP).To4() != nil || !addr.Global() {
+                continue
+            }
+            if iface.DefaultIPv6 {
+                first = append(first, addr.IP)
+            } else {
+                rest = append(rest, addr.IP)
+            }
+        }
+    }
+    return append(first, rest...)
+}
@@ -0,0 +1,88 @@
+package system
+
+import (
+    "net"
+    "os"
+    "path/filepath"
+    "testing"
+
+    "github.com/stretchr/testify/assert"
+    "github.com/stretchr/testify/require"
+)
+
+const procRoute = `Iface	Destination	Gateway 	Flags	RefCnt	Use	Metric	Mask		MTU	Window	IRTT
+docker0	000011AC	00000000	0001	0	0	0	0000FFFF	0	0	0
+ztabcdef12	0011930A	00000000	0001	0	0	0	00FFFFFF	0	0	0
+wg0	00000000	00000000	0001	0	0	500	00000000	0	0	0
+ens4	00000000	0100800A	0003	0	0	100	00000000	0	0	0
+ens4	0000800A	00000000	0001	0	0	100	00FFFFFF	0	0	0
+`
+
+const procIPv6Route = `00000000000000000000000000000000 00 00000000000000000000000000000000 00 00000000000000000000000000000000 ffffffff 00000001 00000000 00200200       lo
+20010db8000000000000000000000000 40 00000000000000000000000000000000 00 00000000000000000000000000000000 00000100 00000001 00000000 00000001     ens4
+00000000000000000000000000000000 00 00000000000000000000000000000000 00 fe800000000000000000000000000001 00000400 00000001 00000000 00000003     ens4
+fe800000000000000000000000000000 40 00000000000000000000000000000000 00 00000000000000000000000000000000 00000100 00000001 00000000 00000001  docker0
+`
+
+func TestParseRoute(t *testing.T) {
+    assert.Equal(t, "ens4", parseRoute([]byte(procRoute)), "lowest metric default route wins")
+    assert.Empty(t, parseRoute([]byte("Iface\tDestination\n")))
+}
+
+func TestParseIPv6Route(t *testing.T) {
+    assert.Equal(t, "ens4", parseIPv6Route([]byte(procIPv6Route)), "the unreachable default on lo is ignored")
+}
+
+func TestDefaultRouteInterfaces(t *testing.T) {
+    dir := t.TempDir()
+    require.NoError(t, os.WriteFile(filepath.Join(dir, "route"), []byte(procRoute), 0644))
+    require.NoError(t, os.WriteFile(filepath.Join(dir, "ipv6_route"), []byte(procIPv6Route), 0644))
+    original := ProcNetDir
+    ProcNetDir = dir
+    defer func() { ProcNetDir = original }()
+
+    v4, v6 := DefaultRouteInterfaces()
+    assert.Equal(t, "ens4", v4)
+    assert.Equal(t, "ens4", v6)
+}
+
+func TestInterfaceKind(t *testing.T) {
+    dir := t.TempDir()
+    original := SysClassNetDir
+    SysClassNetDir = dir
+    defer func() { SysClassNetDir = original }()
+
+    require.NoError(t, os.MkdirAll(filepath.Join(dir, "ens4", "device"), 0755))
+    require.NoError(t, os.MkdirAll(filepath.Join(dir, "docker0", "bridge"), 0755))
+    require.NoError(t, os.MkdirAll(filepath.Join(dir, "ip6tnl0"), 0755))
+    require.NoError(t, os.WriteFile(filepath.Join(dir, "ip6tnl0", "type"), []byte("769\n"), 0644))
+
+    assert.Equal(t, KindLoopback, interfaceKind(net.Interface{Name: "lo", Flags: net.FlagLoopback}))
+    assert.Equal(t, KindPhysical, interfaceKind(net.Interface{Name: "ens4"}))
+    assert.Equal(t, KindBridge, interfaceKind(net.Interface{Name: "docker0"}))
+    assert.Equal(t, KindZeroTier, interfaceKind(net.Interface{Name: "ztabcdef12"}))
+    assert.Equal(t, KindTunnel, interfaceKind(net.Interface{Name: "ip6tnl0"}))
+    assert.Equal(t, KindTunnel, interfaceKind(net.Interface{Name: "wg0"}))
+    assert.Equal(t, KindVirtual, interfaceKind(net.Interface{Name: "veth1234"}))
+}
+
+func TestPrimaryAddresses(t *testing.T) {
+    ifaces := []Interface{
+        {Name: "docker0", Kind: KindBridge, Addresses: []Address{{IP: "172.17.0.1", Scope: "private"}}},
+        {Name: "ztabcdef12", Kind: KindZeroTier, Addresses: []Address{{IP: "10.147.17.5", Scope: "private"}, {IP: "fd80:56c2:e21c::1", Scope: "private"}}},
+        {Name: "ens4", Kind: KindPhysical, DefaultIPv4: true, DefaultIPv6: true, Addresses: []Address{
+            {IP: "169.254.1.1", Scope: "link-local"},
+            {IP: "10.128.0.2", Scope: "private"},
+            {IP: "2001:db8::2", Scope: "global"},
+            {IP: "fe80::1", Scope: "link-local"},
+        }},
+        {Name: "ens5", Kind: KindPhysical, Addresses: []Address{{IP: "2001:db8:1::5", Scope: "global"}}},
+    }
+    assert.Equal(t, "10.128.0.2", primaryIPv4(ifaces))
+    assert.Equal(t, []string{"2001:db8::2", "2001:db8:1::5"}, globalIPv6(ifaces))
+
+    ifaces[2].DefaultIPv4 = false
+    assert.Equal(t, "10.128.0.2", primaryIPv4(ifaces), "falls back to physical interfaces")
+    assert.Equal(t, "private", addressScope(net.ParseIP("fd80::1")))
+    assert.Equal(t, "global", addressScope(net.ParseIP("35.185.174.224")))
+}
@@ -0,0 +1,218 @@
+package system
+
+import (
+    "bufio"
+    "bytes"
+    "encoding/hex"
+    "fmt"
+    "net"
+    "os"
+    "path/filepath"
+    "strconv"
+    "strings"
+)
+
+// ProcDir 是 procfs 的掛載點，用於對應 socket 與程序
+var ProcDir = "/proc"
+
+// tcpListen 是 /proc/net/tcp 中 LISTEN 狀態的代碼
+const tcpListen = "0A"
+
+// Listener 是一個處於 LISTEN 狀態的 TCP socket
+type Listener struct {
+    Addr    string `json:"addr"`
+    Port    int    `json:"port"`
+    Inode   uint64 `json:"inode"`
+    PID     int    `json:"pid"`     // 無權限讀取其他使用者的 /proc/<pid>/fd 時為 0
+    Process string `json:"process"` // 取自 /proc/<pid>/comm
+}
+
+// Listeners 解析 /proc/net/tcp 與 tcp6，並透過 /proc/<pid>/fd 對應到擁有者程序
+func Listeners() ([]Listener, error) {
+    var listeners []Listener
+    for _, name := range []string{"tcp", "tcp6"} {
+        data, err := os.ReadFile(filepath.Join(ProcNetDir, name))
+        if os.IsNotExist(err) {
+            continue
+        }
+        if err != nil {
+            return nil, err
+        }
+        listeners = append(listeners, parseProcNetTCP(data)...)
+    }
+
+    owners := socketOwners()
+    for i := range listeners {
+        if pid, ok := owners[listeners[i].Inode]; ok {
+            listeners[i].PID = pid
+            listeners[i].Process = processName(pid)
+        }
+    }
+    return listeners, nil
+}
+
+// PortListeners 返回監聽指定端口的 socket
+func PortListeners(port int) ([]Listener, error) {
+    all, err := Listeners()
+    if err != nil {
+        return nil, err
+    }
+    var matched []Listener
+    for _, l := range all {
+        if l.Port == port {
+            matched = append(matched, l)
+        }
+    }
+    return matched, nil
+}
+
+// CheckPortOwner 確認端口空閒或只被 allowed 中的程式占用，否則返回說明占用者的錯誤
+func CheckPortOwner(port int, allowed ...string) error {
+    listeners, err := PortListeners(port)
+    if err != nil {
+        return fmt.Errorf("failed to inspect listening sockets: %v", err)
+    }
+    for _, l := range listeners {
+        owned := false
+        for _, name := range allowed {
+            if l.Process == name {
+                owned = true
+            }
+        }
+        if owned {
+            continue
+        }
+        if l.PID == 0 {
+            return fmt.Errorf("port %d is already in use on %s by an unknown process (run as root to identify it)", port, l.Addr)
+        }
+        return fmt.Errorf("port %d is already in use on %s by %s (pid %d)", port, l.Addr, l.Process, l.PID)
+    }
+    return nil
+}
+
+// FreePort 在 [low, high] 範圍內選擇沒有任何程序監聽、且可以綁定的端口
+func FreePort(low, high int) (int, error) {
+    if low <= 0 || high > 65535 || low > high {
+        return 0, fmt.Errorf("invalid port range %d-%d", low, high)
+    }
+    listeners, err := Listeners()
+    if err != nil {
+        return 0, err
+    }
+    used := make(map[int]bool)
+    for _, l := range listeners {
+        used[l.Port] = true
+    }
+    for port := low; port <= high; port++ {
+        if used[port] {
+            continue
+        }
+        ln, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+        if err != nil {
+            continue
+        }
+        ln.Close()
+        return port, nil
+    }
+    return 0, fmt.Errorf("no free port in range %d-%d", low, high)
+}
+
+// ParsePortRange 解析 "20000-40000" 形式的範圍
+func ParsePortRange(s string) (low, high int, err error) {
+    lo, hi, ok := strings.Cut(s, "-")
+    if !ok {
+        return 0, 0, fmt.Errorf("invalid port range %q (expected low-high)", s)
+    }
+    if low, err = strconv.Atoi(strings.TrimSpace(lo)); err != nil {
+        return 0, 0, fmt.Errorf("invalid port range %q: %v", s, err)
+    }
+    if high, err = strconv.Atoi(strings.TrimSpace(hi)); err != nil {
+        return 0, 0, fmt.Errorf("invalid port range %q: %v", s, err)
+    }
+    return low, high, nil
+}
+
+// parseProcNetTCP 解析 /proc/net/tcp{,6} 中處於 LISTEN 的項目：
+//
+//	sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+//	0: 00000000:01BB 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 24116 ...
+func parseProcNetTCP(data []byte) []Listener {
+    var listeners []Listener
+    scanner := bufio.NewScanner(bytes.NewReader(data))
+    for scanner.Scan() {
+        fields := strings.Fields(scanner.Text())
+        if len(fields) < 10 || fields[3] != tcpListen {
+            continue
+        }
+        host, portHex, ok := strings.Cut(fields[1], ":")
+        if !ok {
+            continue
+        }
+        port, err := strconv.ParseUint(portHex, 16, 16)
+        if err != nil {
+            continue
+        }
+        ip := parseProcIP(host)
+        if ip == nil {
+            continue
+        }
+        inode, _ := strconv.ParseUint(fields[9], 10, 64)
+        listeners = append(listeners, Listener{Addr: ip.String(), Port: int(port), Inode: inode})
+    }
+    return listeners
+}
+
+// parseProcIP 解析 procfs 中的位址：以 32 位元為單位的主機位元組序（小端序）十六進位
+func parseProcIP(s string) net.IP {
+    raw, err := hex.DecodeString(s)
+    if err != nil || (len(raw) != net.IPv4len && len(raw) != net.IPv6len) {
+        return nil
+    }
+    ip := make(net.IP, len(raw))
+    for word := 0; word < len(raw); word += 4 {
+        for i := 0; i < 4; i++ {
+            ip[word+i] = raw[word+3-i]
+        }
+    }
+    return ip
+}
+
+// socketOwners 掃描 /proc/<pid>/fd，建立 socket inode 到 PID 的對應；無權限的程序會被略過
+func socketOwners() map[uint64]int {
+    owners := make(map[uint64]int)
+    entries, err := os.ReadDir(ProcDir)
+    if err != nil {
+        return owners
+    }
+    for _, entry := range entries {
+        pid, err := strconv.Atoi(entry.Name())
+        if err != nil {
+            continue
+        }
+        fdDir := filepath.Join(ProcDir, entry.Name(), "fd")
+        fds, err := os.ReadDir(fdDir)
+        if err != nil {
+            continue
+        }
+        for _, fd := range fds {
+            target, err := os.Readlink(filepath.Join(fdDir, fd.Name()))
+            if err != nil || !strings.HasPrefix(target, "socket:[") {
+                continue
+            }
+            inode, err := strconv.ParseUint(strings.TrimSuffix(strings.TrimPrefix(target, "socket:["), "]"), 10, 64)
+            if err == nil {
+                owners[inode] = pid
+            }
+        }
+    }
+    return owners
+}
+
+// processName 讀取 /proc/<pid>/comm
+func processName(pid int) string {
+    data, err := os.ReadFile(filepath.Join(ProcDir, strconv.Itoa(pid), "comm"))
+    if err != nil {
+        return ""
+    }
+    return strings.TrimSpace(string(data))
+}
@@ -0,0 +1,68 @@
+package system
+
+import (
+    "os"
+    "path/filepath"
+    "testing"
+
+    "github.com/stretchr/testify/assert"
+    "github.com/stretchr/testify/require"
+)
+
+const procNetTCP = `  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 00000000:01BB 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 24116 1 0000000000000000 100 0 0 10 0
+   1: 0100007F:0050 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 24117 1 0000000000000000 100 0 0 10 0
+   2: 0A80000A:01BB 0B00A8C0:D431 01 00000000:00000000 00:00000000 00000000     0        0 24118 1 0000000000000000 100 0 0 10 0
+`
+
+const procNetTCP6 = `  sl  local_address                         remote_address                        st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 00000000000000000000000001000000:0016 00000000000000000000000000000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 31337 1 0000000000000000 100 0 0 10 0
+`
+
+func TestParseProcNetTCP(t *testing.T) {
+    listeners := parseProcNetTCP([]byte(procNetTCP))
+    require.Len(t, listeners, 2, "established connections are skipped")
+    assert.Equal(t, Listener{Addr: "0.0.0.0", Port: 443, Inode: 24116}, listeners[0])
+    assert.Equal(t, Listener{Addr: "127.0.0.1", Port: 80, Inode: 24117}, listeners[1])
+
+    listeners = parseProcNetTCP([]byte(procNetTCP6))
+    require.Len(t, listeners, 1)
+    assert.Equal(t, "::1", listeners[0].Addr)
+    assert.Equal(t, 22, listeners[0].Port)
+}
+
+func TestListenersOwners(t *testing.T) {
+    netDir, procDir := t.TempDir(), t.TempDir()
+    require.NoError(t, os.WriteFile(filepath.Join(netDir, "tcp"), []byte(procNetTCP), 0644))
+    fdDir := filepath.Join(procDir, "812", "fd")
+    require.NoError(t, os.MkdirAll(fdDir, 0755))
+    require.NoError(t, os.Symlink("socket:[24116]", filepath.Join(fdDir, "6")))
+    require.NoError(t, os.Symlink("/dev/null", filepath.Join(fdDir, "0")))
+    require.NoError(t, os.WriteFile(filepath.Join(procDir, "812", "comm"), []byte("nginx\n"), 0644))
+    originalNet, originalProc := ProcNetDir, ProcDir
+    ProcNetDir, ProcDir = netDir, procDir
+    defer func() { ProcNetDir, ProcDir = originalNet, originalProc }()
+
+    listeners, err := PortListeners(443)
+    require.NoError(t, err)
+    require.Len(t, listeners, 1)
+    assert.Equal(t, 812, listeners[0].PID)
+    assert.Equal(t, "nginx", listeners[0].Process)
+
+    assert.NoError(t, CheckPortOwner(443, "nginx"))
+    assert.ErrorContains(t, CheckPortOwner(443, "trojan-go"), "nginx (pid 812)")
+    assert.ErrorContains(t, CheckPortOwner(80), "unknown process")
+    assert.NoError(t, CheckPortOwner(8443))
+}
+
+func TestParsePortRange(t *testing.T) {
+    low, high, err := ParsePortRange("20000-40000")
+    require.NoError(t, err)
+    assert.Equal(t, 20000, low)
+    assert.Equal(t, 40000, high)
+
+    _, _, err = ParsePortRange("20000")
+    assert.Error(t, err)
+    _, err = FreePort(40000, 20000)
+    assert.Error(t, err)
+}
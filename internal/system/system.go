@@ -1,11 +1,12 @@
 package system
 
 import (
+    "context"
     "crypto/rand"
     "encoding/hex"
-    "io"
-    "net"
-    "net/http"
+    "go-auto-proxy/internal/dns"
+    "go-auto-proxy/internal/fail2ban"
+    "go-auto-proxy/internal/secret"
     "os"
     "path/filepath"
     "runtime"
@@ -13,34 +14,77 @@ import (
 )
 
 type SystemInfo struct {
-    OS           string `json:"os"`
-    Version      string `json:"version"`
-    Architecture string `json:"architecture"`
-    ExternalIP   string `json:"external_ip"`
-    InternalIP   string `json:"internal_ip"`
+    OS           string      `json:"os"`
+    Version      string      `json:"version"`
+    Architecture string      `json:"architecture"`
+    ExternalIP   string      `json:"external_ip"`
+    ExternalIPv6 string      `json:"external_ipv6"`
+    IPDetection  IPDetection `json:"ip_detection"` // 對外 IP 偵測來源
+    Cloud        Cloud       `json:"cloud"`        // 雲端服務商 metadata
+    InternalIP   string      `json:"internal_ip"`
+    IPv6         []string    `json:"ipv6"`       // 可公開路由的 IPv6 位址
+    Interfaces   []Interface `json:"interfaces"` // 各網路介面與位址
     ZeroTier     struct {
-        NetworkID string `json:"network_id"`
+        NetworkID   string   `json:"network_id"`
+        AssignedIPs []string `json:"assigned_ips"` // 加入網路後分配的位址
+        Device      string   `json:"device"`       // ZeroTier 網路介面，如 ztabcdef12
     } `json:"zerotier"`
     AcmeSH struct {
-        Path     string `json:"path"`
-        Provider string `json:"provider"`
+        Path      string `json:"path"`
+        Provider  string `json:"provider"`
+        Client    string `json:"client"`    // acme.sh、native 或 selfsign
+        Domain    string `json:"domain"`
+        Email     string `json:"email"`
+        Challenge string `json:"challenge"` // http-01、tls-alpn-01 或 dns-01
+        Webroot   string `json:"webroot"`
+        CertPath  string `json:"cert_path"`
+        KeyPath   string `json:"key_path"`
+        CAPath    string `json:"ca_path"` // selfsign 模式下的本地 CA
     } `json:"acme_sh"`
     TrojanGo struct {
-        Port     int    `json:"port"`
-        Password string `json:"password"`
+        Port       int           `json:"port"`
+        Password   secret.Secret `json:"password"`
+        RemoteAddr string        `json:"remote_addr"` // 客戶端連線位址，留空時使用網域或對外 IP
+        Mode       string        `json:"mode"`        // public 或 zerotier（僅在 ZeroTier 位址上監聽）
+        PortRange  string        `json:"port_range"`  // --port auto 時選擇端口的範圍
     } `json:"trojan_go"`
+    Firewall struct {
+        Backend         string   `json:"backend"`          // auto、ufw、firewalld、nftables、iptables 或 none
+        ZeroTier        bool     `json:"zerotier"`         // 放行 ZeroTier 的 UDP 端口，讓節點之間能直接連線
+        ZeroTierSources []string `json:"zerotier_sources"` // 非空時 ZeroTier UDP 端口只允許這些來源
+    } `json:"firewall"`
+    Tune struct {
+        Profile string `json:"profile"` // conservative、throughput、low-memory 或 none
+    } `json:"tune"`
+    // Install 覆寫安裝步驟（apt、trojan-go、acme.sh、nginx、fail2ban、zerotier）的超時與重試
+    Install  map[string]StepOverride `json:"install,omitempty"`
+    DNS      dns.Config              `json:"dns"` // DNS-01 驗證與動態 DNS 使用的服務商
     Fail2Ban struct {
-        MonitoredItems []string `json:"monitored_items"`
+        MonitoredItems []string                     `json:"monitored_items"`
+        BanTime        string                       `json:"bantime"`
+        FindTime       string                       `json:"findtime"`
+        MaxRetry       int                          `json:"maxretry"`
+        Jails          map[string]fail2ban.Settings `json:"jails,omitempty"` // 各 jail 的個別設定
+        Allowlist      []string                     `json:"allowlist"`       // 永不封鎖的 IP 或 CIDR
     } `json:"fail2ban"`
 }
 
+// StepOverride 是 install.<step> 的超時與重試覆寫，時間以 Go duration 字串表示（如 "10m"）
+type StepOverride struct {
+    Timeout  string `json:"timeout,omitempty"`
+    Attempts int    `json:"attempts,omitempty"`
+    Backoff  string `json:"backoff,omitempty"`
+    LockWait string `json:"lock_wait,omitempty"` // 等待 dpkg 鎖的上限，僅用於 apt 步驟
+}
+
 // ReadFileFunc 定義讀取檔案的函數類型
 type ReadFileFunc func(string) ([]byte, error)
 
 // DefaultReadFile 使用標準的 os.ReadFile
 var DefaultReadFile ReadFileFunc = os.ReadFile
 
-func GetSystemInfo() SystemInfo {
+// GetSystemInfo 收集系統資訊與預設配置；ctx 取消時雲端 metadata 與對外 IP 偵測會提前結束
+func GetSystemInfo(ctx context.Context) SystemInfo {
     info := SystemInfo{
         OS:           runtime.GOOS,
         Architecture: runtime.GOARCH,
@@ -63,44 +107,34 @@ func GetSystemInfo() SystemInfo {
         }
     }
 
-    // 內部 IP
-    addrs, err := net.InterfaceAddrs()
-    if err == nil {
-        for _, addr := range addrs {
-            if ipnet, ok := addr.(*net.IPNet); ok && !ipnet.IP.IsLoopback() {
-                if ipnet.IP.To4() != nil {
-                    info.InternalIP = ipnet.IP.String()
-                    break
-                }
-            }
-        }
+    // 內部 IP：以預設路由所在介面為準，避免選到 docker0、zt* 或 link-local 位址
+    if ifaces, err := ListInterfaces(); err == nil {
+        info.Interfaces = ifaces
+        info.InternalIP = primaryIPv4(ifaces)
+        info.IPv6 = globalIPv6(ifaces)
     }
     if info.InternalIP == "" {
         info.InternalIP = "unknown"
     }
 
     // 對外 IP
-    resp, err := http.Get("https://api.ipify.org")
-    if err == nil {
-        defer resp.Body.Close()
-        body, err := io.ReadAll(resp.Body)
-        if err == nil {
-            info.ExternalIP = string(body)
+    // 雲端 metadata 提供公網 IPv4 時直接採用，不依賴第三方服務；metadata 不提供 IPv6，仍需另外偵測
+    info.Cloud = DetectCloud(ctx)
+    info.IPDetection = DefaultIPDetection
+    info.ExternalIP = "unknown"
+    if info.Cloud.PublicIP != "" {
+        info.ExternalIP = info.Cloud.PublicIP
+        if ipv6, err := DetectExternalIPv6(ctx, info.IPDetection); err == nil {
+            info.ExternalIPv6 = ipv6
         }
-    }
-    if info.ExternalIP == "" {
-        resp, err := http.Get("http://ifconfig.me")
-        if err == nil {
-            defer resp.Body.Close()
-            body, err := io.ReadAll(resp.Body)
-            if err == nil {
-                info.ExternalIP = string(body)
-            }
+    } else if ips, err := DetectExternalIP(ctx, info.IPDetection); err == nil {
+        info.ExternalIPv6 = ips.IPv6
+        if ips.IPv4 != "" {
+            info.ExternalIP = ips.IPv4
+        } else {
+            info.ExternalIP = ips.IPv6
         }
     }
-    if info.ExternalIP == "" {
-        info.ExternalIP = "unknown"
-    }
 
     // ZeroTier 預設值
     info.ZeroTier.NetworkID = "" // 留空，待用戶配置
@@ -109,13 +143,27 @@ func GetSystemInfo() SystemInfo {
     homeDir, _ := os.UserHomeDir()
     info.AcmeSH.Path = filepath.Join(homeDir, ".acme.sh", "acme.sh")
     info.AcmeSH.Provider = "letsencrypt" // 預設使用 Let’s Encrypt
+    info.AcmeSH.Client = "acme.sh"
+    info.AcmeSH.Challenge = "http-01"
 
     // trojan-go 預設值
     info.TrojanGo.Port = 443 // 預設 HTTPS 端口
-    info.TrojanGo.Password = generateRandomPassword(16) // 隨機生成 16 字節密碼
+    info.TrojanGo.Password = secret.Secret(generateRandomPassword(16)) // 隨機生成 16 字節密碼
+    info.TrojanGo.Mode = "public"
+    info.TrojanGo.PortRange = "20000-40000"
+
+    // 防火牆預設值
+    info.Firewall.Backend = "auto"
+    info.Firewall.ZeroTier = true
+
+    // 核心調校預設值
+    info.Tune.Profile = "conservative"
 
     // fail2ban 預設值
     info.Fail2Ban.MonitoredItems = []string{"ssh"} // 預設監控 SSH
+    info.Fail2Ban.BanTime = "1h"
+    info.Fail2Ban.FindTime = "10m"
+    info.Fail2Ban.MaxRetry = 5
 
     return info
 }
@@ -1,6 +1,7 @@
 package system
 
 import (
+    "context"
     "net/http"
     "net/http/httptest"
     "os"
@@ -29,12 +30,21 @@ func TestGetSystemInfo(t *testing.T) {
     }
     defer func() { DefaultReadFile = originalReadFile }()
 
+    // 將對外 IP 偵測指向模擬伺服器
+    originalDetection := DefaultIPDetection
+    DefaultIPDetection = IPDetection{IPv4Providers: []string{ts.URL + "/a", ts.URL + "/b"}, Quorum: 2, Timeout: "2s"}
+    defer func() { DefaultIPDetection = originalDetection }()
+    originalMetadata := MetadataURL
+    MetadataURL = ts.URL
+    defer func() { MetadataURL = originalMetadata }()
+
     // 測試 GetSystemInfo
-    info := GetSystemInfo()
+    info := GetSystemInfo(context.Background())
 
     assert.Equal(t, runtime.GOOS, info.OS, "OS should match runtime.GOOS")
     assert.Equal(t, "Ubuntu 22.04.3 LTS", info.Version, "Version should match /etc/os-release")
     assert.Equal(t, runtime.GOARCH, info.Architecture, "Architecture should match runtime.GOARCH")
     assert.NotEmpty(t, info.InternalIP, "InternalIP should not be empty")
     assert.Contains(t, info.ExternalIP, "35.185.174.224", "ExternalIP should match mock server response")
+    assert.Equal(t, CloudNone, info.Cloud.Provider, "the echo server is not a metadata service")
 }
\ No newline at end of file
@@ -0,0 +1,243 @@
+package systemd
+
+import (
+    "bytes"
+    "fmt"
+    "go-auto-proxy/internal/sudo"
+    "log"
+    "os"
+    "path/filepath"
+    "strings"
+    "text/template"
+)
+
+// UnitDir 存放本工具安裝的 systemd unit 檔案
+var UnitDir = "/etc/systemd/system"
+
+// Timer 描述一個以 oneshot service 執行的定時任務
+type Timer struct {
+    Name             string // unit 名稱，不含副檔名
+    Description      string
+    ExecStart        string
+    WorkingDirectory string
+    OnCalendar       string // 例如 daily 或 *:0/5
+    RandomizedDelay  string // 例如 1h，避免多台主機同時執行；留空表示不延遲
+}
+
+var serviceTemplate = template.Must(template.New("service").Parse(`# Managed by go-auto-proxy
+[Unit]
+Description={{.Description}}
+After=network-online.target
+Wants=network-online.target
+
+[Service]
+Type=oneshot
+{{- if .WorkingDirectory}}
+WorkingDirectory={{.WorkingDirectory}}
+{{- end}}
+ExecStart={{.ExecStart}}
+`))
+
+var timerTemplate = template.Must(template.New("timer").Parse(`# Managed by go-auto-proxy
+[Unit]
+Description={{.Description}} (timer)
+
+[Timer]
+OnCalendar={{.OnCalendar}}
+{{- if .RandomizedDelay}}
+RandomizedDelaySec={{.RandomizedDelay}}
+{{- end}}
+Persistent=true
+
+[Install]
+WantedBy=timers.target
+`))
+
+// Service 描述一個常駐服務
+type Service struct {
+    Name             string // unit 名稱，不含副檔名
+    Description      string
+    ExecStart        string
+    WorkingDirectory string
+}
+
+var daemonTemplate = template.Must(template.New("daemon").Parse(`# Managed by go-auto-proxy
+[Unit]
+Description={{.Description}}
+After=network-online.target
+Wants=network-online.target
+
+[Service]
+Type=simple
+{{- if .WorkingDirectory}}
+WorkingDirectory={{.WorkingDirectory}}
+{{- end}}
+ExecStart={{.ExecStart}}
+Restart=on-failure
+RestartSec=5s
+LimitNOFILE=65535
+
+[Install]
+WantedBy=multi-user.target
+`))
+
+// Render 返回 service 檔案內容
+func (s Service) Render() ([]byte, error) {
+    var buf bytes.Buffer
+    if err := daemonTemplate.Execute(&buf, s); err != nil {
+        return nil, err
+    }
+    return buf.Bytes(), nil
+}
+
+// InstallService 寫入 unit 檔案並設定開機啟動；已在執行時由呼叫者決定是否重新啟動
+func InstallService(s Service) error {
+    data, err := s.Render()
+    if err != nil {
+        return err
+    }
+    path := filepath.Join(UnitDir, s.Name+".service")
+    if err := sudo.WriteFile(path, data, 0644); err != nil {
+        return err
+    }
+    log.Printf("Installed %s", path)
+
+    if _, err := sudo.Run("systemctl", "daemon-reload"); err != nil {
+        return err
+    }
+    if _, err := sudo.Run("systemctl", "enable", s.Name+".service"); err != nil {
+        return err
+    }
+    log.Printf("Service %s.service enabled.", s.Name)
+    return nil
+}
+
+// BootTask 描述開機時執行一次的 oneshot 服務，如重新套用不會自行保存的防火牆規則
+type BootTask struct {
+    Name        string // unit 名稱，不含副檔名
+    Description string
+    After       []string // 須在這些 unit 之後執行，不存在的 unit 會被忽略
+    ExecStart   []string // 依序執行的命令
+}
+
+var bootTaskTemplate = template.Must(template.New("boot").Funcs(template.FuncMap{"join": strings.Join}).Parse(`# Managed by go-auto-proxy
+[Unit]
+Description={{.Description}}
+{{- if .After}}
+After={{join .After " "}}
+{{- end}}
+
+[Service]
+Type=oneshot
+RemainAfterExit=yes
+{{- range .ExecStart}}
+ExecStart={{.}}
+{{- end}}
+
+[Install]
+WantedBy=multi-user.target
+`))
+
+// Render 返回 service 檔案內容
+func (b BootTask) Render() ([]byte, error) {
+    var buf bytes.Buffer
+    if err := bootTaskTemplate.Execute(&buf, b); err != nil {
+        return nil, err
+    }
+    return buf.Bytes(), nil
+}
+
+// InstallBootTask 寫入 unit 檔案並設定開機執行；本次不執行，呼叫者已直接套用過
+func InstallBootTask(b BootTask) error {
+    data, err := b.Render()
+    if err != nil {
+        return err
+    }
+    path := filepath.Join(UnitDir, b.Name+".service")
+    if err := sudo.WriteFile(path, data, 0644); err != nil {
+        return err
+    }
+    if _, err := sudo.Run("systemctl", "daemon-reload"); err != nil {
+        return err
+    }
+    if _, err := sudo.Run("systemctl", "enable", b.Name+".service"); err != nil {
+        return err
+    }
+    log.Printf("Installed %s, enabled at boot.", path)
+    return nil
+}
+
+// RemoveService 停用並刪除本工具安裝的 service，檔案不存在時不做任何事
+func RemoveService(name string) error {
+    path := filepath.Join(UnitDir, name+".service")
+    if _, err := os.Stat(path); err != nil {
+        return nil
+    }
+    if _, err := sudo.Run("systemctl", "disable", name+".service"); err != nil {
+        return err
+    }
+    if err := sudo.RemoveFile(path); err != nil {
+        return err
+    }
+    if _, err := sudo.Run("systemctl", "daemon-reload"); err != nil {
+        return err
+    }
+    log.Printf("Removed %s", path)
+    return nil
+}
+
+// Exists 判斷 systemd 是否認得 unit（已安裝的 unit 檔案）
+func Exists(unit string) bool {
+    if !strings.Contains(unit, ".") {
+        unit += ".service"
+    }
+    out, err := sudo.Run("systemctl", "list-unit-files", "--no-legend", unit)
+    return err == nil && strings.Contains(string(out), unit)
+}
+
+// Render 返回 service 與 timer 檔案內容
+func (t Timer) Render() (service, timer []byte, err error) {
+    var s, tm bytes.Buffer
+    if err := serviceTemplate.Execute(&s, t); err != nil {
+        return nil, nil, err
+    }
+    if err := timerTemplate.Execute(&tm, t); err != nil {
+        return nil, nil, err
+    }
+    return s.Bytes(), tm.Bytes(), nil
+}
+
+// InstallTimer 寫入 unit 檔案並啟用 timer
+func InstallTimer(t Timer) error {
+    service, timer, err := t.Render()
+    if err != nil {
+        return err
+    }
+    servicePath := filepath.Join(UnitDir, t.Name+".service")
+    timerPath := filepath.Join(UnitDir, t.Name+".timer")
+    if err := sudo.WriteFile(servicePath, service, 0644); err != nil {
+        return err
+    }
+    if err := sudo.WriteFile(timerPath, timer, 0644); err != nil {
+        return err
+    }
+    log.Printf("Installed %s and %s", servicePath, timerPath)
+
+    if _, err := sudo.Run("systemctl", "daemon-reload"); err != nil {
+        return err
+    }
+    if _, err := sudo.Run("systemctl", "enable", "--now", t.Name+".timer"); err != nil {
+        return err
+    }
+    log.Printf("Timer %s.timer enabled (%s).", t.Name, t.OnCalendar)
+    return nil
+}
+
+// Restart 重新啟動服務
+func Restart(unit string) error {
+    if _, err := sudo.Run("systemctl", "restart", unit); err != nil {
+        return fmt.Errorf("failed to restart %s: %v", unit, err)
+    }
+    log.Printf("Service %s restarted.", unit)
+    return nil
+}
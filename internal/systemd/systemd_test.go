@@ -0,0 +1,76 @@
+package systemd
+
+import (
+    "go-auto-proxy/internal/sudo"
+    "os"
+    "os/exec"
+    "path/filepath"
+    "testing"
+
+    "github.com/stretchr/testify/assert"
+)
+
+func TestTimerRender(t *testing.T) {
+    timer := Timer{
+        Name:             "go-auto-proxy-cert-renew",
+        Description:      "go-auto-proxy certificate renewal",
+        ExecStart:        "/usr/local/bin/go-auto-proxy cert renew --if-expiring-within 30d",
+        WorkingDirectory: "/home/proxy",
+        OnCalendar:       "daily",
+        RandomizedDelay:  "1h",
+    }
+    service, tm, err := timer.Render()
+    assert.NoError(t, err)
+    assert.Contains(t, string(service), "Type=oneshot")
+    assert.Contains(t, string(service), "ExecStart=/usr/local/bin/go-auto-proxy cert renew --if-expiring-within 30d")
+    assert.Contains(t, string(service), "WorkingDirectory=/home/proxy")
+    assert.Contains(t, string(tm), "OnCalendar=daily")
+    assert.Contains(t, string(tm), "RandomizedDelaySec=1h")
+    assert.Contains(t, string(tm), "WantedBy=timers.target")
+}
+
+func TestInstallTimer(t *testing.T) {
+    var commands [][]string
+    original := sudo.DefaultCommand
+    sudo.DefaultCommand = func(name string, args ...string) *exec.Cmd {
+        if name == "sudo" {
+            name, args = args[0], args[1:]
+        }
+        if name == "systemctl" {
+            commands = append(commands, append([]string{name}, args...))
+            return exec.Command("true")
+        }
+        return exec.Command(name, args...)
+    }
+    defer func() { sudo.DefaultCommand = original }()
+
+    originalDir := UnitDir
+    UnitDir = t.TempDir()
+    defer func() { UnitDir = originalDir }()
+
+    err := InstallTimer(Timer{Name: "gap-test", Description: "test", ExecStart: "/bin/true", OnCalendar: "hourly"})
+    assert.NoError(t, err)
+
+    _, err = os.Stat(filepath.Join(UnitDir, "gap-test.service"))
+    assert.NoError(t, err, "service unit should be written")
+    _, err = os.Stat(filepath.Join(UnitDir, "gap-test.timer"))
+    assert.NoError(t, err, "timer unit should be written")
+    assert.Equal(t, [][]string{
+        {"systemctl", "daemon-reload"},
+        {"systemctl", "enable", "--now", "gap-test.timer"},
+    }, commands)
+}
+
+func TestServiceRender(t *testing.T) {
+    service, err := Service{
+        Name:             "trojan-go",
+        Description:      "trojan-go proxy",
+        ExecStart:        "/home/proxy/trojan-go/trojan-go -config /home/proxy/trojan-go/config.json",
+        WorkingDirectory: "/home/proxy/trojan-go",
+    }.Render()
+    assert.NoError(t, err)
+    assert.Contains(t, string(service), "Type=simple")
+    assert.Contains(t, string(service), "ExecStart=/home/proxy/trojan-go/trojan-go -config /home/proxy/trojan-go/config.json")
+    assert.Contains(t, string(service), "Restart=on-failure")
+    assert.Contains(t, string(service), "WantedBy=multi-user.target")
+}
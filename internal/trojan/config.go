@@ -0,0 +1,131 @@
+package trojan
+
+import (
+    "encoding/json"
+    "go-auto-proxy/internal/secret"
+    "go-auto-proxy/internal/system"
+    "net"
+    "os"
+    "path/filepath"
+)
+
+// 部署模式
+const (
+    ModePublic   = "public"   // 在所有介面上監聽
+    ModeZeroTier = "zerotier" // 僅在 ZeroTier 位址上監聽，供內部用戶使用
+)
+
+// Config 對應 trojan-go 的 JSON 配置
+type Config struct {
+    RunType    string   `json:"run_type"`
+    LocalAddr  string   `json:"local_addr"`
+    LocalPort  int      `json:"local_port"`
+    RemoteAddr string   `json:"remote_addr"`
+    RemotePort int      `json:"remote_port"`
+    Password   []string `json:"password"`
+    SSL        SSL      `json:"ssl"`
+    Mux        *Mux     `json:"mux,omitempty"`
+}
+
+// SSL 對應 trojan-go 的 ssl 區塊；客戶端模式下 Cert 為用於驗證伺服器的 CA
+type SSL struct {
+    Verify         *bool  `json:"verify,omitempty"`
+    VerifyHostname *bool  `json:"verify_hostname,omitempty"`
+    Cert           string `json:"cert,omitempty"`
+    Key            string `json:"key,omitempty"`
+    SNI            string `json:"sni,omitempty"`
+}
+
+// Mux 對應 trojan-go 的多路復用設定
+type Mux struct {
+    Enabled bool `json:"enabled"`
+}
+
+// ServerConfig 依系統配置生成伺服器端配置，未通過驗證的流量轉發到本機 nginx
+func ServerConfig(info system.SystemInfo) Config {
+    return Config{
+        RunType:    "server",
+        LocalAddr:  listenAddr(info),
+        LocalPort:  info.TrojanGo.Port,
+        RemoteAddr: "127.0.0.1",
+        RemotePort: 80,
+        Password:   []string{info.TrojanGo.Password.Reveal()},
+        SSL: SSL{
+            Cert: info.AcmeSH.CertPath,
+            Key:  info.AcmeSH.KeyPath,
+            SNI:  info.AcmeSH.Domain,
+        },
+    }
+}
+
+// ClientConfig 生成供客戶端使用的配置；caPath 非空時客戶端以該 CA 驗證伺服器憑證
+func ClientConfig(info system.SystemInfo, caPath string) Config {
+    verify := true
+    return Config{
+        RunType:    "client",
+        LocalAddr:  "127.0.0.1",
+        LocalPort:  1080,
+        RemoteAddr: remoteAddr(info),
+        RemotePort: info.TrojanGo.Port,
+        Password:   []string{info.TrojanGo.Password.Reveal()},
+        SSL: SSL{
+            Verify:         &verify,
+            VerifyHostname: &verify,
+            Cert:           caPath,
+            SNI:            info.AcmeSH.Domain,
+        },
+        Mux: &Mux{Enabled: true},
+    }
+}
+
+// ZeroTierAddr 返回用於 ZeroTier 模式的位址，優先選擇 IPv4；尚未分配時返回空字串
+func ZeroTierAddr(info system.SystemInfo) string {
+    for _, ip := range info.ZeroTier.AssignedIPs {
+        if parsed := net.ParseIP(ip); parsed != nil && parsed.To4() != nil {
+            return ip
+        }
+    }
+    if len(info.ZeroTier.AssignedIPs) > 0 {
+        return info.ZeroTier.AssignedIPs[0]
+    }
+    return ""
+}
+
+// listenAddr 決定伺服器監聽位址：ZeroTier 模式只綁定 ZeroTier 位址
+func listenAddr(info system.SystemInfo) string {
+    if info.TrojanGo.Mode == ModeZeroTier {
+        if addr := ZeroTierAddr(info); addr != "" {
+            return addr
+        }
+    }
+    return "0.0.0.0"
+}
+
+// remoteAddr 決定客戶端連線的位址：ZeroTier 模式使用 ZeroTier 位址，
+// 否則優先使用明確設定，其次網域，最後對外 IP
+func remoteAddr(info system.SystemInfo) string {
+    if info.TrojanGo.Mode == ModeZeroTier {
+        if addr := ZeroTierAddr(info); addr != "" {
+            return addr
+        }
+    }
+    if info.TrojanGo.RemoteAddr != "" {
+        return info.TrojanGo.RemoteAddr
+    }
+    if info.AcmeSH.Domain != "" {
+        return info.AcmeSH.Domain
+    }
+    return info.ExternalIP
+}
+
+// Write 將配置寫入檔案，因含有密碼僅允許擁有者讀寫
+func Write(path string, cfg Config) error {
+    data, err := json.MarshalIndent(cfg, "", "    ")
+    if err != nil {
+        return err
+    }
+    if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+        return err
+    }
+    return secret.WriteFile(path, append(data, '\n'))
+}
@@ -0,0 +1,77 @@
+package trojan
+
+import (
+    "encoding/json"
+    "go-auto-proxy/internal/system"
+    "os"
+    "path/filepath"
+    "testing"
+
+    "github.com/stretchr/testify/assert"
+    "github.com/stretchr/testify/require"
+)
+
+func testInfo() system.SystemInfo {
+    info := system.SystemInfo{ExternalIP: "35.185.174.224"}
+    info.TrojanGo.Port = 443
+    info.TrojanGo.Password = "secret"
+    info.AcmeSH.Domain = "proxy.example.com"
+    info.AcmeSH.CertPath = "/srv/certs/fullchain.pem"
+    info.AcmeSH.KeyPath = "/srv/certs/privkey.pem"
+    return info
+}
+
+func TestServerConfig(t *testing.T) {
+    cfg := ServerConfig(testInfo())
+    assert.Equal(t, "server", cfg.RunType)
+    assert.Equal(t, 443, cfg.LocalPort)
+    assert.Equal(t, []string{"secret"}, cfg.Password)
+    assert.Equal(t, "/srv/certs/fullchain.pem", cfg.SSL.Cert)
+    assert.Equal(t, "proxy.example.com", cfg.SSL.SNI)
+}
+
+func TestClientConfig(t *testing.T) {
+    info := testInfo()
+    cfg := ClientConfig(info, "")
+    assert.Equal(t, "proxy.example.com", cfg.RemoteAddr, "domain should be used when no remote address is set")
+    assert.Empty(t, cfg.SSL.Cert, "public CA needs no pinned certificate")
+
+    info.TrojanGo.RemoteAddr = "10.147.17.5"
+    cfg = ClientConfig(info, "ca.crt")
+    assert.Equal(t, "10.147.17.5", cfg.RemoteAddr)
+    assert.Equal(t, "ca.crt", cfg.SSL.Cert)
+    require.NotNil(t, cfg.SSL.Verify)
+    assert.True(t, *cfg.SSL.Verify, "client should verify the server certificate")
+}
+
+func TestZeroTierMode(t *testing.T) {
+    info := testInfo()
+    info.TrojanGo.Mode = ModeZeroTier
+    info.ZeroTier.AssignedIPs = []string{"fd80:56c2:e21c::1", "10.147.17.5"}
+
+    assert.Equal(t, "10.147.17.5", ServerConfig(info).LocalAddr, "server should only listen on the ZeroTier address")
+    cfg := ClientConfig(info, "")
+    assert.Equal(t, "10.147.17.5", cfg.RemoteAddr)
+    assert.Equal(t, "proxy.example.com", cfg.SSL.SNI, "SNI still matches the certificate")
+
+    info.TrojanGo.Mode = ModePublic
+    assert.Equal(t, "0.0.0.0", ServerConfig(info).LocalAddr)
+    assert.Equal(t, "proxy.example.com", ClientConfig(info, "").RemoteAddr)
+}
+
+func TestWrite(t *testing.T) {
+    path := filepath.Join(t.TempDir(), "client", "client.json")
+    require.NoError(t, Write(path, ClientConfig(testInfo(), "ca.crt")))
+
+    data, err := os.ReadFile(path)
+    require.NoError(t, err)
+    var raw map[string]interface{}
+    require.NoError(t, json.Unmarshal(data, &raw))
+    ssl := raw["ssl"].(map[string]interface{})
+    assert.Equal(t, true, ssl["verify"])
+    assert.Equal(t, "ca.crt", ssl["cert"])
+
+    st, err := os.Stat(path)
+    require.NoError(t, err)
+    assert.Equal(t, os.FileMode(0600), st.Mode().Perm(), "config contains the password")
+}
@@ -0,0 +1,113 @@
+package tune
+
+import (
+    "fmt"
+    "sort"
+    "strings"
+)
+
+// Setting 是一個 sysctl 鍵值
+type Setting struct {
+    Key   string
+    Value string
+}
+
+// Profile 是一組核心網路參數與檔案描述元上限
+type Profile struct {
+    Name        string
+    Description string
+    Sysctls     []Setting
+    NoFile      int // nofile soft/hard 上限
+}
+
+// bbrSettings 啟用 BBR 與 fq；核心不支援 BBR 時會從 profile 中移除
+var bbrSettings = []Setting{
+    {"net.core.default_qdisc", "fq"},
+    {"net.ipv4.tcp_congestion_control", "bbr"},
+}
+
+// Profiles 是可選的調校方案
+var Profiles = map[string]Profile{
+    "conservative": {
+        Name:        "conservative",
+        Description: "BBR, fq and TCP Fast Open with default buffer sizes",
+        Sysctls: append(append([]Setting{}, bbrSettings...),
+            Setting{"net.ipv4.tcp_fastopen", "3"},
+            Setting{"net.ipv4.tcp_mtu_probing", "1"},
+            Setting{"fs.file-max", "1048576"},
+        ),
+        NoFile: 65535,
+    },
+    "throughput": {
+        Name:        "throughput",
+        Description: "BBR with 64 MiB socket buffers and large backlogs for busy hosts",
+        Sysctls: append(append([]Setting{}, bbrSettings...),
+            Setting{"net.ipv4.tcp_fastopen", "3"},
+            Setting{"net.ipv4.tcp_mtu_probing", "1"},
+            Setting{"net.core.rmem_max", "67108864"},
+            Setting{"net.core.wmem_max", "67108864"},
+            Setting{"net.ipv4.tcp_rmem", "4096 87380 67108864"},
+            Setting{"net.ipv4.tcp_wmem", "4096 65536 67108864"},
+            Setting{"net.core.somaxconn", "8192"},
+            Setting{"net.ipv4.tcp_max_syn_backlog", "8192"},
+            Setting{"net.core.netdev_max_backlog", "16384"},
+            Setting{"net.ipv4.tcp_slow_start_after_idle", "0"},
+            Setting{"net.ipv4.tcp_notsent_lowat", "16384"},
+            Setting{"net.ipv4.ip_local_port_range", "1024 65535"},
+            Setting{"fs.file-max", "2097152"},
+        ),
+        NoFile: 1048576,
+    },
+    "low-memory": {
+        Name:        "low-memory",
+        Description: "BBR with socket buffers capped at 4 MiB for small instances",
+        Sysctls: append(append([]Setting{}, bbrSettings...),
+            Setting{"net.ipv4.tcp_fastopen", "3"},
+            Setting{"net.core.rmem_max", "4194304"},
+            Setting{"net.core.wmem_max", "4194304"},
+            Setting{"net.ipv4.tcp_rmem", "4096 65536 4194304"},
+            Setting{"net.ipv4.tcp_wmem", "4096 32768 4194304"},
+            Setting{"net.core.somaxconn", "1024"},
+            Setting{"net.ipv4.tcp_fin_timeout", "15"},
+            Setting{"fs.file-max", "262144"},
+        ),
+        NoFile: 65535,
+    },
+}
+
+// Lookup 依名稱返回 profile
+func Lookup(name string) (Profile, error) {
+    p, ok := Profiles[name]
+    if !ok {
+        return Profile{}, fmt.Errorf("unknown tuning profile %q (use %s)", name, strings.Join(Names(), ", "))
+    }
+    return p, nil
+}
+
+// Names 返回所有 profile 名稱
+func Names() []string {
+    var names []string
+    for name := range Profiles {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+    return names
+}
+
+// withoutBBR 返回移除 BBR 與 fq 設定後的 profile
+func (p Profile) withoutBBR() Profile {
+    var kept []Setting
+    for _, s := range p.Sysctls {
+        bbr := false
+        for _, b := range bbrSettings {
+            if s.Key == b.Key {
+                bbr = true
+            }
+        }
+        if !bbr {
+            kept = append(kept, s)
+        }
+    }
+    p.Sysctls = kept
+    return p
+}
@@ -0,0 +1,235 @@
+package tune
+
+import (
+    "bufio"
+    "bytes"
+    "fmt"
+    "go-auto-proxy/internal/sudo"
+    "log"
+    "os"
+    "path/filepath"
+    "strings"
+)
+
+var (
+    // ProcSysDir 用於讀取目前的 sysctl 值，可在測試中替換
+    ProcSysDir = "/proc/sys"
+    // SysctlPath 是本工具管理的 sysctl 設定檔
+    SysctlPath = "/etc/sysctl.d/99-go-auto-proxy.conf"
+    // LimitsPath 提高登入工作階段的 nofile 上限
+    LimitsPath = "/etc/security/limits.d/99-go-auto-proxy.conf"
+    // SystemdPath 提高 systemd 服務（如 trojan-go）的預設 nofile 上限
+    SystemdPath = "/etc/systemd/system.conf.d/99-go-auto-proxy.conf"
+)
+
+// previousPrefix 標記首次套用前的原始值，revert 時據此還原
+const previousPrefix = "# previous: "
+
+// Change 是一個 sysctl 的目前值與目標值
+type Change struct {
+    Key     string `json:"key"`
+    Current string `json:"current"`
+    Desired string `json:"desired"`
+}
+
+// Changed 表示目前值與目標值不同
+func (c Change) Changed() bool {
+    return c.Current != c.Desired
+}
+
+// Current 從 /proc/sys 讀取 sysctl 值，多個欄位以單一空白分隔
+func Current(key string) (string, error) {
+    data, err := os.ReadFile(filepath.Join(ProcSysDir, strings.ReplaceAll(key, ".", "/")))
+    if err != nil {
+        return "", err
+    }
+    return strings.Join(strings.Fields(string(data)), " "), nil
+}
+
+// Plan 比較 profile 與目前的值；無法讀取的鍵以空字串表示
+func Plan(p Profile) []Change {
+    var changes []Change
+    for _, s := range p.Sysctls {
+        current, _ := Current(s.Key)
+        changes = append(changes, Change{Key: s.Key, Current: current, Desired: s.Value})
+    }
+    return changes
+}
+
+// BBRAvailable 檢查核心是否提供 bbr 擁塞控制，未載入時嘗試 modprobe tcp_bbr
+func BBRAvailable() bool {
+    if BBRLoaded() {
+        return true
+    }
+    if _, err := sudo.Run("modprobe", "tcp_bbr"); err != nil {
+        return false
+    }
+    return BBRLoaded()
+}
+
+// BBRLoaded 檢查 bbr 是否已出現在可用的擁塞控制演算法中
+func BBRLoaded() bool {
+    available, err := Current("net.ipv4.tcp_available_congestion_control")
+    return err == nil && strings.Contains(" "+available+" ", " bbr ")
+}
+
+// Render 返回 sysctl 設定檔內容，previous 以註解保存原始值
+func Render(p Profile, previous []Setting) []byte {
+    var buf bytes.Buffer
+    fmt.Fprintf(&buf, "# Managed by go-auto-proxy (profile: %s), revert with 'go-auto-proxy tune revert'\n", p.Name)
+    for _, s := range previous {
+        fmt.Fprintf(&buf, "%s%s = %s\n", previousPrefix, s.Key, s.Value)
+    }
+    for _, s := range p.Sysctls {
+        fmt.Fprintf(&buf, "%s = %s\n", s.Key, s.Value)
+    }
+    return buf.Bytes()
+}
+
+// Apply 寫入並載入 profile，同時提高 nofile 上限；
+// 核心不支援 BBR 時略過 BBR 與 fq，返回實際套用的 profile
+func Apply(p Profile) (Profile, error) {
+    if !BBRAvailable() {
+        log.Println("Warning: tcp_bbr is not available in this kernel (4.9+ required), skipping BBR and fq.")
+        p = p.withoutBBR()
+    }
+
+    // 重複套用時保留首次記錄的原始值，只補上新出現的鍵
+    previous, err := previousValues()
+    if err != nil {
+        return p, err
+    }
+    recorded := make(map[string]bool)
+    for _, s := range previous {
+        recorded[s.Key] = true
+    }
+    for _, s := range p.Sysctls {
+        if recorded[s.Key] {
+            continue
+        }
+        if value, err := Current(s.Key); err == nil {
+            previous = append(previous, Setting{s.Key, value})
+        }
+    }
+
+    if err := sudo.WriteFile(SysctlPath, Render(p, previous), 0644); err != nil {
+        return p, err
+    }
+    if _, err := sudo.Run("sysctl", "-p", SysctlPath); err != nil {
+        return p, fmt.Errorf("failed to load %s: %v", SysctlPath, err)
+    }
+    // sysctl -p 只設定檔案中的鍵；切換 profile 時，先前 profile 設定而新 profile 沒有的鍵
+    // 必須還原為原始值，否則會一直生效到重新開機
+    if err := restoreUnset(p, previous); err != nil {
+        return p, err
+    }
+    if err := writeLimits(p.NoFile); err != nil {
+        return p, err
+    }
+    log.Printf("Applied the %s tuning profile (%d sysctls, nofile %d).", p.Name, len(p.Sysctls), p.NoFile)
+    return p, nil
+}
+
+// restoreUnset 將 p 沒有設定的鍵還原為 previous 中記錄的原始值
+func restoreUnset(p Profile, previous []Setting) error {
+    desired := make(map[string]bool)
+    for _, s := range p.Sysctls {
+        desired[s.Key] = true
+    }
+    for _, s := range previous {
+        if desired[s.Key] {
+            continue
+        }
+        if current, err := Current(s.Key); err == nil && current == s.Value {
+            continue
+        }
+        if _, err := sudo.Run("sysctl", "-w", s.Key+"="+s.Value); err != nil {
+            return fmt.Errorf("failed to restore %s: %v", s.Key, err)
+        }
+    }
+    return nil
+}
+
+// writeLimits 寫入 limits.d 與 systemd 的 nofile 上限並重新執行 systemd
+func writeLimits(nofile int) error {
+    limits := fmt.Sprintf("# Managed by go-auto-proxy\n* soft nofile %d\n* hard nofile %d\nroot soft nofile %d\nroot hard nofile %d\n", nofile, nofile, nofile, nofile)
+    manager := fmt.Sprintf("# Managed by go-auto-proxy\n[Manager]\nDefaultLimitNOFILE=%d\n", nofile)
+    for path, data := range map[string]string{LimitsPath: limits, SystemdPath: manager} {
+        if _, err := sudo.Run("mkdir", "-p", filepath.Dir(path)); err != nil {
+            return err
+        }
+        if err := sudo.WriteFile(path, []byte(data), 0644); err != nil {
+            return err
+        }
+    }
+    if _, err := sudo.Run("systemctl", "daemon-reexec"); err != nil {
+        return fmt.Errorf("failed to reload systemd limits: %v", err)
+    }
+    return nil
+}
+
+// Revert 刪除本工具寫入的設定檔，並將 sysctl 還原為首次套用前的值
+func Revert() error {
+    previous, err := previousValues()
+    if err != nil {
+        return err
+    }
+    if _, err := os.Stat(SysctlPath); os.IsNotExist(err) {
+        log.Println("No tuning profile is applied.")
+        return nil
+    }
+    for _, path := range []string{SysctlPath, LimitsPath, SystemdPath} {
+        if err := sudo.RemoveFile(path); err != nil {
+            return err
+        }
+    }
+    for _, s := range previous {
+        if _, err := sudo.Run("sysctl", "-w", s.Key+"="+s.Value); err != nil {
+            log.Printf("Warning: failed to restore %s: %v", s.Key, err)
+        }
+    }
+    if _, err := sudo.Run("systemctl", "daemon-reexec"); err != nil {
+        return fmt.Errorf("failed to reload systemd limits: %v", err)
+    }
+    log.Printf("Reverted %d sysctls and removed the nofile limits.", len(previous))
+    return nil
+}
+
+// AppliedProfile 返回目前設定檔記錄的 profile 名稱，未套用時為空字串
+func AppliedProfile() string {
+    data, err := os.ReadFile(SysctlPath)
+    if err != nil {
+        return ""
+    }
+    line, _, _ := strings.Cut(string(data), "\n")
+    _, rest, ok := strings.Cut(line, "(profile: ")
+    if !ok {
+        return ""
+    }
+    name, _, _ := strings.Cut(rest, ")")
+    return name
+}
+
+// previousValues 從已存在的設定檔讀取首次套用前的原始值
+func previousValues() ([]Setting, error) {
+    data, err := os.ReadFile(SysctlPath)
+    if os.IsNotExist(err) {
+        return nil, nil
+    }
+    if err != nil {
+        return nil, err
+    }
+    var previous []Setting
+    scanner := bufio.NewScanner(bytes.NewReader(data))
+    for scanner.Scan() {
+        line := scanner.Text()
+        if !strings.HasPrefix(line, previousPrefix) {
+            continue
+        }
+        key, value, ok := strings.Cut(strings.TrimPrefix(line, previousPrefix), "=")
+        if ok {
+            previous = append(previous, Setting{strings.TrimSpace(key), strings.TrimSpace(value)})
+        }
+    }
+    return previous, nil
+}
@@ -0,0 +1,159 @@
+package tune
+
+import (
+    "go-auto-proxy/internal/sudo"
+    "os"
+    "os/exec"
+    "path/filepath"
+    "strings"
+    "testing"
+
+    "github.com/stretchr/testify/assert"
+    "github.com/stretchr/testify/require"
+)
+
+// fakeSystem 建立暫存的 /proc/sys 與設定檔路徑；sysctl -w 會寫入暫存的 /proc/sys
+func fakeSystem(t *testing.T, congestion string) *[]string {
+    root := t.TempDir()
+    paths := []string{ProcSysDir, SysctlPath, LimitsPath, SystemdPath}
+    t.Cleanup(func() { ProcSysDir, SysctlPath, LimitsPath, SystemdPath = paths[0], paths[1], paths[2], paths[3] })
+    ProcSysDir = filepath.Join(root, "proc")
+    SysctlPath = filepath.Join(root, "sysctl.d", "99-go-auto-proxy.conf")
+    LimitsPath = filepath.Join(root, "limits.d", "99-go-auto-proxy.conf")
+    SystemdPath = filepath.Join(root, "system.conf.d", "99-go-auto-proxy.conf")
+    require.NoError(t, os.MkdirAll(filepath.Dir(SysctlPath), 0755))
+    writeSysctl(t, "net.ipv4.tcp_available_congestion_control", congestion)
+    writeSysctl(t, "net.ipv4.tcp_congestion_control", "cubic")
+    writeSysctl(t, "net.core.default_qdisc", "pfifo_fast")
+    writeSysctl(t, "net.ipv4.tcp_fastopen", "1")
+
+    var calls []string
+    original := sudo.DefaultCommand
+    sudo.DefaultCommand = func(name string, args ...string) *exec.Cmd {
+        if name == "sudo" {
+            name, args = args[0], args[1:]
+        }
+        calls = append(calls, name+" "+strings.Join(args, " "))
+        switch name {
+        case "sysctl":
+            if args[0] == "-w" {
+                key, value, _ := strings.Cut(args[1], "=")
+                writeSysctl(t, key, value)
+            }
+            return exec.Command("true")
+        case "modprobe":
+            return exec.Command("false")
+        case "systemctl":
+            return exec.Command("true")
+        }
+        return exec.Command(name, args...)
+    }
+    t.Cleanup(func() { sudo.DefaultCommand = original })
+    return &calls
+}
+
+func writeSysctl(t *testing.T, key, value string) {
+    path := filepath.Join(ProcSysDir, strings.ReplaceAll(key, ".", "/"))
+    require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+    require.NoError(t, os.WriteFile(path, []byte(value+"\n"), 0644))
+}
+
+func TestPlan(t *testing.T) {
+    fakeSystem(t, "reno cubic bbr")
+    writeSysctl(t, "net.ipv4.tcp_rmem", "4096\t131072\t6291456")
+
+    profile, err := Lookup("throughput")
+    require.NoError(t, err)
+    changes := Plan(profile)
+    assert.Equal(t, Change{Key: "net.core.default_qdisc", Current: "pfifo_fast", Desired: "fq"}, changes[0])
+    for _, c := range changes {
+        if c.Key == "net.ipv4.tcp_rmem" {
+            assert.Equal(t, "4096 131072 6291456", c.Current, "tabs are normalized")
+        }
+    }
+    assert.True(t, BBRLoaded())
+
+    _, err = Lookup("turbo")
+    assert.ErrorContains(t, err, "conservative, low-memory, throughput")
+}
+
+func TestApplyAndRevert(t *testing.T) {
+    calls := fakeSystem(t, "reno cubic bbr")
+    profile, err := Lookup("conservative")
+    require.NoError(t, err)
+
+    applied, err := Apply(profile)
+    require.NoError(t, err)
+    assert.Len(t, applied.Sysctls, len(profile.Sysctls))
+    data, err := os.ReadFile(SysctlPath)
+    require.NoError(t, err)
+    assert.Contains(t, string(data), "# previous: net.ipv4.tcp_congestion_control = cubic\n")
+    assert.Contains(t, string(data), "net.ipv4.tcp_congestion_control = bbr\n")
+    assert.Equal(t, "conservative", AppliedProfile())
+    limits, err := os.ReadFile(SystemdPath)
+    require.NoError(t, err)
+    assert.Contains(t, string(limits), "DefaultLimitNOFILE=65535")
+    assert.Contains(t, *calls, "sysctl -p "+SysctlPath)
+
+    // 模擬已生效後再次套用，原始值不可被覆蓋
+    writeSysctl(t, "net.ipv4.tcp_congestion_control", "bbr")
+    _, err = Apply(profile)
+    require.NoError(t, err)
+    data, err = os.ReadFile(SysctlPath)
+    require.NoError(t, err)
+    assert.Equal(t, 1, strings.Count(string(data), "# previous: net.ipv4.tcp_congestion_control"))
+    assert.Contains(t, string(data), "# previous: net.ipv4.tcp_congestion_control = cubic\n")
+
+    require.NoError(t, Revert())
+    assert.NoFileExists(t, SysctlPath)
+    assert.NoFileExists(t, LimitsPath)
+    current, err := Current("net.ipv4.tcp_congestion_control")
+    require.NoError(t, err)
+    assert.Equal(t, "cubic", current)
+    assert.Empty(t, AppliedProfile())
+    assert.NoError(t, Revert(), "reverting twice is a no-op")
+}
+
+func TestApplySwitchProfile(t *testing.T) {
+    fakeSystem(t, "reno cubic bbr")
+    writeSysctl(t, "net.ipv4.ip_local_port_range", "32768 60999")
+    writeSysctl(t, "net.core.rmem_max", "212992")
+    throughput, err := Lookup("throughput")
+    require.NoError(t, err)
+    lowMemory, err := Lookup("low-memory")
+    require.NoError(t, err)
+
+    _, err = Apply(throughput)
+    require.NoError(t, err)
+    // fakeSystem 的 sysctl -p 不會寫入 /proc/sys，這裡模擬 throughput 已生效
+    writeSysctl(t, "net.ipv4.ip_local_port_range", "1024 65535")
+    writeSysctl(t, "net.core.rmem_max", "67108864")
+
+    _, err = Apply(lowMemory)
+    require.NoError(t, err)
+    current, err := Current("net.ipv4.ip_local_port_range")
+    require.NoError(t, err)
+    assert.Equal(t, "32768 60999", current, "keys only set by the previous profile go back to their original value")
+    current, err = Current("net.core.rmem_max")
+    require.NoError(t, err)
+    assert.Equal(t, "67108864", current, "keys set by the new profile are left to sysctl -p")
+
+    data, err := os.ReadFile(SysctlPath)
+    require.NoError(t, err)
+    assert.Contains(t, string(data), "# previous: net.ipv4.ip_local_port_range = 32768 60999\n", "the original value is still kept for revert")
+}
+
+func TestApplyWithoutBBR(t *testing.T) {
+    calls := fakeSystem(t, "reno cubic")
+    profile, err := Lookup("low-memory")
+    require.NoError(t, err)
+
+    applied, err := Apply(profile)
+    require.NoError(t, err)
+    assert.Contains(t, *calls, "modprobe tcp_bbr")
+    assert.Len(t, applied.Sysctls, len(profile.Sysctls)-2)
+    data, err := os.ReadFile(SysctlPath)
+    require.NoError(t, err)
+    assert.NotContains(t, string(data), "bbr")
+    assert.NotContains(t, string(data), "default_qdisc")
+}
@@ -0,0 +1,163 @@
+package zerotier
+
+import (
+    "bytes"
+    "context"
+    "encoding/json"
+    "fmt"
+    "go-auto-proxy/internal/sudo"
+    "io"
+    "net/http"
+    "os"
+    "strings"
+    "time"
+)
+
+const (
+    // Port 是 zerotier-one 對等連線使用的 UDP 端口，本地 API 也在同一 TCP 端口
+    Port = 9993
+    // DefaultAPIURL 是 zerotier-one 本地服務的 JSON API 位址
+    DefaultAPIURL = "http://127.0.0.1:9993"
+)
+
+// AuthTokenPath 是本地 API 的認證 token，僅 root 可讀
+var AuthTokenPath = "/var/lib/zerotier-one/authtoken.secret"
+
+// Client 呼叫 zerotier-one 的本地 JSON API
+type Client struct {
+    BaseURL    string
+    Token      string
+    HTTPClient *http.Client
+}
+
+// Status 是 GET /status 的結果
+type Status struct {
+    Address           string `json:"address"`
+    Online            bool   `json:"online"`
+    Version           string `json:"version"`
+    TCPFallbackActive bool   `json:"tcpFallbackActive"`
+}
+
+// Path 是與 peer 之間的一條實體路徑
+type Path struct {
+    Address   string `json:"address"`
+    Active    bool   `json:"active"`
+    Preferred bool   `json:"preferred"`
+}
+
+// Peer 是 GET /peer 的一項
+type Peer struct {
+    Address string `json:"address"`
+    Role    string `json:"role"` // LEAF、PLANET 或 MOON
+    Version string `json:"version"`
+    Latency int    `json:"latency"`
+    Paths   []Path `json:"paths"`
+}
+
+// NewClient 讀取 authtoken.secret 並返回連到本機服務的客戶端；
+// token 檔案僅 root 可讀，權限不足時改以 sudo 讀取
+func NewClient() (*Client, error) {
+    token, err := os.ReadFile(AuthTokenPath)
+    if os.IsPermission(err) {
+        token, err = sudo.Run("cat", AuthTokenPath)
+    }
+    if err != nil {
+        return nil, fmt.Errorf("failed to read ZeroTier auth token: %v", err)
+    }
+    return &Client{BaseURL: DefaultAPIURL, Token: strings.TrimSpace(string(token))}, nil
+}
+
+// Status 返回本機節點狀態
+func (c *Client) Status(ctx context.Context) (Status, error) {
+    var status Status
+    err := c.do(ctx, http.MethodGet, "/status", nil, &status)
+    return status, err
+}
+
+// Networks 返回已加入的網路
+func (c *Client) Networks(ctx context.Context) ([]Network, error) {
+    var networks []Network
+    err := c.do(ctx, http.MethodGet, "/network", nil, &networks)
+    return networks, err
+}
+
+// Network 返回單個網路
+func (c *Client) Network(ctx context.Context, id string) (Network, error) {
+    var network Network
+    err := c.do(ctx, http.MethodGet, "/network/"+id, nil, &network)
+    return network, err
+}
+
+// Peers 返回目前已知的 peer
+func (c *Client) Peers(ctx context.Context) ([]Peer, error) {
+    var peers []Peer
+    err := c.do(ctx, http.MethodGet, "/peer", nil, &peers)
+    return peers, err
+}
+
+// Join 加入網路
+func (c *Client) Join(ctx context.Context, id string) (Network, error) {
+    if !ValidNetworkID(id) {
+        return Network{}, fmt.Errorf("invalid ZeroTier network ID %q (expected 16 hex characters)", id)
+    }
+    var network Network
+    err := c.do(ctx, http.MethodPost, "/network/"+id, struct{}{}, &network)
+    return network, err
+}
+
+// Leave 離開網路
+func (c *Client) Leave(ctx context.Context, id string) error {
+    if !ValidNetworkID(id) {
+        return fmt.Errorf("invalid ZeroTier network ID %q (expected 16 hex characters)", id)
+    }
+    return c.do(ctx, http.MethodDelete, "/network/"+id, nil, nil)
+}
+
+func (c *Client) do(ctx context.Context, method, path string, body, out interface{}) error {
+    var reader io.Reader
+    if body != nil {
+        data, err := json.Marshal(body)
+        if err != nil {
+            return err
+        }
+        reader = bytes.NewReader(data)
+    }
+    req, err := http.NewRequestWithContext(ctx, method, strings.TrimRight(c.BaseURL, "/")+path, reader)
+    if err != nil {
+        return err
+    }
+    req.Header.Set("X-ZT1-Auth", c.Token)
+    if body != nil {
+        req.Header.Set("Content-Type", "application/json")
+    }
+
+    client := c.HTTPClient
+    if client == nil {
+        client = &http.Client{Timeout: 10 * time.Second}
+    }
+    resp, err := client.Do(req)
+    if err != nil {
+        return fmt.Errorf("ZeroTier API %s %s failed: %v (is zerotier-one running?)", method, path, err)
+    }
+    defer resp.Body.Close()
+
+    data, err := io.ReadAll(resp.Body)
+    if err != nil {
+        return err
+    }
+    switch {
+    case resp.StatusCode == http.StatusUnauthorized:
+        return fmt.Errorf("ZeroTier API rejected the auth token (check %s)", AuthTokenPath)
+    case resp.StatusCode == http.StatusNotFound:
+        return fmt.Errorf("ZeroTier API %s %s: not found", method, path)
+    case resp.StatusCode >= 300:
+        return fmt.Errorf("ZeroTier API %s %s returned %s: %s", method, path, resp.Status, strings.TrimSpace(string(data)))
+    }
+    if out == nil {
+        return nil
+    }
+    if err := json.Unmarshal(data, out); err != nil {
+        return fmt.Errorf("unexpected ZeroTier API response for %s: %v", path, err)
+    }
+    return nil
+}
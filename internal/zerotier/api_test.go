@@ -0,0 +1,132 @@
+package zerotier
+
+import (
+    "context"
+    "encoding/json"
+    "net/http"
+    "net/http/httptest"
+    "os"
+    "path/filepath"
+    "strings"
+    "sync"
+    "testing"
+
+    "github.com/stretchr/testify/assert"
+    "github.com/stretchr/testify/require"
+)
+
+// fakeService 模擬 zerotier-one 的本地 JSON API
+type fakeService struct {
+    mu       sync.Mutex
+    networks map[string]Network
+    // progress 依序返回給 GET /network/<id>，模擬狀態變化
+    progress []Network
+}
+
+func newFakeService(t *testing.T) (*fakeService, *Client) {
+    svc := &fakeService{networks: map[string]Network{}}
+    srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        if r.Header.Get("X-ZT1-Auth") != "secret-token" {
+            w.WriteHeader(http.StatusUnauthorized)
+            return
+        }
+        svc.mu.Lock()
+        defer svc.mu.Unlock()
+
+        var result interface{}
+        switch {
+        case r.URL.Path == "/status":
+            result = Status{Address: "a1b2c3d4e5", Online: true, Version: "1.14.0"}
+        case r.URL.Path == "/peer":
+            result = []Peer{{Address: "62f865ae71", Role: "PLANET", Latency: 42, Version: "-1.-1.-1",
+                Paths: []Path{{Address: "50.7.252.138/9993", Active: true, Preferred: true}}}}
+        case r.URL.Path == "/network":
+            list := []Network{}
+            for _, n := range svc.networks {
+                list = append(list, n)
+            }
+            result = list
+        case strings.HasPrefix(r.URL.Path, "/network/"):
+            id := strings.TrimPrefix(r.URL.Path, "/network/")
+            switch r.Method {
+            case http.MethodPost:
+                svc.networks[id] = Network{ID: id, Status: "REQUESTING_CONFIGURATION"}
+            case http.MethodDelete:
+                delete(svc.networks, id)
+                result = map[string]bool{"result": true}
+            }
+            if r.Method == http.MethodGet && len(svc.progress) > 0 {
+                svc.networks[id] = svc.progress[0]
+                if len(svc.progress) > 1 {
+                    svc.progress = svc.progress[1:]
+                }
+            }
+            if result == nil {
+                n, ok := svc.networks[id]
+                if !ok {
+                    w.WriteHeader(http.StatusNotFound)
+                    return
+                }
+                result = n
+            }
+        default:
+            w.WriteHeader(http.StatusNotFound)
+            return
+        }
+        json.NewEncoder(w).Encode(result)
+    }))
+    t.Cleanup(srv.Close)
+    return svc, &Client{BaseURL: srv.URL, Token: "secret-token"}
+}
+
+func TestClient(t *testing.T) {
+    _, c := newFakeService(t)
+    ctx := context.Background()
+
+    status, err := c.Status(ctx)
+    require.NoError(t, err)
+    assert.Equal(t, "a1b2c3d4e5", status.Address)
+    assert.True(t, status.Online)
+
+    peers, err := c.Peers(ctx)
+    require.NoError(t, err)
+    require.Len(t, peers, 1)
+    assert.Equal(t, "PLANET", peers[0].Role)
+    assert.True(t, peers[0].Paths[0].Preferred)
+
+    network, err := c.Join(ctx, testNetworkID)
+    require.NoError(t, err)
+    assert.Equal(t, testNetworkID, network.ID)
+
+    networks, err := c.Networks(ctx)
+    require.NoError(t, err)
+    assert.Len(t, networks, 1)
+
+    require.NoError(t, c.Leave(ctx, testNetworkID))
+    networks, err = c.Networks(ctx)
+    require.NoError(t, err)
+    assert.Empty(t, networks)
+
+    _, err = c.Network(ctx, testNetworkID)
+    assert.ErrorContains(t, err, "not found")
+    assert.Error(t, c.Leave(ctx, "bogus"), "network IDs are validated before calling the API")
+}
+
+func TestClientUnauthorized(t *testing.T) {
+    _, c := newFakeService(t)
+    c.Token = "wrong"
+    _, err := c.Status(context.Background())
+    assert.ErrorContains(t, err, "auth token")
+}
+
+func TestNewClientReadsToken(t *testing.T) {
+    original := AuthTokenPath
+    AuthTokenPath = filepath.Join(t.TempDir(), "authtoken.secret")
+    defer func() { AuthTokenPath = original }()
+    require.NoError(t, os.WriteFile(AuthTokenPath, []byte("secret-token\n"), 0600))
+
+    c, err := NewClient()
+    require.NoError(t, err)
+    assert.Equal(t, "secret-token", c.Token)
+    assert.Equal(t, DefaultAPIURL, c.BaseURL)
+}
@@ -0,0 +1,102 @@
+package zerotier
+
+import (
+    "context"
+    "fmt"
+    "log"
+    "regexp"
+    "strings"
+    "time"
+)
+
+// 網路狀態，見 GET /network
+const (
+    StatusOK           = "OK"
+    StatusAccessDenied = "ACCESS_DENIED"
+    StatusNotFound     = "NOT_FOUND"
+)
+
+var networkIDPattern = regexp.MustCompile(`^[0-9a-f]{16}$`)
+
+// Network 是 GET /network 的一項
+type Network struct {
+    ID                string   `json:"nwid"`
+    Name              string   `json:"name"`
+    Status            string   `json:"status"`
+    Type              string   `json:"type"`
+    PortDeviceName    string   `json:"portDeviceName"`
+    AssignedAddresses []string `json:"assignedAddresses"` // CIDR 格式，如 10.147.17.5/24
+}
+
+// IPs 返回不含前綴長度的已分配位址
+func (n Network) IPs() []string {
+    ips := make([]string, 0, len(n.AssignedAddresses))
+    for _, addr := range n.AssignedAddresses {
+        ip, _, _ := strings.Cut(addr, "/")
+        ips = append(ips, ip)
+    }
+    return ips
+}
+
+// ValidNetworkID 檢查是否為 16 位十六進位的網路 ID
+func ValidNetworkID(id string) bool {
+    return networkIDPattern.MatchString(id)
+}
+
+// JoinAndWait 加入網路並輪詢狀態，直到狀態為 OK 且已分配位址，或 ctx 逾時；
+// 節點尚未授權時返回包含授權步驟的錯誤
+func (c *Client) JoinAndWait(ctx context.Context, networkID string, interval time.Duration) (Network, error) {
+    if _, err := c.Join(ctx, networkID); err != nil {
+        return Network{}, err
+    }
+    log.Printf("Joining ZeroTier network %s...", networkID)
+
+    ticker := time.NewTicker(interval)
+    defer ticker.Stop()
+    last := Network{ID: networkID}
+    for {
+        n, err := c.Network(ctx, networkID)
+        if err != nil && ctx.Err() == nil {
+            return last, err
+        }
+        if err == nil {
+            if n.Status != last.Status {
+                log.Printf("ZeroTier network %s status: %s", networkID, n.Status)
+                if n.Status == StatusAccessDenied {
+                    log.Printf("Still waiting, %v", c.accessDenied(ctx, networkID))
+                }
+            }
+            last = n
+        }
+        switch last.Status {
+        case StatusOK:
+            if len(last.AssignedAddresses) > 0 {
+                return last, nil
+            }
+        case StatusNotFound:
+            return last, fmt.Errorf("ZeroTier network %s does not exist", networkID)
+        }
+
+        select {
+        case <-ctx.Done():
+            if last.Status == StatusAccessDenied {
+                return last, c.accessDenied(context.Background(), networkID)
+            }
+            if last.Status == StatusOK {
+                return last, fmt.Errorf("joined ZeroTier network %s but no address was assigned (enable auto-assign or assign one manually)", networkID)
+            }
+            return last, fmt.Errorf("timed out waiting for ZeroTier network %s (status %q)", networkID, last.Status)
+        case <-ticker.C:
+        }
+    }
+}
+
+// accessDenied 組合授權節點的提示
+func (c *Client) accessDenied(ctx context.Context, networkID string) error {
+    node := "<node address from 'go-auto-proxy zerotier status'>"
+    if status, err := c.Status(ctx); err == nil {
+        node = status.Address
+    }
+    return fmt.Errorf("ZeroTier network %s returned ACCESS_DENIED: authorize node %s at https://my.zerotier.com/network/%s "+
+        "(or in your self-hosted controller), then run 'go-auto-proxy zerotier join' again", networkID, node, networkID)
+}
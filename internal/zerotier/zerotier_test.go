@@ -0,0 +1,48 @@
+package zerotier
+
+import (
+    "context"
+    "testing"
+    "time"
+
+    "github.com/stretchr/testify/assert"
+    "github.com/stretchr/testify/require"
+)
+
+const testNetworkID = "8056c2e21c000001"
+
+func TestJoinAndWait(t *testing.T) {
+    svc, c := newFakeService(t)
+    svc.progress = []Network{
+        {ID: testNetworkID, Status: "REQUESTING_CONFIGURATION"},
+        {ID: testNetworkID, Name: "proxy", Status: StatusOK, PortDeviceName: "ztabcdef12",
+            AssignedAddresses: []string{"10.147.17.5/24", "fd80:56c2:e21c::1/88"}},
+    }
+
+    ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+    defer cancel()
+    network, err := c.JoinAndWait(ctx, testNetworkID, time.Millisecond)
+    require.NoError(t, err)
+    assert.Equal(t, "proxy", network.Name)
+    assert.Equal(t, []string{"10.147.17.5", "fd80:56c2:e21c::1"}, network.IPs())
+}
+
+func TestJoinAndWaitAccessDenied(t *testing.T) {
+    svc, c := newFakeService(t)
+    svc.progress = []Network{{ID: testNetworkID, Status: StatusAccessDenied}}
+
+    ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+    defer cancel()
+    network, err := c.JoinAndWait(ctx, testNetworkID, 5*time.Millisecond)
+    assert.Equal(t, StatusAccessDenied, network.Status)
+    require.Error(t, err)
+    assert.Contains(t, err.Error(), "ACCESS_DENIED")
+    assert.Contains(t, err.Error(), "a1b2c3d4e5", "the hint should name the node to authorize")
+}
+
+func TestJoinInvalidNetworkID(t *testing.T) {
+    svc, c := newFakeService(t)
+    _, err := c.JoinAndWait(context.Background(), "not-a-network", time.Millisecond)
+    assert.Error(t, err)
+    assert.Empty(t, svc.networks)
+}